@@ -1,51 +1,134 @@
 package cmd
 
-import "os"
+import (
+	"os"
+	"time"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/traefik/v2/pkg/types"
+)
 
 // MaeshConfiguration wraps the static configuration and extra parameters.
 type MaeshConfiguration struct {
 	// ConfigFile is the path to the configuration file.
-	ConfigFile       string   `description:"Configuration file to use. If specified all other flags are ignored." export:"true"`
-	KubeConfig       string   `description:"Path to a kubeconfig. Only required if out-of-cluster." export:"true"`
-	MasterURL        string   `description:"The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster." export:"true"`
-	Debug            bool     `description:"Debug mode" export:"true"`
-	SMI              bool     `description:"Enable SMI operation" export:"true"`
-	DefaultMode      string   `description:"Default mode for mesh services" export:"true"`
-	Namespace        string   `description:"The namespace that maesh is installed in." export:"true"`
-	IgnoreNamespaces []string `description:"The namespace that maesh should be ignoring." export:"true"`
-	APIPort          int      `description:"API port for the controller" export:"true"`
+	ConfigFile                string         `description:"Configuration file to use. If specified all other flags are ignored." export:"true"`
+	KubeConfig                string         `description:"Path to a kubeconfig. Only required if out-of-cluster." export:"true"`
+	MasterURL                 string         `description:"The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster." export:"true"`
+	Debug                     bool           `description:"Debug mode" export:"true"`
+	SMI                       bool           `description:"Enable SMI operation" export:"true"`
+	DefaultMode               string         `description:"Default mode for mesh services" export:"true"`
+	Namespace                 string         `description:"The namespace that maesh is installed in." export:"true"`
+	IgnoreNamespaces          []string       `description:"The namespace that maesh should be ignoring." export:"true"`
+	APIPort                   int            `description:"API port for the controller" export:"true"`
+	DNSNamespace              string         `description:"The namespace of the cluster DNS deployment." export:"true"`
+	DNSDeployment             string         `description:"The name of the cluster DNS deployment." export:"true"`
+	DNSConfigMap              string         `description:"The name of the cluster DNS configmap." export:"true"`
+	CoreDNSVersions           string         `description:"The semver range of supported CoreDNS versions." export:"true"`
+	DNSServerPort             int            `description:"Port of the controller DNS server for the maesh zone. Disabled if 0." export:"true"`
+	NamespaceSelector         string         `description:"Label selector of the namespaces part of the mesh. All namespaces if empty." export:"true"`
+	ServiceSelector           string         `description:"Label selector of the services part of the mesh. All services if empty." export:"true"`
+	WatchNamespaces           []string       `description:"The namespaces watched by the controller, in addition to the maesh namespace. All namespaces if empty." export:"true"`
+	RolloutCanaryNodes        int            `description:"Number of mesh nodes receiving a new configuration first. No canary if 0." export:"true"`
+	RolloutBatchSize          int            `description:"Number of mesh nodes receiving a new configuration at once after the canaries. All the remaining nodes if 0." export:"true"`
+	RolloutVerifyDelay        types.Duration `description:"Time waited after deploying to mesh nodes before verifying them." export:"true"`
+	APIToken                  string         `description:"Bearer token required to redeploy configurations through the API. Redeploys are disabled if empty. Defaults to the MAESH_API_TOKEN environment variable."`
+	APIAuth                   string         `description:"Authentication of the API requests: none, token (the API token) or kubernetes (TokenReview and SubjectAccessReview)." export:"true"`
+	APITLSCert                string         `description:"Path to the TLS certificate of the API. TLS is enabled if set with the key." export:"true"`
+	APITLSKey                 string         `description:"Path to the TLS key of the API." export:"true"`
+	ReadinessFailureThreshold int            `description:"Number of consecutive failed configuration builds or deployments making the controller not ready. Disabled if 0." export:"true"`
+	ShutdownTimeout           types.Duration `description:"Time given to the in-flight deployments and API requests to complete on shutdown." export:"true"`
+	MeshNodeAPIPort           int            `description:"Port of the API of the mesh nodes." export:"true"`
+	MeshNodeAPIScheme         string         `description:"Scheme of the API of the mesh nodes: http or https." export:"true"`
+	MeshNodeAPICA             string         `description:"Path to the CA bundle verifying the certificates of the mesh nodes API. The system CAs if empty." export:"true"`
+	MeshNodeAPIServerName     string         `description:"Name verified in the certificates of the mesh nodes API. The mesh node IP if empty." export:"true"`
+	MeshNodeAPIUsername       string         `description:"Basic authentication username of the mesh nodes API." export:"true"`
+	MeshNodeAPIPassword       string         `description:"Basic authentication password of the mesh nodes API. Defaults to the MAESH_MESH_API_PASSWORD environment variable."`
+	MeshNodeAPIToken          string         `description:"Bearer token of the mesh nodes API, used without basic authentication credentials. Defaults to the MAESH_MESH_API_TOKEN environment variable."`
+	DistributionMode          string         `description:"Distribution of the configuration: push to each mesh node, pull from the API by the mesh nodes, or kv to write it to a KV store." export:"true"`
+	DistributionPort          int            `description:"Port of the listener serving the configuration to the mesh nodes in pull mode." export:"true"`
+	KVStore                   string         `description:"KV store the configuration is written to in kv mode: consul, etcdv3, redis or zk." export:"true"`
+	KVEndpoints               []string       `description:"Endpoints of the KV store." export:"true"`
+	KVRootKey                 string         `description:"Root key the configuration is written under in the KV store." export:"true"`
+	KVUsername                string         `description:"Username of the KV store." export:"true"`
+	KVPassword                string         `description:"Password of the KV store. Defaults to the MAESH_KV_PASSWORD environment variable."`
+	KVTLS                     bool           `description:"Connect to the KV store over TLS." export:"true"`
+	KVTLSCA                   string         `description:"Path to the CA bundle verifying the KV store. The system CAs if empty." export:"true"`
+	KVTLSCert                 string         `description:"Path to the client certificate presented to the KV store." export:"true"`
+	KVTLSKey                  string         `description:"Path to the key of the client certificate presented to the KV store." export:"true"`
 }
 
 // NewMaeshConfiguration creates a MaeshConfiguration with default values.
 func NewMaeshConfiguration() *MaeshConfiguration {
 	return &MaeshConfiguration{
-		ConfigFile:  "",
-		KubeConfig:  os.Getenv("KUBECONFIG"),
-		Debug:       false,
-		SMI:         false,
-		DefaultMode: "http",
-		Namespace:   "maesh",
-		APIPort:     9000,
+		ConfigFile:         "",
+		KubeConfig:         os.Getenv("KUBECONFIG"),
+		Debug:              false,
+		SMI:                false,
+		DefaultMode:        "http",
+		Namespace:          "maesh",
+		APIPort:            9000,
+		APIAuth:            "none",
+		CoreDNSVersions:    k8s.DefaultSupportedCoreDNSVersions,
+		RolloutCanaryNodes: 1,
+		RolloutVerifyDelay: types.Duration(3 * time.Second),
+		ShutdownTimeout:    types.Duration(10 * time.Second),
+		MeshNodeAPIPort:    8080,
+		MeshNodeAPIScheme:  "http",
+		DistributionMode:   "push",
+		DistributionPort:   9001,
+		KVRootKey:          "traefik",
+		// The secrets are read from the environment, as the arguments of a process are readable by any user of its host.
+		APIToken:            os.Getenv("MAESH_API_TOKEN"),
+		MeshNodeAPIPassword: os.Getenv("MAESH_MESH_API_PASSWORD"),
+		MeshNodeAPIToken:    os.Getenv("MAESH_MESH_API_TOKEN"),
+		KVPassword:          os.Getenv("MAESH_KV_PASSWORD"),
 	}
 }
 
 // PrepareConfig .
 type PrepareConfig struct {
-	KubeConfig    string `description:"Path to a kubeconfig. Only required if out-of-cluster." export:"true"`
-	MasterURL     string `description:"The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster." export:"true"`
-	Debug         bool   `description:"Debug mode" export:"true"`
-	Namespace     string `description:"The namespace that maesh is installed in." export:"true"`
-	ClusterDomain string `description:"Your internal K8s cluster domain." export:"true"`
-	SMI           bool   `description:"Enable SMI operation" export:"true"`
+	KubeConfig              string `description:"Path to a kubeconfig. Only required if out-of-cluster." export:"true"`
+	MasterURL               string `description:"The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster." export:"true"`
+	Debug                   bool   `description:"Debug mode" export:"true"`
+	Namespace               string `description:"The namespace that maesh is installed in." export:"true"`
+	ClusterDomain           string `description:"Your internal K8s cluster domain." export:"true"`
+	SMI                     bool   `description:"Enable SMI operation" export:"true"`
+	DNSNamespace            string `description:"The namespace of the cluster DNS deployment." export:"true"`
+	DNSDeployment           string `description:"The name of the cluster DNS deployment." export:"true"`
+	DNSConfigMap            string `description:"The name of the cluster DNS configmap." export:"true"`
+	CoreDNSServiceNamespace string `description:"The namespace of the maesh CoreDNS service used with KubeDNS. Defaults to the maesh namespace." export:"true"`
+	CoreDNSVersions         string `description:"The semver range of supported CoreDNS versions." export:"true"`
+	SkipDNSPatch            bool   `description:"Do not patch the cluster DNS, when the maesh zone is served by the controller DNS server." export:"true"`
 }
 
 // NewPrepareConfig creates PrepareConfig.
 func NewPrepareConfig() *PrepareConfig {
 	return &PrepareConfig{
-		KubeConfig:    os.Getenv("KUBECONFIG"),
-		Debug:         false,
-		Namespace:     "maesh",
-		ClusterDomain: "cluster.local",
-		SMI:           false,
+		KubeConfig:      os.Getenv("KUBECONFIG"),
+		Debug:           false,
+		Namespace:       "maesh",
+		ClusterDomain:   "cluster.local",
+		SMI:             false,
+		CoreDNSVersions: k8s.DefaultSupportedCoreDNSVersions,
+	}
+}
+
+// RenderConfig holds the configuration of the render command.
+type RenderConfig struct {
+	Manifests         []string `description:"Paths of the YAML manifests, or of directories of YAML manifests, to render the configuration of." export:"true"`
+	Format            string   `description:"Output format: json, yaml or toml." export:"true"`
+	SMI               bool     `description:"Enable SMI operation" export:"true"`
+	DefaultMode       string   `description:"Default mode for mesh services" export:"true"`
+	IgnoreNamespaces  []string `description:"The namespace that maesh should be ignoring." export:"true"`
+	NamespaceSelector string   `description:"Label selector of the namespaces part of the mesh. All namespaces if empty." export:"true"`
+	ServiceSelector   string   `description:"Label selector of the services part of the mesh. All services if empty." export:"true"`
+}
+
+// NewRenderConfig creates a RenderConfig with default values.
+func NewRenderConfig() *RenderConfig {
+	return &RenderConfig{
+		Format:      "json",
+		SMI:         false,
+		DefaultMode: "http",
 	}
 }
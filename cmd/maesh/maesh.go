@@ -4,15 +4,20 @@ import (
 	"fmt"
 	stdlog "log"
 	"os"
+	"time"
 
+	"github.com/abronan/valkeyrie/store"
 	"github.com/containous/maesh/cmd"
 	"github.com/containous/maesh/cmd/prepare"
+	"github.com/containous/maesh/cmd/render"
 	"github.com/containous/maesh/cmd/version"
 	"github.com/containous/maesh/internal/controller"
 	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/kv"
 	"github.com/containous/maesh/internal/signals"
 	"github.com/containous/traefik/v2/pkg/cli"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func main() {
@@ -35,6 +40,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	rConfig := cmd.NewRenderConfig()
+	if err := cmdMaesh.AddCommand(render.NewCmd(rConfig, loaders)); err != nil {
+		stdlog.Println(err)
+		os.Exit(1)
+	}
+
 	if err := cmdMaesh.AddCommand(version.NewCmd()); err != nil {
 		stdlog.Println(err)
 		os.Exit(1)
@@ -65,14 +76,105 @@ func maeshCommand(iConfig *cmd.MaeshConfiguration) error {
 		return fmt.Errorf("error building clients: %v", err)
 	}
 
-	if err = clients.CheckCluster(); err != nil {
-		return fmt.Errorf("error during cluster check: %v", err)
+	dnsOptions := k8s.DNSOptions{
+		Namespace:                iConfig.DNSNamespace,
+		Deployment:               iConfig.DNSDeployment,
+		ConfigMap:                iConfig.DNSConfigMap,
+		SupportedCoreDNSVersions: iConfig.CoreDNSVersions,
+	}
+
+	// The cluster DNS is not used when the maesh zone is served by the controller DNS server.
+	if iConfig.DNSServerPort == 0 {
+		if err = clients.CheckCluster(dnsOptions); err != nil {
+			return fmt.Errorf("error during cluster check: %v", err)
+		}
+	}
+
+	namespaceSelector, err := labels.Parse(iConfig.NamespaceSelector)
+	if err != nil {
+		return fmt.Errorf("invalid namespace selector %q: %v", iConfig.NamespaceSelector, err)
+	}
+
+	serviceSelector, err := labels.Parse(iConfig.ServiceSelector)
+	if err != nil {
+		return fmt.Errorf("invalid service selector %q: %v", iConfig.ServiceSelector, err)
+	}
+
+	switch iConfig.APIAuth {
+	case controller.APIAuthNone, controller.APIAuthKubernetes:
+	case controller.APIAuthToken:
+		if iConfig.APIToken == "" {
+			return fmt.Errorf("the %s API authentication requires an API token", iConfig.APIAuth)
+		}
+	default:
+		return fmt.Errorf("invalid API authentication %q", iConfig.APIAuth)
+	}
+
+	var kvStore store.Store
+
+	switch iConfig.DistributionMode {
+	case controller.DistributionModePush, controller.DistributionModePull:
+	case controller.DistributionModeKV:
+		kvStore, err = kv.NewStore(kv.StoreConfig{
+			Backend:   iConfig.KVStore,
+			Endpoints: iConfig.KVEndpoints,
+			Username:  iConfig.KVUsername,
+			Password:  iConfig.KVPassword,
+			TLS:       iConfig.KVTLS,
+			CAFile:    iConfig.KVTLSCA,
+			CertFile:  iConfig.KVTLSCert,
+			KeyFile:   iConfig.KVTLSKey,
+		})
+		if err != nil {
+			return fmt.Errorf("invalid KV store configuration: %v", err)
+		}
+	default:
+		return fmt.Errorf("invalid distribution mode %q", iConfig.DistributionMode)
+	}
+
+	meshNodes, err := controller.NewMeshNodeClient(controller.MeshNodeConfig{
+		Port:       iConfig.MeshNodeAPIPort,
+		Scheme:     iConfig.MeshNodeAPIScheme,
+		CAFile:     iConfig.MeshNodeAPICA,
+		ServerName: iConfig.MeshNodeAPIServerName,
+		Username:   iConfig.MeshNodeAPIUsername,
+		Password:   iConfig.MeshNodeAPIPassword,
+		Token:      iConfig.MeshNodeAPIToken,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid mesh node API configuration: %v", err)
 	}
 
 	// Create a new stop Channel
 	stopCh := signals.SetupSignalHandler()
 	// Create a new ctr.
-	ctr := controller.NewMeshController(clients, iConfig.SMI, iConfig.DefaultMode, iConfig.Namespace, iConfig.IgnoreNamespaces, iConfig.APIPort)
+	ctr := controller.NewMeshController(clients, controller.MeshControllerConfig{
+		SMIEnabled:        iConfig.SMI,
+		DefaultMode:       iConfig.DefaultMode,
+		Namespace:         iConfig.Namespace,
+		IgnoreNamespaces:  iConfig.IgnoreNamespaces,
+		APIPort:           iConfig.APIPort,
+		DNSServerPort:     iConfig.DNSServerPort,
+		NamespaceSelector: namespaceSelector,
+		ServiceSelector:   serviceSelector,
+		WatchNamespaces:   iConfig.WatchNamespaces,
+		Rollout: controller.RolloutConfig{
+			CanaryNodes: iConfig.RolloutCanaryNodes,
+			BatchSize:   iConfig.RolloutBatchSize,
+			VerifyDelay: time.Duration(iConfig.RolloutVerifyDelay),
+		},
+		APIToken:                  iConfig.APIToken,
+		APIAuth:                   iConfig.APIAuth,
+		APITLSCertFile:            iConfig.APITLSCert,
+		APITLSKeyFile:             iConfig.APITLSKey,
+		ReadinessFailureThreshold: iConfig.ReadinessFailureThreshold,
+		ShutdownTimeout:           time.Duration(iConfig.ShutdownTimeout),
+		MeshNodes:                 meshNodes,
+		DistributionMode:          iConfig.DistributionMode,
+		DistributionPort:          iConfig.DistributionPort,
+		KVStore:                   kvStore,
+		KVRootKey:                 iConfig.KVRootKey,
+	})
 
 	// run the ctr loop to process items
 	if err = ctr.Run(stopCh); err != nil {
@@ -40,15 +40,30 @@ func prepareCommand(pConfig *cmd.PrepareConfig) error {
 		return fmt.Errorf("error building clients: %v", err)
 	}
 
-	if err = clients.CheckCluster(); err != nil {
-		return fmt.Errorf("error during cluster check: %v", err)
+	dnsOptions := k8s.DNSOptions{
+		Namespace:                pConfig.DNSNamespace,
+		Deployment:               pConfig.DNSDeployment,
+		ConfigMap:                pConfig.DNSConfigMap,
+		CoreDNSServiceNamespace:  pConfig.CoreDNSServiceNamespace,
+		SupportedCoreDNSVersions: pConfig.CoreDNSVersions,
+	}
+
+	if !pConfig.SkipDNSPatch {
+		if err = clients.CheckCluster(dnsOptions); err != nil {
+			return fmt.Errorf("error during cluster check: %v", err)
+		}
 	}
 
 	if err = clients.CheckInformersStart(pConfig.SMI); err != nil {
 		return fmt.Errorf("error during informer check: %v, this can be caused by pre-existing objects in your cluster that do not conform to the spec", err)
 	}
 
-	if err = clients.InitCluster(pConfig.Namespace, pConfig.ClusterDomain); err != nil {
+	if pConfig.SkipDNSPatch {
+		log.Infoln("Skipping DNS patch, the maesh zone must be delegated to the controller DNS server")
+		return nil
+	}
+
+	if err = clients.InitCluster(pConfig.Namespace, pConfig.ClusterDomain, dnsOptions); err != nil {
 		return fmt.Errorf("error initializing cluster: %v", err)
 	}
 
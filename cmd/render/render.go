@@ -0,0 +1,64 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/containous/maesh/cmd"
+	"github.com/containous/maesh/internal/render"
+	"github.com/containous/traefik/v2/pkg/cli"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// NewCmd builds a new Render command.
+func NewCmd(rConfig *cmd.RenderConfig, loaders []cli.ResourceLoader) *cli.Command {
+	return &cli.Command{
+		Name:          "render",
+		Description:   `Renders the configuration of YAML manifests, without a cluster.`,
+		Configuration: rConfig,
+		Run: func(_ []string) error {
+			return renderCommand(rConfig)
+		},
+		Resources: loaders,
+	}
+}
+
+func renderCommand(rConfig *cmd.RenderConfig) error {
+	// The configuration is written on the standard output, the logs are kept apart.
+	log.SetOutput(os.Stderr)
+	log.SetLevel(log.WarnLevel)
+
+	if len(rConfig.Manifests) == 0 {
+		return errors.New("no manifests to render")
+	}
+
+	namespaceSelector, err := labels.Parse(rConfig.NamespaceSelector)
+	if err != nil {
+		return fmt.Errorf("invalid namespace selector %q: %v", rConfig.NamespaceSelector, err)
+	}
+
+	serviceSelector, err := labels.Parse(rConfig.ServiceSelector)
+	if err != nil {
+		return fmt.Errorf("invalid service selector %q: %v", rConfig.ServiceSelector, err)
+	}
+
+	objects, err := render.LoadManifests(rConfig.Manifests)
+	if err != nil {
+		return fmt.Errorf("error loading manifests: %v", err)
+	}
+
+	conf, err := render.BuildConfiguration(objects, render.Config{
+		SMI:               rConfig.SMI,
+		DefaultMode:       rConfig.DefaultMode,
+		IgnoreNamespaces:  rConfig.IgnoreNamespaces,
+		NamespaceSelector: namespaceSelector,
+		ServiceSelector:   serviceSelector,
+	})
+	if err != nil {
+		return fmt.Errorf("error building configuration: %v", err)
+	}
+
+	return render.Write(os.Stdout, conf, rConfig.Format)
+}
@@ -4,6 +4,8 @@ go 1.13
 
 // Kubernetes version kubernetes-1.15.3
 require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/Masterminds/semver v1.4.2
 	github.com/abronan/valkeyrie v0.0.0-20190802193736-ed4c4a229894
 	github.com/cenkalti/backoff/v3 v3.0.0
 	github.com/containous/traefik/v2 v2.0.2
@@ -11,6 +13,7 @@ require (
 	github.com/go-check/check v0.0.0-20180628173108-788fd7840127
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/mux v1.7.3
+	github.com/miekg/dns v1.1.15
 	github.com/pmezard/go-difflib v1.0.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.4.0
@@ -19,6 +22,7 @@ require (
 	k8s.io/api v0.0.0-20190819141258-3544db3b9e44
 	k8s.io/apimachinery v0.0.0-20190817020851-f2f3a405f61d
 	k8s.io/client-go v0.0.0-20190819141724-e14f31a72a77
+	sigs.k8s.io/yaml v1.1.0
 )
 
 replace (
@@ -31,3 +31,41 @@ Define the ignoreNamespaces List
             {{- $ns }}
     {{- end -}}
 {{- end -}}
+
+{{/*
+Define the watchNamespaces List
+*/}}
+{{- define "maesh.controllerWatchNamespaces" -}}
+    --watchNamespaces=
+    {{- range $idx, $ns := .Values.controller.watchNamespaces }}
+        {{- if $idx }},{{ end }}
+            {{- $ns }}
+    {{- end -}}
+{{- end -}}
+
+{{/*
+Define whether the mesh nodes API is served over TLS or authenticated
+*/}}
+{{- define "maesh.meshAPISecured" -}}
+    {{- if or .Values.mesh.api.tls.secretName .Values.mesh.api.auth.secretName -}}
+        true
+    {{- end -}}
+{{- end -}}
+
+{{/*
+Define whether the mesh nodes pull their configuration from the controller over TLS
+*/}}
+{{- define "maesh.meshPullTLS" -}}
+    {{- if and (eq .Values.controller.distribution.mode "pull") .Values.controller.api.tls.secretName -}}
+        true
+    {{- end -}}
+{{- end -}}
+
+{{/*
+Define whether the controller and the mesh nodes connect to the KV store over TLS
+*/}}
+{{- define "maesh.kvTLS" -}}
+    {{- if and (eq .Values.controller.distribution.mode "kv") .Values.controller.distribution.kv.tls.secretName -}}
+        true
+    {{- end -}}
+{{- end -}}
@@ -1,8 +1,16 @@
 package integration
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/containous/traefik/v2/pkg/rules"
 	"github.com/go-check/check"
 	checker "github.com/vdemeester/shakers"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
 )
 
 // HelmSuite
@@ -43,3 +51,52 @@ func (s *HelmSuite) TestSMIInstall(c *check.C) {
 
 	s.waitForMaeshControllerStarted(c)
 }
+
+func (s *HelmSuite) TestSecuredMeshAPIRouters(c *check.C) {
+	cmd := exec.Command("helm", "template", "powpow", "../helm/chart/maesh", "--namespace", maeshNamespace,
+		"--show-only", "templates/mesh/mesh-api-configmap.yaml",
+		"--set", "mesh.api.auth.secretName=mesh-api-auth",
+		"--set", "controller.distribution.mode=push")
+
+	output, err := cmd.Output()
+	c.Assert(err, checker.IsNil)
+
+	var configMap corev1.ConfigMap
+	c.Assert(yaml.Unmarshal(output, &configMap), checker.IsNil)
+
+	var config dynamic.Configuration
+	c.Assert(yaml.Unmarshal([]byte(configMap.Data["api.yaml"]), &config), checker.IsNil)
+	c.Assert(config.HTTP, checker.NotNil)
+
+	// Route the requests as the mesh nodes do, each router answering with the name of its service.
+	router, err := rules.NewRouter()
+	c.Assert(err, checker.IsNil)
+
+	for _, r := range config.HTTP.Routers {
+		service := r.Service
+		handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(service))
+		})
+
+		c.Assert(router.AddRoute(r.Rule, r.Priority, handler), checker.IsNil)
+	}
+
+	router.SortRoutes()
+
+	testCases := []struct {
+		method          string
+		path            string
+		expectedService string
+	}{
+		{method: http.MethodPut, path: "/api/providers/rest", expectedService: "rest@internal"},
+		{method: http.MethodGet, path: "/api/rawdata", expectedService: "api@internal"},
+		{method: http.MethodGet, path: "/dashboard/", expectedService: "api@internal"},
+	}
+
+	for _, test := range testCases {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, httptest.NewRequest(test.method, test.path, nil))
+
+		c.Assert(res.Body.String(), checker.Equals, test.expectedService)
+	}
+}
@@ -203,7 +203,7 @@ func (t *Try) WaitClientCreated(url string, kubeConfigPath string, timeout time.
 			return fmt.Errorf("unable to create clients: %v", err)
 		}
 
-		if _, err = clients.KubeClient.ServerVersion(); err != nil {
+		if _, err = clients.KubeClient.Discovery().ServerVersion(); err != nil {
 			return fmt.Errorf("unable to get server version: %v", err)
 		}
 
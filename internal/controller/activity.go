@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// Activity event types.
+const (
+	ActivityTypeInformer  = "informer"
+	ActivityTypeRebuild   = "rebuild"
+	ActivityTypeDeploy    = "deploy"
+	ActivityTypeReadiness = "readiness"
+)
+
+// activityBufferSize is the number of events buffered for each subscriber, before events are dropped.
+const activityBufferSize = 100
+
+// ActivityEvent is an event of the controller activity.
+type ActivityEvent struct {
+	Type      string
+	TimeStamp time.Time
+	// Namespace and Service are set for the events related to a service, and used to filter the events.
+	Namespace string      `json:",omitempty"`
+	Service   string      `json:",omitempty"`
+	Pod       string      `json:",omitempty"`
+	Message   string      `json:",omitempty"`
+	Data      interface{} `json:",omitempty"`
+}
+
+// Matches checks if the event matches the namespace and service filters. Empty filters match all the events.
+// Events not related to a service, like rebuilds or deploys, match all the filters.
+func (e ActivityEvent) Matches(namespace, service string) bool {
+	if namespace != "" && e.Namespace != "" && e.Namespace != namespace {
+		return false
+	}
+
+	if service != "" && e.Service != "" && e.Service != service {
+		return false
+	}
+
+	return true
+}
+
+// ActivityStream broadcasts the controller activity events to its subscribers.
+type ActivityStream struct {
+	mu          sync.RWMutex
+	subscribers map[chan ActivityEvent]struct{}
+}
+
+// NewActivityStream returns an initialized ActivityStream.
+func NewActivityStream() *ActivityStream {
+	return &ActivityStream{
+		subscribers: make(map[chan ActivityEvent]struct{}),
+	}
+}
+
+// Publish sends the event to all the subscribers.
+// The event is dropped for the subscribers which are too slow to keep up, so that the controller is never blocked.
+func (s *ActivityStream) Publish(event ActivityEvent) {
+	if s == nil {
+		return
+	}
+
+	if event.TimeStamp.IsZero() {
+		event.TimeStamp = time.Now()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the published events, and a function to unsubscribe.
+func (s *ActivityStream) Subscribe() (<-chan ActivityEvent, func()) {
+	subscriber := make(chan ActivityEvent, activityBufferSize)
+
+	s.mu.Lock()
+	s.subscribers[subscriber] = struct{}{}
+	s.mu.Unlock()
+
+	unsubscribe := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.subscribers, subscriber)
+	}
+
+	return subscriber, unsubscribe
+}
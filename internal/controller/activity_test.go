@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityEventMatches(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		event     ActivityEvent
+		namespace string
+		service   string
+		expected  bool
+	}{
+		{
+			desc:     "no filters",
+			event:    ActivityEvent{Namespace: "foo", Service: "bar"},
+			expected: true,
+		},
+		{
+			desc:      "matching namespace and service",
+			event:     ActivityEvent{Namespace: "foo", Service: "bar"},
+			namespace: "foo",
+			service:   "bar",
+			expected:  true,
+		},
+		{
+			desc:      "other namespace",
+			event:     ActivityEvent{Namespace: "foo", Service: "bar"},
+			namespace: "baz",
+			expected:  false,
+		},
+		{
+			desc:     "other service",
+			event:    ActivityEvent{Namespace: "foo", Service: "bar"},
+			service:  "baz",
+			expected: false,
+		},
+		{
+			desc:      "event not related to a service",
+			event:     ActivityEvent{Type: ActivityTypeRebuild},
+			namespace: "foo",
+			service:   "bar",
+			expected:  true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.event.Matches(test.namespace, test.service))
+		})
+	}
+}
+
+func TestActivityStream(t *testing.T) {
+	stream := NewActivityStream()
+
+	events, unsubscribe := stream.Subscribe()
+
+	stream.Publish(ActivityEvent{Type: ActivityTypeDeploy, Pod: "foo"})
+
+	event := <-events
+	assert.Equal(t, "foo", event.Pod)
+	assert.False(t, event.TimeStamp.IsZero())
+
+	// Slow subscribers miss the events instead of blocking the publisher.
+	for i := 0; i < activityBufferSize+10; i++ {
+		stream.Publish(ActivityEvent{Type: ActivityTypeDeploy})
+	}
+
+	require.Len(t, events, activityBufferSize)
+
+	unsubscribe()
+
+	stream.Publish(ActivityEvent{Type: ActivityTypeDeploy})
+	assert.Len(t, events, activityBufferSize)
+}
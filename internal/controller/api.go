@@ -1,14 +1,23 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
 	"github.com/containous/traefik/v2/pkg/safe"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	kubeerror "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
@@ -17,30 +26,84 @@ import (
 
 // API is an implementation of an api.
 type API struct {
-	router            *mux.Router
-	readiness         bool
-	lastConfiguration *safe.Safe
-	apiPort           int
-	deployLog         *DeployLog
-	meshNamespace     string
-	podLister         listers.PodLister
+	router             *mux.Router
+	server             *http.Server
+	distributionRouter *mux.Router
+	distributionServer *http.Server
+	distributionPort   int
+	shutdownCh         chan struct{}
+	readinessMu        sync.RWMutex
+	readiness          bool
+	stopping           bool
+	lastConfiguration  *safe.Safe
+	apiPort            int
+	deployLog          *DeployLog
+	configVersions     *ConfigVersions
+	rollout            *Rollout
+	history            *ConfigHistory
+	activity           *ActivityStream
+	apiToken           string
+	redeployChan       chan<- redeployRequest
+	authenticator      Authenticator
+	tlsCertFile        string
+	tlsKeyFile         string
+	meshNamespace      string
+	podLister          listers.PodLister
+	meshNodes          *MeshNodeClient
+	health             *Health
+	informerStates     func() map[string]bool
+	topology           func() (*Topology, error)
+	explain            func(request ExplainRequest, config *dynamic.Configuration) (*Explanation, error)
+	viewService        func(namespace, name string, config *dynamic.Configuration) (*ServiceView, error)
+	published          *PublishedConfiguration
+	nodeVersionsKnown  bool
+}
+
+// readinessPath is the path of the readiness endpoint.
+const readinessPath = "/api/status/readiness"
+
+// healthPath is the path of the health endpoint.
+const healthPath = "/api/status/health"
+
+// livenessPath is the path of the liveness endpoint.
+const livenessPath = "/api/status/liveness"
+
+// meshConfigurationPath is the path of the endpoint serving the configuration to the mesh nodes in pull mode.
+const meshConfigurationPath = "/api/configuration/mesh"
+
+// activityKeepAlivePeriod is the period of the keep-alive comments sent on the event streams.
+const activityKeepAlivePeriod = 30 * time.Second
+
+// redeployRequest asks the controller to redeploy a configuration, and pin it if requested.
+// A request without configuration unpins the configuration.
+type redeployRequest struct {
+	config *dynamic.Configuration
+	pin    bool
 }
 
 type podInfo struct {
-	Name  string
-	IP    string
-	Ready bool
+	Name          string
+	IP            string
+	Ready         bool
+	ConfigVersion string
 }
 
 // NewAPI creates a new api.
-func NewAPI(apiPort int, lastConfiguration *safe.Safe, deployLog *DeployLog, podLister listers.PodLister, meshNamespace string) *API {
+func NewAPI(apiPort int, lastConfiguration *safe.Safe, deployLog *DeployLog, configVersions *ConfigVersions, rollout *Rollout, history *ConfigHistory, activity *ActivityStream, podLister listers.PodLister, meshNamespace string) *API {
 	a := &API{
 		readiness:         false,
+		shutdownCh:        make(chan struct{}),
 		lastConfiguration: lastConfiguration,
 		apiPort:           apiPort,
 		deployLog:         deployLog,
+		configVersions:    configVersions,
+		rollout:           rollout,
+		history:           history,
+		activity:          activity,
 		podLister:         podLister,
+		meshNodes:         newDefaultMeshNodeClient(),
 		meshNamespace:     meshNamespace,
+		nodeVersionsKnown: true,
 	}
 
 	if err := a.Init(); err != nil {
@@ -57,10 +120,27 @@ func (a *API) Init() error {
 	a.router = mux.NewRouter()
 
 	a.router.HandleFunc("/api/configuration/current", a.getCurrentConfiguration)
+	a.router.HandleFunc("/api/configuration/history", a.getConfigurationHistory)
+	a.router.HandleFunc("/api/configuration/diff", a.getConfigurationDiff)
+	a.router.HandleFunc("/api/configuration/pin", a.unpinConfiguration).Methods(http.MethodDelete)
+	a.router.HandleFunc("/api/configuration/{version}", a.getConfiguration)
+	a.router.HandleFunc("/api/configuration/{version}/redeploy", a.redeployConfiguration).Methods(http.MethodPost)
 	a.router.HandleFunc("/api/status/nodes", a.getMeshNodes)
 	a.router.HandleFunc("/api/status/node/{node}/configuration", a.getMeshNodeConfiguration)
-	a.router.HandleFunc("/api/status/readiness", a.getReadiness)
+	a.router.HandleFunc(readinessPath, a.getReadiness)
+	a.router.HandleFunc(healthPath, a.getHealth)
+	a.router.HandleFunc(livenessPath, a.getLiveness)
+	a.router.HandleFunc("/api/status/rollout", a.getRolloutStatus)
 	a.router.HandleFunc("/api/log/deployment", a.getDeployLog)
+	a.router.HandleFunc("/api/log/deployment/summary", a.getDeployLogSummary)
+	a.router.HandleFunc("/api/events", a.streamEvents)
+	a.router.HandleFunc("/api/topology", a.getTopology)
+	a.router.HandleFunc("/api/explain", a.getExplanation)
+	a.router.HandleFunc("/api/services/{namespace}/{name}", a.getServiceView)
+
+	// The mesh nodes, which cannot authenticate, only reach the mesh configuration, on its own listener.
+	a.distributionRouter = mux.NewRouter()
+	a.distributionRouter.HandleFunc(meshConfigurationPath, a.getMeshConfiguration).Methods(http.MethodGet)
 
 	return nil
 }
@@ -69,23 +149,162 @@ func (a *API) Init() error {
 func (a *API) Start() {
 	log.Debugln("API.Start")
 
+	var handler http.Handler = a.router
+	if a.authenticator != nil {
+		handler = a.authenticate(a.router)
+	}
+
+	a.server = &http.Server{
+		Addr:    fmt.Sprintf(":%d", a.apiPort),
+		Handler: handler,
+	}
+
+	// The event streams never become idle, they are closed for the server to shut down.
+	a.server.RegisterOnShutdown(func() {
+		close(a.shutdownCh)
+	})
+
 	go a.Run()
+
+	if a.published != nil {
+		a.distributionServer = &http.Server{
+			Addr:    fmt.Sprintf(":%d", a.distributionPort),
+			Handler: a.distributionRouter,
+		}
+
+		go a.serve(a.distributionServer)
+	}
 }
 
 // Run wraps the listenAndServe method.
 func (a *API) Run() {
-	log.Error(http.ListenAndServe(fmt.Sprintf(":%d", a.apiPort), a.router))
+	a.serve(a.server)
+}
+
+// serve runs the given server, over TLS if enabled.
+func (a *API) serve(server *http.Server) {
+	var err error
+	if a.tlsCertFile != "" && a.tlsKeyFile != "" {
+		err = server.ListenAndServeTLS(a.tlsCertFile, a.tlsKeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != http.ErrServerClosed {
+		log.Error(err)
+	}
+}
+
+// Shutdown gracefully shuts down the API and the distribution servers, waiting for the active requests until the context is done.
+func (a *API) Shutdown(ctx context.Context) error {
+	if a.server == nil {
+		return nil
+	}
+
+	if err := a.server.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	if a.distributionServer == nil {
+		return nil
+	}
+
+	return a.distributionServer.Shutdown(ctx)
+}
+
+// EnableTLS serves the API over TLS, with the given certificate and key files.
+func (a *API) EnableTLS(certFile, keyFile string) {
+	a.tlsCertFile = certFile
+	a.tlsKeyFile = keyFile
+}
+
+// EnableAuthentication requires all the API requests, but the readiness and liveness ones, to be allowed by the authenticator.
+func (a *API) EnableAuthentication(authenticator Authenticator) {
+	a.authenticator = authenticator
+}
+
+// EnableHealth enables the health endpoint, reporting the given health and informer sync states.
+// The readiness is reported as false while the health is degraded.
+func (a *API) EnableHealth(health *Health, informerStates func() map[string]bool) {
+	a.health = health
+	a.informerStates = informerStates
+}
+
+// EnableTopology enables the topology endpoint, serving the topology returned by the given function.
+func (a *API) EnableTopology(topology func() (*Topology, error)) {
+	a.topology = topology
+}
+
+// EnableExplain enables the explain endpoint, evaluating the requests with the given function.
+func (a *API) EnableExplain(explain func(request ExplainRequest, config *dynamic.Configuration) (*Explanation, error)) {
+	a.explain = explain
+}
+
+// SetMeshNodeClient sets the client getting the configuration of the mesh nodes.
+func (a *API) SetMeshNodeClient(meshNodes *MeshNodeClient) {
+	a.meshNodes = meshNodes
 }
 
-// EnableReadiness enables the readiness flag in the API.
+// EnableServiceView enables the service endpoint, describing the services with the given function.
+func (a *API) EnableServiceView(viewService func(namespace, name string, config *dynamic.Configuration) (*ServiceView, error)) {
+	a.viewService = viewService
+}
+
+// DisableNodeVersions stops reporting the stale mesh pods, when the configuration versions they run are not known,
+// as in kv mode where they watch a KV store.
+func (a *API) DisableNodeVersions() {
+	a.nodeVersionsKnown = false
+}
+
+// EnableDistribution enables the endpoint serving the given published configuration to the mesh nodes, on the given port.
+// The endpoint is not authenticated, as the mesh nodes hold no credentials, and nothing else is served on this port.
+func (a *API) EnableDistribution(published *PublishedConfiguration, port int) {
+	a.published = published
+	a.distributionPort = port
+}
+
+// authenticate wraps the handler with the authenticator.
+func (a *API) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The readiness and liveness endpoints are used by the kubelet probes, which have no credentials.
+		if r.URL.Path == readinessPath || r.URL.Path == livenessPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if status, err := a.authenticator.Authorize(r); err != nil {
+			writeErrorResponse(w, fmt.Sprintf("unauthorized API request: %v", err), status)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// EnableReadiness enables the readiness flag in the API, unless the controller is shutting down.
 func (a *API) EnableReadiness() {
-	if !a.readiness {
+	a.readinessMu.Lock()
+	defer a.readinessMu.Unlock()
+
+	if !a.readiness && !a.stopping {
 		log.Debug("Controller Readiness enabled")
 
 		a.readiness = true
+
+		a.activity.Publish(ActivityEvent{
+			Type:    ActivityTypeReadiness,
+			Message: "controller ready",
+			Data:    true,
+		})
 	}
 }
 
+// EnableRedeploy enables the endpoints redeploying configurations from the history, protected by the given bearer token.
+func (a *API) EnableRedeploy(apiToken string, redeployChan chan<- redeployRequest) {
+	a.apiToken = apiToken
+	a.redeployChan = redeployChan
+}
+
 // getCurrentConfiguration returns the current configuration.
 func (a *API) getCurrentConfiguration(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -95,22 +314,433 @@ func (a *API) getCurrentConfiguration(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// getTopology returns the topology of the mesh, in JSON or, with the format=dot query parameter, in the DOT language.
+func (a *API) getTopology(w http.ResponseWriter, r *http.Request) {
+	if a.topology == nil {
+		writeErrorResponse(w, "topology is not enabled", http.StatusNotFound)
+		return
+	}
+
+	topology, err := a.topology()
+	if err != nil {
+		writeErrorResponse(w, fmt.Sprintf("unable to build topology: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if topology == nil {
+		writeErrorResponse(w, "topology is not built yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(topology); err != nil {
+			log.Error(err)
+		}
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+
+		if err := topology.WriteDOT(w); err != nil {
+			log.Error(err)
+		}
+	default:
+		writeErrorResponse(w, fmt.Sprintf("unsupported topology format %q", format), http.StatusBadRequest)
+	}
+}
+
+// getExplanation explains why the traffic from a pod or service account to a service is allowed or denied.
+func (a *API) getExplanation(w http.ResponseWriter, r *http.Request) {
+	if a.explain == nil {
+		writeErrorResponse(w, "explain is not enabled", http.StatusNotFound)
+		return
+	}
+
+	request, err := parseExplainRequest(r)
+	if err != nil {
+		writeErrorResponse(w, fmt.Sprintf("invalid explain request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	config, _ := a.lastConfiguration.Get().(*dynamic.Configuration)
+
+	explanation, err := a.explain(request, config)
+	if err != nil {
+		writeErrorResponse(w, fmt.Sprintf("unable to explain traffic: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(explanation); err != nil {
+		log.Error(err)
+	}
+}
+
+// getServiceView returns the mesh configuration of a user service.
+func (a *API) getServiceView(w http.ResponseWriter, r *http.Request) {
+	if a.viewService == nil {
+		writeErrorResponse(w, "service view is not enabled", http.StatusNotFound)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	config, _ := a.lastConfiguration.Get().(*dynamic.Configuration)
+
+	view, err := a.viewService(vars["namespace"], vars["name"], config)
+	if err != nil {
+		writeErrorResponse(w, fmt.Sprintf("unable to view service: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if view == nil {
+		writeErrorResponse(w, fmt.Sprintf("unable to find service %s/%s", vars["namespace"], vars["name"]), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(view); err != nil {
+		log.Error(err)
+	}
+}
+
+// parseExplainRequest parses the from, to, port, path and method query parameters of an explain request.
+func parseExplainRequest(r *http.Request) (ExplainRequest, error) {
+	query := r.URL.Query()
+
+	var request ExplainRequest
+
+	var ok bool
+
+	request.FromNamespace, request.FromName, ok = splitNamespacedName(query.Get("from"))
+	if !ok {
+		return request, fmt.Errorf("from must be <namespace>/<pod or service account>, got %q", query.Get("from"))
+	}
+
+	request.ToNamespace, request.ToName, ok = splitNamespacedName(query.Get("to"))
+	if !ok {
+		return request, fmt.Errorf("to must be <namespace>/<service>, got %q", query.Get("to"))
+	}
+
+	if value := query.Get("port"); value != "" {
+		port, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || port <= 0 {
+			return request, fmt.Errorf("invalid port %q", value)
+		}
+
+		request.Port = int32(port)
+	}
+
+	request.Path = query.Get("path")
+	request.Method = strings.ToUpper(query.Get("method"))
+
+	return request, nil
+}
+
+func splitNamespacedName(value string) (string, string, bool) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// getMeshConfiguration serves the published configuration to the mesh nodes, as expected by the Traefik HTTP provider.
+func (a *API) getMeshConfiguration(w http.ResponseWriter, r *http.Request) {
+	if a.published == nil {
+		writeErrorResponse(w, "configuration distribution is not enabled", http.StatusNotFound)
+		return
+	}
+
+	version, data := a.published.Get()
+	if data == nil {
+		writeErrorResponse(w, "no configuration published yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	a.recordServedVersion(r.RemoteAddr, version)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(configVersionHeader, version)
+
+	if _, err := w.Write(data); err != nil {
+		log.Error(err)
+	}
+}
+
+// recordServedVersion records the configuration version served to the mesh pod with the given address.
+// The pod is not found when the source address of the mesh nodes is translated on the way to the controller.
+func (a *API) recordServedVersion(remoteAddr, version string) {
+	if a.podLister == nil || a.configVersions == nil {
+		return
+	}
+
+	ip, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		ip = remoteAddr
+	}
+
+	pods, err := a.listMeshPods()
+	if err != nil {
+		log.Errorf("Unable to list mesh pods: %v", err)
+		return
+	}
+
+	for _, pod := range pods {
+		if pod.Status.PodIP == ip {
+			a.configVersions.Set(pod.Name, version)
+			return
+		}
+	}
+}
+
+// getConfigurationHistory returns the history of the deployed configurations.
+func (a *API) getConfigurationHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(a.history.Entries()); err != nil {
+		log.Error(err)
+	}
+}
+
+// getConfiguration returns a configuration from the history.
+func (a *API) getConfiguration(w http.ResponseWriter, r *http.Request) {
+	version := mux.Vars(r)["version"]
+
+	config, ok := a.history.Get(version)
+	if !ok {
+		writeErrorResponse(w, fmt.Sprintf("unable to find configuration version: %s", version), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(config); err != nil {
+		log.Error(err)
+	}
+}
+
+// getConfigurationDiff returns the differences between the configurations with the from and to versions.
+func (a *API) getConfigurationDiff(w http.ResponseWriter, r *http.Request) {
+	var configs []*dynamic.Configuration
+
+	for _, param := range []string{"from", "to"} {
+		version := r.URL.Query().Get(param)
+		if version == "" {
+			writeErrorResponse(w, fmt.Sprintf("missing %s version", param), http.StatusBadRequest)
+			return
+		}
+
+		config, ok := a.history.Get(version)
+		if !ok {
+			writeErrorResponse(w, fmt.Sprintf("unable to find configuration version: %s", version), http.StatusNotFound)
+			return
+		}
+
+		configs = append(configs, config)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(diffConfigurations(configs[0], configs[1])); err != nil {
+		log.Error(err)
+	}
+}
+
+// redeployConfiguration redeploys a configuration from the history, and pins it if the pin parameter is true.
+func (a *API) redeployConfiguration(w http.ResponseWriter, r *http.Request) {
+	if !a.authorizeRedeploy(w, r) {
+		return
+	}
+
+	version := mux.Vars(r)["version"]
+
+	config, ok := a.history.Get(version)
+	if !ok {
+		writeErrorResponse(w, fmt.Sprintf("unable to find configuration version: %s", version), http.StatusNotFound)
+		return
+	}
+
+	pin, _ := strconv.ParseBool(r.URL.Query().Get("pin"))
+
+	a.sendRedeployRequest(w, r, redeployRequest{config: config, pin: pin})
+}
+
+// unpinConfiguration unpins the configuration, so that the latest built configuration is deployed again.
+func (a *API) unpinConfiguration(w http.ResponseWriter, r *http.Request) {
+	if !a.authorizeRedeploy(w, r) {
+		return
+	}
+
+	a.sendRedeployRequest(w, r, redeployRequest{})
+}
+
+// authorizeRedeploy checks that redeploys are enabled and that the request is authorized.
+// Requests are already authorized by the API authenticator if any, otherwise they must hold the API token.
+func (a *API) authorizeRedeploy(w http.ResponseWriter, r *http.Request) bool {
+	if a.redeployChan == nil || (a.authenticator == nil && a.apiToken == "") {
+		writeErrorResponse(w, "redeploy is disabled, no API authentication or token is configured", http.StatusForbidden)
+		return false
+	}
+
+	if a.authenticator != nil {
+		return true
+	}
+
+	if status, err := NewTokenAuthenticator(a.apiToken).Authorize(r); err != nil {
+		writeErrorResponse(w, err.Error(), status)
+		return false
+	}
+
+	return true
+}
+
+// sendRedeployRequest hands the request over to the controller.
+func (a *API) sendRedeployRequest(w http.ResponseWriter, r *http.Request, request redeployRequest) {
+	select {
+	case a.redeployChan <- request:
+		w.WriteHeader(http.StatusAccepted)
+	case <-r.Context().Done():
+		writeErrorResponse(w, "request canceled before the controller handled it", http.StatusServiceUnavailable)
+	}
+}
+
+// DisableReadiness disables the readiness flag in the API, for the controller to be removed from the endpoints while shutting down.
+func (a *API) DisableReadiness() {
+	a.readinessMu.Lock()
+	defer a.readinessMu.Unlock()
+
+	a.stopping = true
+
+	if a.readiness {
+		log.Debug("Controller Readiness disabled")
+
+		a.readiness = false
+
+		a.activity.Publish(ActivityEvent{
+			Type:    ActivityTypeReadiness,
+			Message: "controller not ready",
+			Data:    false,
+		})
+	}
+}
+
 // getReadiness returns the current readiness value, and sets the status code to 500 if not ready.
 func (a *API) getReadiness(w http.ResponseWriter, r *http.Request) {
-	if !a.readiness {
+	a.readinessMu.RLock()
+	readiness := a.readiness && !a.health.Degraded()
+	a.readinessMu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !readiness {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	if err := json.NewEncoder(w).Encode(readiness); err != nil {
+		log.Error(err)
+	}
+}
+
+// getLiveness returns true as long as the API serves requests.
+// Unlike the health, it does not depend on the cluster or the mesh nodes, which a restart of the controller would not fix.
+func (a *API) getLiveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(true); err != nil {
+		log.Error(err)
+	}
+}
+
+// getHealth returns the health report of the controller, and sets the status code to 500 if not healthy.
+func (a *API) getHealth(w http.ResponseWriter, r *http.Request) {
+	if a.health == nil {
+		writeErrorResponse(w, "health is not enabled", http.StatusNotFound)
+		return
+	}
+
+	var informers map[string]bool
+	if a.informerStates != nil {
+		informers = a.informerStates()
+	}
+
+	status := a.health.Status(time.Now(), informers, a.getStalePods())
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !status.Healthy {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Error(err)
+	}
+}
+
+// getStalePods returns the names of the mesh pods not running the current configuration, sorted.
+func (a *API) getStalePods() []string {
+	stalePods := []string{}
+
+	config, ok := a.lastConfiguration.Get().(*dynamic.Configuration)
+	if !ok || a.podLister == nil || a.configVersions == nil || !a.nodeVersionsKnown {
+		return stalePods
+	}
+
+	version := getConfigVersion(config)
+
+	podList, err := a.listMeshPods()
+	if err != nil {
+		log.Errorf("Unable to list mesh pods: %v", err)
+		return stalePods
+	}
+
+	for _, pod := range podList {
+		if a.configVersions.Get(pod.Name) != version {
+			stalePods = append(stalePods, pod.Name)
+		}
+	}
+
+	sort.Strings(stalePods)
+
+	return stalePods
+}
+
+// listMeshPods returns the mesh pods.
+func (a *API) listMeshPods() ([]*corev1.Pod, error) {
+	sel := labels.Everything()
+
+	requirement, err := labels.NewRequirement("component", selection.Equals, []string{"maesh-mesh"})
+	if err != nil {
+		return nil, err
+	}
+
+	sel = sel.Add(*requirement)
+
+	return a.podLister.Pods(a.meshNamespace).List(sel)
+}
+
+// getRolloutStatus returns the state of the last configuration rollout.
+func (a *API) getRolloutStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if err := json.NewEncoder(w).Encode(a.readiness); err != nil {
+	if err := json.NewEncoder(w).Encode(a.rollout.Status()); err != nil {
 		log.Error(err)
 	}
 }
 
-// getDeployLog returns the current deploylog.
+// getDeployLog returns the deploylog entries, filtered by the pod, success, since, until and limit parameters.
 func (a *API) getDeployLog(w http.ResponseWriter, r *http.Request) {
-	entries := a.deployLog.GetLog()
+	query, err := parseDeployLogQuery(r)
+	if err != nil {
+		writeErrorResponse(w, fmt.Sprintf("invalid deploy log query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	entries := a.deployLog.Query(query)
 
 	data, err := json.Marshal(entries)
 	if err != nil {
@@ -125,20 +755,117 @@ func (a *API) getDeployLog(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// getMeshNodes returns a list of mesh nodes visible from the controller, and some basic readiness info.
-func (a *API) getMeshNodes(w http.ResponseWriter, r *http.Request) {
-	podInfoList := []podInfo{}
-
-	sel := labels.Everything()
+// getDeployLogSummary returns the deployment summary of each pod.
+func (a *API) getDeployLogSummary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	requirement, err := labels.NewRequirement("component", selection.Equals, []string{"maesh-mesh"})
-	if err != nil {
+	if err := json.NewEncoder(w).Encode(a.deployLog.Summaries()); err != nil {
 		log.Error(err)
 	}
+}
 
-	sel = sel.Add(*requirement)
+// parseDeployLogQuery builds a deploy log query from the request parameters.
+func parseDeployLogQuery(r *http.Request) (DeployLogQuery, error) {
+	params := r.URL.Query()
+
+	query := DeployLogQuery{
+		PodName: params.Get("pod"),
+	}
+
+	if value := params.Get("success"); value != "" {
+		successful, err := strconv.ParseBool(value)
+		if err != nil {
+			return query, fmt.Errorf("invalid success value %q: %v", value, err)
+		}
+
+		query.Successful = &successful
+	}
 
-	podList, err := a.podLister.Pods(a.meshNamespace).List(sel)
+	for param, field := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		value := params.Get(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, fmt.Errorf("invalid %s value %q: %v", param, value, err)
+		}
+
+		*field = t
+	}
+
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return query, fmt.Errorf("invalid limit value %q", value)
+		}
+
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// streamEvents streams the controller activity as Server-Sent Events, filtered by the namespace and service parameters.
+func (a *API) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || a.activity == nil {
+		writeErrorResponse(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	service := r.URL.Query().Get("service")
+
+	events, unsubscribe := a.activity.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(activityKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.shutdownCh:
+			return
+		case <-keepAlive.C:
+			// Comments keep the connection open through proxies.
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event := <-events:
+			if !event.Matches(namespace, service) {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Errorf("unable to marshal activity event: %v", err)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+// getMeshNodes returns a list of mesh nodes visible from the controller, and some basic readiness info.
+func (a *API) getMeshNodes(w http.ResponseWriter, r *http.Request) {
+	podInfoList := []podInfo{}
+
+	podList, err := a.listMeshPods()
 	if err != nil {
 		writeErrorResponse(w, fmt.Sprintf("unable to retrieve pod list: %v", err), http.StatusInternalServerError)
 		return
@@ -160,6 +887,10 @@ func (a *API) getMeshNodes(w http.ResponseWriter, r *http.Request) {
 			IP:    pod.Status.PodIP,
 			Ready: readiness,
 		}
+
+		if a.configVersions != nil {
+			p.ConfigVersion = a.configVersions.Get(pod.Name)
+		}
 		podInfoList = append(podInfoList, p)
 	}
 
@@ -186,7 +917,13 @@ func (a *API) getMeshNodeConfiguration(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := http.Get(fmt.Sprintf("http://%s:8080/api/rawdata", pod.Status.PodIP))
+	req, err := http.NewRequest(http.MethodGet, a.meshNodes.URL(pod.Status.PodIP, "/api/rawdata"), nil)
+	if err != nil {
+		writeErrorResponse(w, fmt.Sprintf("unable to create request: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := a.meshNodes.Do(req.WithContext(r.Context()), 5*time.Second)
 	if err != nil {
 		writeErrorResponse(w, fmt.Sprintf("unable to get configuration from pod: %v", err), http.StatusBadGateway)
 		return
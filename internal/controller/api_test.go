@@ -1,20 +1,28 @@
 package controller
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
 	"github.com/containous/traefik/v2/pkg/safe"
 	"github.com/containous/traefik/v2/pkg/testhelpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestEnableReadiness(t *testing.T) {
 	config := safe.Safe{}
-	api := NewAPI(9000, &config, nil, nil, "foo")
+	api := NewAPI(9000, &config, nil, nil, nil, nil, nil, nil, "foo")
 
 	assert.Equal(t, false, api.readiness)
 
@@ -23,6 +31,41 @@ func TestEnableReadiness(t *testing.T) {
 	assert.Equal(t, true, api.readiness)
 }
 
+func TestDisableReadiness(t *testing.T) {
+	config := safe.Safe{}
+	api := NewAPI(9000, &config, nil, nil, nil, nil, nil, nil, "foo")
+
+	api.EnableReadiness()
+	api.DisableReadiness()
+
+	assert.Equal(t, false, api.readiness)
+
+	// The readiness can not be enabled again once the controller is shutting down.
+	api.EnableReadiness()
+
+	assert.Equal(t, false, api.readiness)
+}
+
+func TestShutdown(t *testing.T) {
+	config := safe.Safe{}
+	api := NewAPI(0, &config, nil, nil, nil, nil, nil, nil, "foo")
+	api.EnableDistribution(NewPublishedConfiguration(), 0)
+
+	api.Start()
+	require.NotNil(t, api.distributionServer)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, api.Shutdown(ctx))
+
+	select {
+	case <-api.shutdownCh:
+	case <-ctx.Done():
+		t.Fatal("the event streams were not notified of the shutdown")
+	}
+}
+
 func TestGetReadiness(t *testing.T) {
 	testCases := []struct {
 		desc               string
@@ -46,7 +89,7 @@ func TestGetReadiness(t *testing.T) {
 		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
 			config := safe.Safe{}
-			api := NewAPI(9000, &config, nil, nil, "foo")
+			api := NewAPI(9000, &config, nil, nil, nil, nil, nil, nil, "foo")
 			api.readiness = test.readiness
 
 			res := httptest.NewRecorder()
@@ -61,7 +104,7 @@ func TestGetReadiness(t *testing.T) {
 
 func TestGetCurrentConfiguration(t *testing.T) {
 	config := safe.Safe{}
-	api := NewAPI(9000, &config, nil, nil, "foo")
+	api := NewAPI(9000, &config, nil, nil, nil, nil, nil, nil, "foo")
 
 	config.Set("foo")
 
@@ -76,7 +119,7 @@ func TestGetCurrentConfiguration(t *testing.T) {
 func TestGetDeployLog(t *testing.T) {
 	config := safe.Safe{}
 	log := NewDeployLog(1000)
-	api := NewAPI(9000, &config, log, nil, "foo")
+	api := NewAPI(9000, &config, log, nil, nil, nil, nil, nil, "foo")
 
 	currentTime := time.Now()
 	log.LogDeploy(currentTime, "foo", "bar", true, "blabla")
@@ -94,3 +137,479 @@ func TestGetDeployLog(t *testing.T) {
 	assert.Equal(t, expected, res.Body.String())
 	assert.Equal(t, http.StatusOK, res.Code)
 }
+
+func TestRedeployConfiguration(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		apiToken           string
+		authorization      string
+		version            string
+		expectedStatusCode int
+		expectedRequest    *redeployRequest
+	}{
+		{
+			desc:               "redeploy disabled",
+			version:            "foo",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			desc:               "invalid token",
+			apiToken:           "secret",
+			authorization:      "Bearer wrong",
+			version:            "foo",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			desc:               "unknown version",
+			apiToken:           "secret",
+			authorization:      "Bearer secret",
+			version:            "bar",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			desc:               "redeploy and pin",
+			apiToken:           "secret",
+			authorization:      "Bearer secret",
+			version:            "foo",
+			expectedStatusCode: http.StatusAccepted,
+			expectedRequest:    &redeployRequest{pin: true},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			foo := buildVersionedConfig(t, "foo")
+
+			history := NewConfigHistory(10)
+			history.Add(HistoryEntry{Version: "foo"}, foo)
+
+			redeployChan := make(chan redeployRequest, 1)
+
+			config := safe.Safe{}
+			api := NewAPI(9000, &config, nil, nil, nil, history, nil, nil, "foo")
+			api.EnableRedeploy(test.apiToken, redeployChan)
+
+			res := httptest.NewRecorder()
+			req := testhelpers.MustNewRequest(http.MethodPost, "/api/configuration/"+test.version+"/redeploy?pin=true", nil)
+			req.Header.Set("Authorization", test.authorization)
+
+			api.router.ServeHTTP(res, req)
+
+			assert.Equal(t, test.expectedStatusCode, res.Code)
+
+			if test.expectedRequest == nil {
+				assert.Len(t, redeployChan, 0)
+				return
+			}
+
+			request := <-redeployChan
+			assert.True(t, request.config == foo)
+			assert.Equal(t, test.expectedRequest.pin, request.pin)
+		})
+	}
+}
+
+func TestGetConfigurationDiff(t *testing.T) {
+	history := NewConfigHistory(10)
+	history.Add(HistoryEntry{Version: "foo"}, buildVersionedConfig(t, "foo"))
+	history.Add(HistoryEntry{Version: "bar"}, buildVersionedConfig(t, "bar"))
+
+	config := safe.Safe{}
+	api := NewAPI(9000, &config, nil, nil, nil, history, nil, nil, "foo")
+
+	res := httptest.NewRecorder()
+	req := testhelpers.MustNewRequest(http.MethodGet, "/api/configuration/diff?from=foo&to=bar", nil)
+
+	api.router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "{\"From\":\"foo\",\"To\":\"bar\",\"Added\":null,\"Removed\":null,\"Changed\":null}\n", res.Body.String())
+
+	res = httptest.NewRecorder()
+	req = testhelpers.MustNewRequest(http.MethodGet, "/api/configuration/diff?from=foo&to=baz", nil)
+
+	api.router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestStreamEvents(t *testing.T) {
+	activity := NewActivityStream()
+
+	config := safe.Safe{}
+	api := NewAPI(9000, &config, nil, nil, nil, nil, activity, nil, "foo")
+
+	server := httptest.NewServer(api.router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/events?namespace=foo")
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	activity.Publish(ActivityEvent{Type: ActivityTypeInformer, Namespace: "bar", Message: "filtered out"})
+	activity.Publish(ActivityEvent{Type: ActivityTypeInformer, Namespace: "foo", Message: "added Service foo/baz"})
+
+	reader := bufio.NewReader(resp.Body)
+
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "event: informer\n", line)
+
+	line, err = reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Contains(t, line, "added Service foo/baz")
+}
+
+func TestGetDeployLogQuery(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		query              string
+		expectedStatusCode int
+		expectedPods       []string
+	}{
+		{
+			desc:               "pod and success",
+			query:              "?pod=foo&success=false",
+			expectedStatusCode: http.StatusOK,
+			expectedPods:       []string{"foo"},
+		},
+		{
+			desc:               "time range and limit",
+			query:              "?since=2020-01-01T00:00:00Z&until=2020-01-01T00:05:00Z&limit=1",
+			expectedStatusCode: http.StatusOK,
+			expectedPods:       []string{"bar"},
+		},
+		{
+			desc:               "invalid time",
+			query:              "?since=yesterday",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			desc:               "invalid limit",
+			query:              "?limit=-1",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	log := NewDeployLog(10)
+	log.LogDeploy(start, "foo", "10.0.0.1", false, "boom")
+	log.LogDeploy(start.Add(time.Minute), "foo", "10.0.0.1", true, "")
+	log.LogDeploy(start.Add(2*time.Minute), "bar", "10.0.0.2", true, "")
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			config := safe.Safe{}
+			api := NewAPI(9000, &config, log, nil, nil, nil, nil, nil, "foo")
+
+			res := httptest.NewRecorder()
+			req := testhelpers.MustNewRequest(http.MethodGet, "/api/log/deployment"+test.query, nil)
+
+			api.router.ServeHTTP(res, req)
+
+			assert.Equal(t, test.expectedStatusCode, res.Code)
+
+			if test.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var entries []Entry
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &entries))
+
+			var pods []string
+			for _, entry := range entries {
+				pods = append(pods, entry.PodName)
+			}
+
+			assert.Equal(t, test.expectedPods, pods)
+		})
+	}
+}
+
+func TestGetHealth(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+
+	for _, name := range []string{"maesh-mesh-a", "maesh-mesh-b"} {
+		pod := buildMeshPod(name)
+		pod.Labels = map[string]string{"component": "maesh-mesh"}
+
+		require.NoError(t, indexer.Add(pod))
+	}
+
+	current := buildVersionedConfig(t, "v2")
+
+	configVersions := NewConfigVersions()
+	configVersions.Set("maesh-mesh-a", "v2")
+	configVersions.Set("maesh-mesh-b", "v1")
+
+	config := safe.Safe{}
+	config.Set(current)
+
+	api := NewAPI(9000, &config, nil, configVersions, nil, nil, nil, listers.NewPodLister(indexer), "maesh")
+	api.EnableHealth(NewHealth(1), func() map[string]bool {
+		return map[string]bool{"*v1.Pod": true}
+	})
+
+	res := httptest.NewRecorder()
+	api.getHealth(res, testhelpers.MustNewRequest(http.MethodGet, "/api/status/health", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var status HealthStatus
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+
+	assert.True(t, status.Healthy)
+	assert.Equal(t, []string{"maesh-mesh-b"}, status.StalePods)
+
+	// A failed deployment degrades the health and the readiness.
+	api.EnableReadiness()
+	api.health.RecordDeploy(fmt.Errorf("boom"))
+
+	res = httptest.NewRecorder()
+	api.getHealth(res, testhelpers.MustNewRequest(http.MethodGet, "/api/status/health", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+
+	res = httptest.NewRecorder()
+	api.getReadiness(res, testhelpers.MustNewRequest(http.MethodGet, "/api/status/readiness", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+
+	// The liveness does not depend on the health.
+	res = httptest.NewRecorder()
+	api.router.ServeHTTP(res, testhelpers.MustNewRequest(http.MethodGet, "/api/status/liveness", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestGetTopology(t *testing.T) {
+	testCases := []struct {
+		desc                string
+		topology            *Topology
+		topologyErr         error
+		format              string
+		expectedStatusCode  int
+		expectedContentType string
+	}{
+		{
+			desc:                "json",
+			topology:            &Topology{},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/json",
+		},
+		{
+			desc:                "dot",
+			topology:            &Topology{},
+			format:              "dot",
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "text/vnd.graphviz",
+		},
+		{
+			desc:               "unsupported format",
+			topology:           &Topology{},
+			format:             "svg",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			desc:               "topology not built",
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			desc:               "topology build error",
+			topologyErr:        errors.New("boom"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			config := safe.Safe{}
+			api := NewAPI(9000, &config, nil, nil, nil, nil, nil, nil, "foo")
+			api.EnableTopology(func() (*Topology, error) {
+				return test.topology, test.topologyErr
+			})
+
+			res := httptest.NewRecorder()
+			req := testhelpers.MustNewRequest(http.MethodGet, "/api/topology?format="+test.format, nil)
+
+			api.getTopology(res, req)
+
+			assert.Equal(t, test.expectedStatusCode, res.Code)
+
+			if test.expectedContentType != "" {
+				assert.Equal(t, test.expectedContentType, res.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestGetExplanation(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		query              string
+		expectedStatusCode int
+		expectedRequest    ExplainRequest
+	}{
+		{
+			desc:               "valid request",
+			query:              "?from=foo/client&to=bar/api&port=80&path=/api&method=get",
+			expectedStatusCode: http.StatusOK,
+			expectedRequest:    ExplainRequest{FromNamespace: "foo", FromName: "client", ToNamespace: "bar", ToName: "api", Port: 80, Path: "/api", Method: "GET"},
+		},
+		{
+			desc:               "missing namespace",
+			query:              "?from=client&to=bar/api",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			desc:               "invalid port",
+			query:              "?from=foo/client&to=bar/api&port=http",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var actual ExplainRequest
+
+			config := safe.Safe{}
+			api := NewAPI(9000, &config, nil, nil, nil, nil, nil, nil, "foo")
+			api.EnableExplain(func(request ExplainRequest, _ *dynamic.Configuration) (*Explanation, error) {
+				actual = request
+				return &Explanation{Allowed: true}, nil
+			})
+
+			res := httptest.NewRecorder()
+			api.getExplanation(res, testhelpers.MustNewRequest(http.MethodGet, "/api/explain"+test.query, nil))
+
+			assert.Equal(t, test.expectedStatusCode, res.Code)
+			assert.Equal(t, test.expectedRequest, actual)
+		})
+	}
+}
+
+func TestGetServiceView(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		path               string
+		expectedStatusCode int
+	}{
+		{
+			desc:               "existing service",
+			path:               "/api/services/foo/api",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			desc:               "unknown service",
+			path:               "/api/services/foo/unknown",
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			config := safe.Safe{}
+			api := NewAPI(9000, &config, nil, nil, nil, nil, nil, nil, "foo")
+			api.EnableServiceView(func(namespace, name string, _ *dynamic.Configuration) (*ServiceView, error) {
+				if name != "api" {
+					return nil, nil
+				}
+
+				return &ServiceView{Namespace: namespace, Name: name}, nil
+			})
+
+			res := httptest.NewRecorder()
+			api.router.ServeHTTP(res, testhelpers.MustNewRequest(http.MethodGet, test.path, nil))
+
+			assert.Equal(t, test.expectedStatusCode, res.Code)
+		})
+	}
+}
+
+func TestGetMeshConfiguration(t *testing.T) {
+	published, err := withConfigVersion(&dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{}})
+	require.NoError(t, err)
+
+	version := getConfigVersion(published)
+
+	testCases := []struct {
+		desc               string
+		disabled           bool
+		publish            bool
+		path               string
+		expectedStatusCode int
+		expectedVersion    string
+	}{
+		{
+			desc:               "distribution not enabled",
+			disabled:           true,
+			path:               "/api/configuration/mesh",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			desc:               "nothing published",
+			path:               "/api/configuration/mesh",
+			expectedStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			desc:               "published configuration",
+			publish:            true,
+			path:               "/api/configuration/mesh",
+			expectedStatusCode: http.StatusOK,
+			expectedVersion:    version,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			config := safe.Safe{}
+			api := NewAPI(9000, &config, nil, nil, nil, nil, nil, nil, "foo")
+
+			if !test.disabled {
+				distribution := NewPublishedConfiguration()
+				if test.publish {
+					require.NoError(t, distribution.Publish(published))
+				}
+
+				api.EnableDistribution(distribution, 9001)
+			}
+
+			res := httptest.NewRecorder()
+			api.distributionRouter.ServeHTTP(res, testhelpers.MustNewRequest(http.MethodGet, test.path, nil))
+
+			assert.Equal(t, test.expectedStatusCode, res.Code)
+
+			if test.expectedVersion == "" {
+				return
+			}
+
+			assert.Equal(t, test.expectedVersion, res.Header().Get(configVersionHeader))
+
+			var served dynamic.Configuration
+			require.NoError(t, json.Unmarshal(res.Body.Bytes(), &served))
+			assert.Equal(t, test.expectedVersion, getConfigVersion(&served))
+		})
+	}
+}
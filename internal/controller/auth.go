@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// API authentication modes.
+const (
+	APIAuthNone       = "none"
+	APIAuthToken      = "token"
+	APIAuthKubernetes = "kubernetes"
+)
+
+// authDecisionTTL is the time an authorization decision of the Kubernetes API is cached.
+const authDecisionTTL = 30 * time.Second
+
+// maxAuthDecisions is the maximum number of cached authorization decisions.
+const maxAuthDecisions = 1000
+
+// Authenticator authenticates and authorizes the API requests.
+type Authenticator interface {
+	// Authorize returns an error, and the matching response status code, if the request is not allowed.
+	Authorize(r *http.Request) (int, error)
+}
+
+// TokenAuthenticator allows the requests holding a static bearer token.
+type TokenAuthenticator struct {
+	token string
+}
+
+// NewTokenAuthenticator returns an authenticator allowing the requests holding the given bearer token.
+func NewTokenAuthenticator(token string) *TokenAuthenticator {
+	return &TokenAuthenticator{token: token}
+}
+
+// Authorize checks the request bearer token.
+func (a *TokenAuthenticator) Authorize(r *http.Request) (int, error) {
+	token, ok := bearerToken(r)
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		return http.StatusUnauthorized, errors.New("invalid API token")
+	}
+
+	return http.StatusOK, nil
+}
+
+type authDecision struct {
+	status  int
+	err     error
+	expires time.Time
+}
+
+// KubernetesAuthenticator authenticates the request bearer tokens with TokenReviews,
+// and authorizes the requests with SubjectAccessReviews on the request path and verb, like
+// any other non-resource URL of the Kubernetes API. The decisions are cached for a short time.
+type KubernetesAuthenticator struct {
+	client kubernetes.Interface
+
+	mu        sync.Mutex
+	decisions map[string]authDecision
+}
+
+// NewKubernetesAuthenticator returns an authenticator relying on the Kubernetes API.
+func NewKubernetesAuthenticator(client kubernetes.Interface) *KubernetesAuthenticator {
+	return &KubernetesAuthenticator{
+		client:    client,
+		decisions: make(map[string]authDecision),
+	}
+}
+
+// Authorize reviews the request bearer token, and checks that its user can access the request path with the request verb.
+func (a *KubernetesAuthenticator) Authorize(r *http.Request) (int, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return http.StatusUnauthorized, errors.New("missing bearer token")
+	}
+
+	verb := requestVerb(r)
+
+	sum := sha256.Sum256([]byte(token))
+	key := hex.EncodeToString(sum[:]) + " " + verb + " " + r.URL.Path
+
+	a.mu.Lock()
+	decision, ok := a.decisions[key]
+	a.mu.Unlock()
+
+	if ok && time.Now().Before(decision.expires) {
+		return decision.status, decision.err
+	}
+
+	status, err := a.review(token, verb, r.URL.Path)
+
+	// Errors of the Kubernetes API are not cached, so that the next request reviews the token again.
+	if status != http.StatusInternalServerError {
+		a.mu.Lock()
+		if len(a.decisions) >= maxAuthDecisions {
+			a.decisions = make(map[string]authDecision)
+		}
+
+		a.decisions[key] = authDecision{status: status, err: err, expires: time.Now().Add(authDecisionTTL)}
+		a.mu.Unlock()
+	}
+
+	return status, err
+}
+
+func (a *KubernetesAuthenticator) review(token, verb, path string) (int, error) {
+	tokenReview, err := a.client.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("unable to review token: %v", err)
+	}
+
+	if !tokenReview.Status.Authenticated {
+		return http.StatusUnauthorized, fmt.Errorf("invalid token: %s", tokenReview.Status.Error)
+	}
+
+	user := tokenReview.Status.User
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	accessReview, err := a.client.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		ObjectMeta: metav1.ObjectMeta{},
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			NonResourceAttributes: &authorizationv1.NonResourceAttributes{
+				Path: path,
+				Verb: verb,
+			},
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+		},
+	})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("unable to review access: %v", err)
+	}
+
+	if !accessReview.Status.Allowed {
+		return http.StatusForbidden, fmt.Errorf("user %q is not allowed to %s %s", user.Username, verb, path)
+	}
+
+	return http.StatusOK, nil
+}
+
+// requestVerb returns the Kubernetes verb matching the request method.
+func requestVerb(r *http.Request) string {
+	switch r.Method {
+	case http.MethodPost:
+		return "create"
+	case http.MethodPut:
+		return "update"
+	case http.MethodPatch:
+		return "patch"
+	case http.MethodDelete:
+		return "delete"
+	default:
+		return "get"
+	}
+}
+
+// bearerToken returns the bearer token of the request Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+
+	return token, token != ""
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/v2/pkg/testhelpers"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestTokenAuthenticator(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		authorization      string
+		expectedStatusCode int
+	}{
+		{
+			desc:               "valid token",
+			authorization:      "Bearer secret",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			desc:               "invalid token",
+			authorization:      "Bearer wrong",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			desc:               "missing token",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := testhelpers.MustNewRequest(http.MethodGet, "/api/configuration/current", nil)
+			req.Header.Set("Authorization", test.authorization)
+
+			status, _ := NewTokenAuthenticator("secret").Authorize(req)
+			assert.Equal(t, test.expectedStatusCode, status)
+		})
+	}
+}
+
+func TestKubernetesAuthenticator(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		method             string
+		authorization      string
+		expectedStatusCode int
+	}{
+		{
+			desc:               "allowed read",
+			method:             http.MethodGet,
+			authorization:      "Bearer reader",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			desc:               "forbidden write",
+			method:             http.MethodPost,
+			authorization:      "Bearer reader",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			desc:               "unauthenticated token",
+			method:             http.MethodGet,
+			authorization:      "Bearer unknown",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			desc:               "missing token",
+			method:             http.MethodGet,
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset()
+
+			reviews := 0
+			client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				reviews++
+
+				review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+				if review.Spec.Token == "reader" {
+					review.Status.Authenticated = true
+					review.Status.User = authenticationv1.UserInfo{Username: "reader", Groups: []string{"readers"}}
+				}
+
+				return true, review, nil
+			})
+			client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				review.Status.Allowed = review.Spec.User == "reader" && review.Spec.NonResourceAttributes.Verb == "get"
+
+				return true, review, nil
+			})
+
+			authenticator := NewKubernetesAuthenticator(client)
+
+			for i := 0; i < 2; i++ {
+				req := testhelpers.MustNewRequest(test.method, "/api/configuration/current", nil)
+				req.Header.Set("Authorization", test.authorization)
+
+				status, _ := authenticator.Authorize(req)
+				assert.Equal(t, test.expectedStatusCode, status)
+			}
+
+			// The second request is served from the cache.
+			assert.LessOrEqual(t, reviews, 1)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	api := NewAPI(9000, nil, nil, nil, nil, nil, nil, nil, "foo")
+	api.EnableAuthentication(NewTokenAuthenticator("secret"))
+
+	handler := api.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	testCases := []struct {
+		desc               string
+		path               string
+		authorization      string
+		expectedStatusCode int
+	}{
+		{
+			desc:               "readiness without credentials",
+			path:               "/api/status/readiness",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			desc:               "liveness without credentials",
+			path:               "/api/status/liveness",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			desc:               "health without credentials",
+			path:               "/api/status/health",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			desc:               "configuration without credentials",
+			path:               "/api/configuration/current",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			desc:               "configuration with credentials",
+			path:               "/api/configuration/current",
+			authorization:      "Bearer secret",
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			res := httptest.NewRecorder()
+			req := testhelpers.MustNewRequest(http.MethodGet, test.path, nil)
+			req.Header.Set("Authorization", test.authorization)
+
+			handler.ServeHTTP(res, req)
+
+			assert.Equal(t, test.expectedStatusCode, res.Code)
+		})
+	}
+}
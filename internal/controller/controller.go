@@ -9,20 +9,21 @@ import (
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/abronan/valkeyrie/store"
 	"github.com/cenkalti/backoff/v3"
+	"github.com/containous/maesh/internal/dns"
 	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/kv"
 	"github.com/containous/maesh/internal/providers/base"
 	"github.com/containous/maesh/internal/providers/kubernetes"
 	"github.com/containous/maesh/internal/providers/smi"
 	"github.com/containous/traefik/v2/pkg/config/dynamic"
 	"github.com/containous/traefik/v2/pkg/safe"
-	accessInformer "github.com/deislabs/smi-sdk-go/pkg/gen/client/access/informers/externalversions"
 	accessLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/access/listers/access/v1alpha1"
-	specsInformer "github.com/deislabs/smi-sdk-go/pkg/gen/client/specs/informers/externalversions"
 	specsLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/specs/listers/specs/v1alpha1"
-	splitInformer "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/informers/externalversions"
 	splitLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/listers/split/v1alpha2"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
@@ -35,29 +36,63 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/informers"
 	listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/retry"
 )
 
 // Controller hold controller configuration.
 type Controller struct {
-	clients              *k8s.ClientWrapper
-	kubernetesFactory    informers.SharedInformerFactory
-	smiAccessFactory     accessInformer.SharedInformerFactory
-	smiSpecsFactory      specsInformer.SharedInformerFactory
-	smiSplitFactory      splitInformer.SharedInformerFactory
-	handler              *Handler
-	configRefreshChan    chan string
-	provider             base.Provider
-	ignored              k8s.IgnoreWrapper
-	smiEnabled           bool
-	defaultMode          string
-	meshNamespace        string
-	tcpStateTable        *k8s.State
-	lastConfiguration    safe.Safe
+	clients             *k8s.ClientWrapper
+	kubernetesFactories k8s.NamespacedInformerFactories
+	meshFactory         informers.SharedInformerFactory
+	meshPodFactory      informers.SharedInformerFactory
+	namespaceFactory    informers.SharedInformerFactory
+	endpointSlices      *k8s.EndpointSliceInformers
+	smiFactories        k8s.NamespacedSMIInformerFactories
+	handler             *Handler
+	configRefreshChan   chan string
+	provider            base.Provider
+	ignored             k8s.IgnoreWrapper
+	smiEnabled          bool
+	defaultMode         string
+	meshNamespace       string
+	watchNamespaces     []string
+	tcpStateTable       *k8s.State
+	lastConfiguration   safe.Safe
+	lastBuiltConfig     *dynamic.Configuration
+	topology            topologyCache
+	configVersions      *ConfigVersions
+	rolloutConfig       RolloutConfig
+	rollout             *Rollout
+	history             *ConfigHistory
+	activity            *ActivityStream
+	pinnedConfiguration *dynamic.Configuration
+	redeployChan        chan redeployRequest
+	apiToken            string
+	apiAuth             string
+	apiTLSCertFile      string
+	apiTLSKeyFile       string
+	shutdownTimeout     time.Duration
+	health              *Health
+	meshNodes           *MeshNodeClient
+	distributionMode    string
+	distributionPort    int
+	publisher           configPublisher
+	kvStore             store.Store
+	kvRootKey           string
+	// rolledBackVersion is the version of the last configuration rolled back, not deployed again until another one is built.
+	rolledBackVersion string
+	// unreadyDeployInterval is the period of the deploys to the unready mesh nodes.
+	unreadyDeployInterval time.Duration
+	// deployRetryTimeout is the time spent retrying a failed deploy to a mesh node.
+	deployRetryTimeout   time.Duration
 	api                  *API
 	apiPort              int
+	dnsServer            *dns.Server
+	dnsServerPort        int
 	deployLog            *DeployLog
 	PodLister            listers.PodLister
+	MeshPodLister        listers.PodLister
 	ConfigMapLister      listers.ConfigMapLister
 	ServiceLister        listers.ServiceLister
 	EndpointsLister      listers.EndpointsLister
@@ -67,32 +102,88 @@ type Controller struct {
 	TrafficSplitLister   splitLister.TrafficSplitLister
 }
 
+// MeshControllerConfig holds the configuration of the mesh controller.
+type MeshControllerConfig struct {
+	SMIEnabled       bool
+	DefaultMode      string
+	Namespace        string
+	IgnoreNamespaces []string
+	APIPort          int
+	DNSServerPort    int
+	// WatchNamespaces restricts the namespaces watched by the controller, all of them if empty.
+	WatchNamespaces []string
+	// NamespaceSelector selects the namespaces whose services are part of the mesh.
+	NamespaceSelector labels.Selector
+	// ServiceSelector selects the services part of the mesh.
+	ServiceSelector labels.Selector
+	// Rollout is the strategy used to roll out new configurations across the mesh nodes.
+	Rollout RolloutConfig
+	// APIToken is the bearer token required by the API endpoints changing the deployed configuration, disabled if empty.
+	APIToken string
+	// APIAuth is the authentication mode of the API requests: none, token or kubernetes.
+	APIAuth string
+	// APITLSCertFile and APITLSKeyFile enable TLS on the API when both are set.
+	APITLSCertFile string
+	APITLSKeyFile  string
+	// ReadinessFailureThreshold is the number of consecutive failed rebuilds or deploys after which the readiness is reported as false.
+	// The readiness does not degrade if 0.
+	ReadinessFailureThreshold int
+	// ShutdownTimeout is the time given to the in-flight deployments and API requests to complete on shutdown.
+	ShutdownTimeout time.Duration
+	// MeshNodes is the client of the mesh nodes API, served on 8080 over plain HTTP without authentication if not set.
+	MeshNodes *MeshNodeClient
+	// DistributionMode is how the configuration reaches the mesh nodes: push (the default), pull or kv.
+	DistributionMode string
+	// DistributionPort is the port of the listener serving the configuration to the mesh nodes in pull mode.
+	DistributionPort int
+	// KVStore and KVRootKey are the store and the root key the configuration is written under in kv mode.
+	KVStore   store.Store
+	KVRootKey string
+}
+
 // NewMeshController is used to build the informers and other required components of the mesh controller,
 // and return an initialized mesh controller object.
-func NewMeshController(clients *k8s.ClientWrapper, smiEnabled bool, defaultMode string, meshNamespace string, ignoreNamespaces []string, apiPort int) *Controller {
-	ignored := k8s.NewIgnored()
+func NewMeshController(clients *k8s.ClientWrapper, cfg MeshControllerConfig) *Controller {
+	ignored := k8s.NewDefaultIgnored(cfg.IgnoreNamespaces)
 
-	for _, ns := range ignoreNamespaces {
-		ignored.AddIgnoredNamespace(ns)
+	if cfg.NamespaceSelector != nil {
+		ignored.NamespaceSelector = cfg.NamespaceSelector
 	}
 
-	ignored.AddIgnoredService("kubernetes", metav1.NamespaceDefault)
-	ignored.AddIgnoredNamespace(metav1.NamespaceSystem)
-	ignored.AddIgnoredApps("maesh", "jaeger")
-
-	// configRefreshChan is used to trigger configuration refreshes and deploys.
-	configRefreshChan := make(chan string)
-	handler := NewHandler(ignored, configRefreshChan)
+	if cfg.ServiceSelector != nil {
+		ignored.ServiceSelector = cfg.ServiceSelector
+	}
 
 	c := &Controller{
-		clients:           clients,
-		handler:           handler,
-		configRefreshChan: configRefreshChan,
-		ignored:           ignored,
-		smiEnabled:        smiEnabled,
-		defaultMode:       defaultMode,
-		meshNamespace:     meshNamespace,
-		apiPort:           apiPort,
+		clients: clients,
+		// configRefreshChan is used to trigger configuration refreshes and deploys.
+		configRefreshChan:     make(chan string),
+		redeployChan:          make(chan redeployRequest),
+		ignored:               ignored,
+		smiEnabled:            cfg.SMIEnabled,
+		defaultMode:           cfg.DefaultMode,
+		meshNamespace:         cfg.Namespace,
+		watchNamespaces:       cfg.WatchNamespaces,
+		apiPort:               cfg.APIPort,
+		dnsServerPort:         cfg.DNSServerPort,
+		rolloutConfig:         cfg.Rollout,
+		apiToken:              cfg.APIToken,
+		apiAuth:               cfg.APIAuth,
+		apiTLSCertFile:        cfg.APITLSCertFile,
+		apiTLSKeyFile:         cfg.APITLSKeyFile,
+		shutdownTimeout:       cfg.ShutdownTimeout,
+		health:                NewHealth(cfg.ReadinessFailureThreshold),
+		meshNodes:             cfg.MeshNodes,
+		distributionMode:      cfg.DistributionMode,
+		distributionPort:      cfg.DistributionPort,
+		kvStore:               cfg.KVStore,
+		kvRootKey:             cfg.KVRootKey,
+		unreadyDeployInterval: 10 * time.Second,
+		deployRetryTimeout:    15 * time.Second,
+	}
+
+	if c.meshNodes == nil {
+		c.meshNodes = newDefaultMeshNodeClient()
 	}
 
 	if err := c.Init(); err != nil {
@@ -104,51 +195,135 @@ func NewMeshController(clients *k8s.ClientWrapper, smiEnabled bool, defaultMode
 
 // Init the Controller.
 func (c *Controller) Init() error {
+	// Create the SharedInformerFactories of the watched namespaces, and the base listers.
+	c.kubernetesFactories = k8s.NewNamespacedInformerFactories(c.clients.KubeClient, c.getWatchedNamespaces(), k8s.ResyncPeriod)
+
+	c.ServiceLister = c.kubernetesFactories.ServiceLister()
+
+	// EndpointSlices are used when served by the cluster, Endpoints otherwise.
+	if resource, ok := k8s.GetEndpointSliceResource(c.clients.KubeClient.Discovery()); ok {
+		log.Infof("Using EndpointSlices from %s", resource.GroupVersion())
+
+		var err error
+
+		c.endpointSlices, err = k8s.NewEndpointSliceInformers(c.clients.DynamicClient, resource, c.getWatchedNamespaces(), k8s.ResyncPeriod)
+		if err != nil {
+			return err
+		}
+
+		c.EndpointsLister = c.endpointSlices.Lister()
+	} else {
+		c.EndpointsLister = c.kubernetesFactories.EndpointsLister()
+	}
+
+	// The TCP state table only lives in the mesh namespace.
+	c.meshFactory = informers.NewSharedInformerFactoryWithOptions(c.clients.KubeClient, k8s.ResyncPeriod, informers.WithNamespace(c.meshNamespace))
+	c.ConfigMapLister = c.meshFactory.Core().V1().ConfigMaps().Lister()
+
+	// Mesh pods are watched on their own, so that user pods events don't go through the handler.
+	c.meshPodFactory = informers.NewSharedInformerFactoryWithOptions(c.clients.KubeClient, k8s.ResyncPeriod,
+		informers.WithNamespace(c.meshNamespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = meshPodLabelSelector
+		}),
+	)
+	c.MeshPodLister = c.meshPodFactory.Core().V1().Pods().Lister()
+
+	// Namespaces are only watched when they are used to select the services part of the mesh.
+	if !c.ignored.NamespaceSelector.Empty() {
+		c.namespaceFactory = informers.NewSharedInformerFactoryWithOptions(c.clients.KubeClient, k8s.ResyncPeriod)
+		c.ignored.NamespaceLister = c.namespaceFactory.Core().V1().Namespaces().Lister()
+	}
+
+	c.handler = NewHandler(c.ignored, c.ServiceLister, c.configRefreshChan)
+
 	// Register handler funcs to controller funcs.
 	c.handler.RegisterMeshHandlers(c.createMeshService, c.updateMeshService, c.deleteMeshService)
+	c.handler.RegisterSyncMeshServicesHandler(c.syncMeshServices)
 
-	// Create a new SharedInformerFactory, and register the event handler to informers.
-	c.kubernetesFactory = informers.NewSharedInformerFactoryWithOptions(c.clients.KubeClient, k8s.ResyncPeriod)
-	c.kubernetesFactory.Core().V1().Services().Informer().AddEventHandler(c.handler)
-	c.kubernetesFactory.Core().V1().Endpoints().Informer().AddEventHandler(c.handler)
-	c.kubernetesFactory.Core().V1().Pods().Informer().AddEventHandler(c.handler)
+	// Register the event handler to informers.
+	c.kubernetesFactories.AddServiceEventHandler(c.handler)
 
-	c.tcpStateTable = &k8s.State{Table: make(map[int]*k8s.ServiceWithPort)}
+	if c.endpointSlices != nil {
+		c.endpointSlices.AddEventHandler(c.handler)
+	} else {
+		c.kubernetesFactories.AddEndpointsEventHandler(c.handler)
+	}
 
-	// Create the base listers
-	c.PodLister = c.kubernetesFactory.Core().V1().Pods().Lister()
-	c.ConfigMapLister = c.kubernetesFactory.Core().V1().ConfigMaps().Lister()
-	c.ServiceLister = c.kubernetesFactory.Core().V1().Services().Lister()
-	c.EndpointsLister = c.kubernetesFactory.Core().V1().Endpoints().Lister()
+	c.meshPodFactory.Core().V1().Pods().Informer().AddEventHandler(c.handler)
+
+	if c.namespaceFactory != nil {
+		c.namespaceFactory.Core().V1().Namespaces().Informer().AddEventHandler(c.handler)
+	}
+
+	c.tcpStateTable = &k8s.State{Table: make(map[int]*k8s.ServiceWithPort)}
 
 	c.deployLog = NewDeployLog(1000)
-	c.api = NewAPI(c.apiPort, &c.lastConfiguration, c.deployLog, c.PodLister, c.meshNamespace)
+	c.configVersions = NewConfigVersions()
+	c.rollout = NewRollout(c.rolloutConfig, c.deployToPods, c.verifyPods)
+	c.history = NewConfigHistory(20)
+	c.activity = NewActivityStream()
+	c.handler.RegisterActivityStream(c.activity)
+	c.api = NewAPI(c.apiPort, &c.lastConfiguration, c.deployLog, c.configVersions, c.rollout, c.history, c.activity, c.MeshPodLister, c.meshNamespace)
+	c.api.EnableRedeploy(c.apiToken, c.redeployChan)
+	c.api.EnableHealth(c.health, c.informerSyncStates)
+	c.api.EnableTopology(c.getTopology)
+	c.api.EnableExplain(c.explain)
+	c.api.EnableServiceView(c.viewService)
+	c.api.SetMeshNodeClient(c.meshNodes)
+
+	switch c.distributionMode {
+	case DistributionModePull:
+		published := NewPublishedConfiguration()
+		c.api.EnableDistribution(published, c.distributionPort)
+		c.publisher = published
+	case DistributionModeKV:
+		c.publisher = kv.NewPublisher(c.kvStore, c.kvRootKey)
+		c.api.DisableNodeVersions()
+	}
 
-	if c.smiEnabled {
-		// Create new SharedInformerFactories, and register the event handler to informers.
-		c.smiAccessFactory = accessInformer.NewSharedInformerFactoryWithOptions(c.clients.SmiAccessClient, k8s.ResyncPeriod)
-		c.smiAccessFactory.Access().V1alpha1().TrafficTargets().Informer().AddEventHandler(c.handler)
+	switch c.apiAuth {
+	case APIAuthToken:
+		c.api.EnableAuthentication(NewTokenAuthenticator(c.apiToken))
+	case APIAuthKubernetes:
+		c.api.EnableAuthentication(NewKubernetesAuthenticator(c.clients.KubeClient))
+	}
+
+	if c.apiTLSCertFile != "" && c.apiTLSKeyFile != "" {
+		c.api.EnableTLS(c.apiTLSCertFile, c.apiTLSKeyFile)
+	}
 
-		c.smiSpecsFactory = specsInformer.NewSharedInformerFactoryWithOptions(c.clients.SmiSpecsClient, k8s.ResyncPeriod)
-		c.smiSpecsFactory.Specs().V1alpha1().HTTPRouteGroups().Informer().AddEventHandler(c.handler)
-		c.smiSpecsFactory.Specs().V1alpha1().TCPRoutes().Informer().AddEventHandler(c.handler)
+	if c.dnsServerPort > 0 {
+		c.dnsServer = dns.NewServer(c.dnsServerPort, c.meshNamespace, c.ServiceLister)
+	}
 
-		c.smiSplitFactory = splitInformer.NewSharedInformerFactoryWithOptions(c.clients.SmiSplitClient, k8s.ResyncPeriod)
-		c.smiSplitFactory.Split().V1alpha2().TrafficSplits().Informer().AddEventHandler(c.handler)
+	if c.smiEnabled {
+		// Create the SMI informer factories of the watched namespaces, and register the event handler to informers.
+		c.smiFactories = k8s.NewNamespacedSMIInformerFactories(c.clients, c.getWatchedNamespaces(), k8s.ResyncPeriod)
+		c.smiFactories.AddEventHandler(c.handler)
+
+		// User pods are the SMI sources, the events of the mesh pods are handled by their own informer.
+		c.PodLister = c.kubernetesFactories.PodLister()
+		c.kubernetesFactories.AddPodEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: isUserPod,
+			Handler:    c.handler,
+		})
 
 		// Create the SMI listers
-		c.TrafficTargetLister = c.smiAccessFactory.Access().V1alpha1().TrafficTargets().Lister()
-		c.HTTPRouteGroupLister = c.smiSpecsFactory.Specs().V1alpha1().HTTPRouteGroups().Lister()
-		c.TCPRouteLister = c.smiSpecsFactory.Specs().V1alpha1().TCPRoutes().Lister()
-		c.TrafficSplitLister = c.smiSplitFactory.Split().V1alpha2().TrafficSplits().Lister()
+		c.TrafficTargetLister = c.smiFactories.TrafficTargetLister()
+		c.HTTPRouteGroupLister = c.smiFactories.HTTPRouteGroupLister()
+		c.TCPRouteLister = c.smiFactories.TCPRouteLister()
+		c.TrafficSplitLister = c.smiFactories.TrafficSplitLister()
 
 		c.provider = smi.New(c.defaultMode, c.tcpStateTable, c.ignored, c.ServiceLister, c.EndpointsLister, c.PodLister, c.TrafficTargetLister, c.HTTPRouteGroupLister, c.TCPRouteLister, c.TrafficSplitLister)
+		c.handler.RegisterInvalidator(c.provider)
 
 		return nil
 	}
 
 	// If SMI is not configured, use the kubernetes provider.
 	c.provider = kubernetes.New(c.defaultMode, c.tcpStateTable, c.ignored, c.ServiceLister, c.EndpointsLister)
+	c.handler.RegisterInvalidator(c.provider)
 
 	return nil
 }
@@ -180,42 +355,259 @@ func (c *Controller) Run(stopCh <-chan struct{}) error {
 	// Start the api, and enable the readiness endpoint
 	c.api.Start()
 
+	if c.dnsServer != nil {
+		c.dnsServer.Start()
+	}
+
+	// Once the controller is stopped, the deployments and the API requests in progress share the shutdown timeout to complete.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	shutdownDeadline := make(chan time.Time, 1)
+
+	go func() {
+		<-stopCh
+		// Stop receiving traffic as soon as possible, while the in-flight work completes.
+		c.api.DisableReadiness()
+
+		deadline := time.Now().Add(c.shutdownTimeout)
+		shutdownDeadline <- deadline
+
+		time.AfterFunc(time.Until(deadline), cancel)
+	}()
+
+	// Failed configuration builds are retried with an exponential backoff, until the next successful build.
+	retryBackoff := backoff.NewExponentialBackOff()
+	retryBackoff.MaxElapsedTime = 0
+
+	var (
+		retryCh    <-chan time.Time
+		retryForce bool
+	)
+
+	refresh := func(force bool) {
+		if refreshErr := c.refreshConfiguration(ctx, force); refreshErr != nil {
+			retryForce = retryForce || force
+			next := retryBackoff.NextBackOff()
+
+			log.Errorf("Unable to build configuration, retrying in %s: %v", next, refreshErr)
+
+			retryCh = time.After(next)
+
+			return
+		}
+
+		retryBackoff.Reset()
+
+		retryCh = nil
+		retryForce = false
+	}
+
 	for {
-		timer := time.NewTimer(10 * time.Second)
+		timer := time.NewTimer(c.unreadyDeployInterval)
 		select {
 		case <-stopCh:
+			timer.Stop()
 			log.Info("Shutting down workers")
-			return nil
-		case message := <-c.configRefreshChan:
-			// Reload the configuration
-			conf, confErr := c.provider.BuildConfig()
-			if confErr != nil {
-				return confErr
-			}
-
-			if message == k8s.ConfigMessageChanForce || !reflect.DeepEqual(c.lastConfiguration.Get(), conf) {
-				c.lastConfiguration.Set(conf)
 
-				if deployErr := c.deployConfiguration(conf); deployErr != nil {
-					break
-				}
-
-				// Configuration successfully deployed, enable readiness in the api.
-				c.api.EnableReadiness()
+			return c.shutdown(<-shutdownDeadline)
+		case message := <-c.configRefreshChan:
+			refresh(message == k8s.ConfigMessageChanForce || retryForce)
+		case <-retryCh:
+			refresh(retryForce)
+		case request := <-c.redeployChan:
+			if err = c.redeploy(ctx, request); err != nil {
+				log.Errorf("Unable to redeploy configuration: %v", err)
 			}
 		case <-timer.C:
+			config, ok := c.lastConfiguration.Get().(*dynamic.Configuration)
+			if !ok || c.publisher != nil {
+				// When the configuration is published, the unready nodes get it by themselves.
+				break
+			}
+
 			log.Debug("Deploying configuration to unready nodes")
 
-			if deployErr := c.deployConfigurationToUnreadyNodes(c.lastConfiguration.Get().(*dynamic.Configuration)); deployErr != nil {
+			if deployErr := c.deployConfigurationToUnreadyNodes(ctx, config); deployErr != nil {
 				break
 			}
 
 			// Configuration successfully deployed, enable readiness in the api.
 			c.api.EnableReadiness()
 		}
+
+		timer.Stop()
 	}
 }
 
+// shutdown stops the API and the DNS server, waiting until the shutdown deadline at most for the requests in progress.
+func (c *Controller) shutdown(deadline time.Time) error {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	if err := c.api.Shutdown(ctx); err != nil {
+		log.Errorf("Unable to shut down the API: %v", err)
+	}
+
+	if c.dnsServer != nil {
+		c.dnsServer.Shutdown(ctx)
+	}
+
+	return nil
+}
+
+// refreshConfiguration builds the configuration, and deploys it if it changed or if forced.
+// While a configuration is pinned, it is deployed in place of the built one.
+func (c *Controller) refreshConfiguration(ctx context.Context, force bool) (err error) {
+	defer func() {
+		c.health.RecordRebuild(err)
+	}()
+
+	conf, err := c.provider.BuildConfig()
+	if err != nil {
+		return err
+	}
+
+	// The events which triggered the build are kept until a deploy records them in the history.
+	if c.pinnedConfiguration != nil {
+		if force {
+			c.deployAndRecord(ctx, c.pinnedConfiguration, c.handler.TakeEvents())
+			return nil
+		}
+
+		log.Debugf("Configuration pinned to version %s, skipping the new configuration", getConfigVersion(c.pinnedConfiguration))
+
+		return nil
+	}
+
+	// The provider returns the last configuration as is when nothing changed.
+	if !force && c.lastBuiltConfig == conf {
+		return nil
+	}
+
+	versioned, err := withConfigVersion(conf)
+	if err != nil {
+		return fmt.Errorf("unable to version configuration: %w", err)
+	}
+
+	c.lastBuiltConfig = conf
+	c.topology.Update(getConfigVersion(versioned), conf, c.tcpStateTable)
+
+	previous, _ := c.lastConfiguration.Get().(*dynamic.Configuration)
+	c.activity.Publish(ActivityEvent{
+		Type:    ActivityTypeRebuild,
+		Message: getConfigVersion(versioned),
+		Data:    diffConfigurations(previous, versioned),
+	})
+
+	// Forced refreshes, like on mesh pod updates, would otherwise roll out a bad configuration again and again.
+	if version := getConfigVersion(versioned); version == c.rolledBackVersion {
+		log.Debugf("Configuration version %s was rolled back, deploying the last good configuration", version)
+
+		if lastGood := c.rollout.LastGoodConfiguration(); lastGood != nil {
+			c.deployAndRecord(ctx, lastGood, c.handler.TakeEvents())
+		}
+
+		return nil
+	}
+
+	c.rolledBackVersion = ""
+
+	c.deployAndRecord(ctx, versioned, c.handler.TakeEvents())
+
+	return nil
+}
+
+// getTopology returns the topology of the mesh for the latest built configuration.
+// The builder is created on each call, as the SMI listers are created after the API.
+func (c *Controller) getTopology() (*Topology, error) {
+	return c.topology.Get(&topologyBuilder{
+		defaultMode:         c.defaultMode,
+		ignored:             c.ignored,
+		serviceLister:       c.ServiceLister,
+		endpointsLister:     c.EndpointsLister,
+		podLister:           c.PodLister,
+		trafficTargetLister: c.TrafficTargetLister,
+		trafficSplitLister:  c.TrafficSplitLister,
+	})
+}
+
+// inspector returns an inspector of the current listers.
+func (c *Controller) inspector() *inspector {
+	return &inspector{
+		smiEnabled:           c.smiEnabled,
+		defaultMode:          c.defaultMode,
+		meshNamespace:        c.meshNamespace,
+		ignored:              c.ignored,
+		serviceLister:        c.ServiceLister,
+		endpointsLister:      c.EndpointsLister,
+		podLister:            c.PodLister,
+		trafficTargetLister:  c.TrafficTargetLister,
+		httpRouteGroupLister: c.HTTPRouteGroupLister,
+		tcpRouteLister:       c.TCPRouteLister,
+		trafficSplitLister:   c.TrafficSplitLister,
+	}
+}
+
+// explain explains the decision of the mesh on some traffic.
+// The inspector is built on each call, as the SMI listers are created after the API.
+func (c *Controller) explain(request ExplainRequest, config *dynamic.Configuration) (*Explanation, error) {
+	return c.inspector().Explain(request, config)
+}
+
+// viewService returns the mesh configuration of a user service.
+func (c *Controller) viewService(namespace, name string, config *dynamic.Configuration) (*ServiceView, error) {
+	return c.inspector().ViewService(namespace, name, config)
+}
+
+// redeploy deploys a configuration from the history, and pins it if requested.
+// A request without configuration unpins the configuration, and deploys the latest built one.
+func (c *Controller) redeploy(ctx context.Context, request redeployRequest) error {
+	if request.config == nil {
+		log.Info("Unpinning configuration")
+
+		c.pinnedConfiguration = nil
+		c.lastBuiltConfig = nil
+
+		return c.refreshConfiguration(ctx, true)
+	}
+
+	version := getConfigVersion(request.config)
+	log.Infof("Redeploying configuration version %s (pinned: %t)", version, request.pin)
+
+	c.pinnedConfiguration = nil
+	if request.pin {
+		c.pinnedConfiguration = request.config
+	}
+
+	c.deployAndRecord(ctx, request.config, []string{fmt.Sprintf("redeployed version %s", version)})
+
+	return nil
+}
+
+// deployAndRecord deploys the configuration to the mesh nodes, and records it in the history.
+func (c *Controller) deployAndRecord(ctx context.Context, config *dynamic.Configuration, events []string) {
+	c.lastConfiguration.Set(config)
+
+	err := c.deployConfiguration(ctx, config)
+	c.health.RecordDeploy(err)
+
+	c.history.Add(HistoryEntry{
+		Version:      getConfigVersion(config),
+		TimeStamp:    time.Now(),
+		Events:       events,
+		RolloutPhase: c.rollout.Status().Phase,
+	}, config)
+
+	if err != nil {
+		log.Errorf("Unable to deploy configuration: %v", err)
+		return
+	}
+
+	// Configuration successfully deployed, enable readiness in the api.
+	c.api.EnableReadiness()
+}
+
 // startInformers starts the controller informers.
 func (c *Controller) startInformers(stopCh <-chan struct{}, syncTimeout time.Duration) {
 	// Start the informers with a timeout.
@@ -223,39 +615,60 @@ func (c *Controller) startInformers(stopCh <-chan struct{}, syncTimeout time.Dur
 	defer cancel()
 
 	log.Debug("Starting Informers")
-	c.kubernetesFactory.Start(stopCh)
-
-	for t, ok := range c.kubernetesFactory.WaitForCacheSync(ctx.Done()) {
-		if !ok {
-			log.Errorf("timed out waiting for controller caches to sync: %s", t.String())
-		}
-	}
 
-	if c.smiEnabled {
-		c.smiAccessFactory.Start(stopCh)
+	for _, factory := range c.informerFactories() {
+		factory.Start(stopCh)
 
-		for t, ok := range c.smiAccessFactory.WaitForCacheSync(ctx.Done()) {
+		for t, ok := range factory.WaitForCacheSync(ctx.Done()) {
 			if !ok {
 				log.Errorf("timed out waiting for controller caches to sync: %s", t.String())
 			}
 		}
+	}
+}
+
+// informerFactory is implemented by all the informer factories of the controller.
+type informerFactory interface {
+	Start(stopCh <-chan struct{})
+	WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool
+}
 
-		c.smiSpecsFactory.Start(stopCh)
+// informerFactories returns the informer factories used by the controller.
+func (c *Controller) informerFactories() []informerFactory {
+	factories := []informerFactory{c.kubernetesFactories}
 
-		for t, ok := range c.smiSpecsFactory.WaitForCacheSync(ctx.Done()) {
-			if !ok {
-				log.Errorf("timed out waiting for controller caches to sync: %s", t.String())
-			}
+	if c.endpointSlices != nil {
+		factories = append(factories, c.endpointSlices)
+	}
+
+	for _, factory := range []informers.SharedInformerFactory{c.meshFactory, c.meshPodFactory, c.namespaceFactory} {
+		if factory != nil {
+			factories = append(factories, factory)
 		}
+	}
 
-		c.smiSplitFactory.Start(stopCh)
+	if c.smiEnabled {
+		factories = append(factories, c.smiFactories)
+	}
 
-		for t, ok := range c.smiSplitFactory.WaitForCacheSync(ctx.Done()) {
-			if !ok {
-				log.Errorf("timed out waiting for controller caches to sync: %s", t.String())
-			}
+	return factories
+}
+
+// informerSyncStates returns the current sync state of the started informers, keyed by resource type.
+func (c *Controller) informerSyncStates() map[string]bool {
+	// With a closed stop channel, the factories report the sync states without waiting.
+	stopCh := make(chan struct{})
+	close(stopCh)
+
+	states := make(map[string]bool)
+
+	for _, factory := range c.informerFactories() {
+		for t, ok := range factory.WaitForCacheSync(stopCh) {
+			states[t.String()] = ok
 		}
 	}
+
+	return states
 }
 
 func (c *Controller) createMeshServices() error {
@@ -299,6 +712,49 @@ func (c *Controller) createMeshServices() error {
 	return nil
 }
 
+// syncMeshServices creates the missing mesh services, and deletes the mesh services of the services which left the mesh.
+func (c *Controller) syncMeshServices() error {
+	if err := c.createMeshServices(); err != nil {
+		return err
+	}
+
+	return c.deleteIgnoredMeshServices()
+}
+
+// deleteIgnoredMeshServices deletes the mesh services of the services which are not part of the mesh anymore,
+// like the services of a namespace which is not selected anymore.
+func (c *Controller) deleteIgnoredMeshServices() error {
+	svcs, err := c.ServiceLister.List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("unable to get services: %w", err)
+	}
+
+	for _, service := range svcs {
+		if service.Namespace == c.meshNamespace || !c.ignored.IsIgnored(service.ObjectMeta) {
+			continue
+		}
+
+		meshServiceName := c.userServiceToMeshServiceName(service.Name, service.Namespace)
+
+		_, err := c.ServiceLister.Services(c.meshNamespace).Get(meshServiceName)
+		if errors.IsNotFound(err) {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("unable to check if maesh service exists: %w", err)
+		}
+
+		log.Infof("Deleting mesh service of ignored service %s/%s: %s", service.Namespace, service.Name, meshServiceName)
+
+		if err := c.clients.DeleteService(c.meshNamespace, meshServiceName); err != nil {
+			return fmt.Errorf("unable to delete mesh service: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (c *Controller) createMeshService(service *corev1.Service) error {
 	meshServiceName := c.userServiceToMeshServiceName(service.Name, service.Namespace)
 	log.Debugf("Creating mesh service: %s", meshServiceName)
@@ -320,7 +776,7 @@ func (c *Controller) createMeshService(service *corev1.Service) error {
 				continue
 			}
 
-			targetPort := intstr.FromInt(5000 + id)
+			targetPort := intstr.FromInt(base.GetHTTPMeshPort(id))
 			if serviceMode == k8s.ServiceTypeTCP {
 				targetPort = intstr.FromInt(c.getTCPPortFromState(service.Name, service.Namespace, sp.Port))
 			}
@@ -405,7 +861,7 @@ func (c *Controller) updateMeshService(oldUserService *corev1.Service, newUserSe
 				continue
 			}
 
-			targetPort := intstr.FromInt(5000 + id)
+			targetPort := intstr.FromInt(base.GetHTTPMeshPort(id))
 			if serviceMode == k8s.ServiceTypeTCP {
 				targetPort = intstr.FromInt(c.getTCPPortFromState(newUserService.Name, newUserService.Namespace, sp.Port))
 			}
@@ -438,9 +894,27 @@ func (c *Controller) updateMeshService(oldUserService *corev1.Service, newUserSe
 	return updatedSvc, nil
 }
 
+// getWatchedNamespaces returns the namespaces to watch, including the mesh namespace, or nil to watch all of them.
+func (c *Controller) getWatchedNamespaces() []string {
+	if len(c.watchNamespaces) == 0 {
+		return nil
+	}
+
+	namespaces := make([]string, 0, len(c.watchNamespaces)+1)
+	namespaces = append(namespaces, c.watchNamespaces...)
+
+	for _, namespace := range c.watchNamespaces {
+		if namespace == c.meshNamespace {
+			return namespaces
+		}
+	}
+
+	return append(namespaces, c.meshNamespace)
+}
+
 // userServiceToMeshServiceName converts a User service with a namespace to a mesh service name.
 func (c *Controller) userServiceToMeshServiceName(serviceName string, namespace string) string {
-	return fmt.Sprintf("%s-%s-6d61657368-%s", c.meshNamespace, serviceName, namespace)
+	return k8s.MeshServiceName(c.meshNamespace, serviceName, namespace)
 }
 
 func (c *Controller) loadTCPStateTable() (*k8s.State, error) {
@@ -500,6 +974,7 @@ func (c *Controller) getTCPPortFromState(serviceName, serviceNamespace string, s
 
 		if err := c.saveTCPStateTable(); err != nil {
 			log.Errorf("unable to save TCP state table config map: %v", err)
+			c.health.RecordTCPStateError(err)
 			return 0
 		}
 
@@ -531,8 +1006,12 @@ func (c *Controller) saveTCPStateTable() error {
 	})
 }
 
-// deployConfiguration deploys the configuration to the mesh pods.
-func (c *Controller) deployConfiguration(config *dynamic.Configuration) error {
+// deployConfiguration deploys the configuration to the mesh pods, or publishes it for them to get it in pull and kv modes.
+func (c *Controller) deployConfiguration(ctx context.Context, config *dynamic.Configuration) error {
+	if c.publisher != nil {
+		return c.publisher.Publish(config)
+	}
+
 	sel := labels.Everything()
 
 	r, err := labels.NewRequirement("component", selection.Equals, []string{"maesh-mesh"})
@@ -542,7 +1021,7 @@ func (c *Controller) deployConfiguration(config *dynamic.Configuration) error {
 
 	sel = sel.Add(*r)
 
-	podList, err := c.PodLister.Pods(c.meshNamespace).List(sel)
+	podList, err := c.MeshPodLister.Pods(c.meshNamespace).List(sel)
 	if err != nil {
 		return fmt.Errorf("unable to get pods: %w", err)
 	}
@@ -551,15 +1030,49 @@ func (c *Controller) deployConfiguration(config *dynamic.Configuration) error {
 		return fmt.Errorf("unable to find any active mesh pods to deploy config : %+v", config)
 	}
 
-	if err := c.deployToPods(podList, config); err != nil {
+	if err := c.rollout.Run(ctx, podList, config); err != nil {
+		if c.rollout.Status().Phase == RolloutPhaseRolledBack {
+			lastGood := c.rollout.LastGoodConfiguration()
+
+			// Keep the unready nodes away from the bad configuration.
+			c.lastConfiguration.Set(lastGood)
+
+			if version := getConfigVersion(config); version != getConfigVersion(lastGood) {
+				c.rolledBackVersion = version
+			}
+		}
+
 		return fmt.Errorf("error deploying configuration: %v", err)
 	}
 
 	return nil
 }
 
+// verifyPods checks that the pods which were ready are still ready, and that none of them reports configuration errors.
+func (c *Controller) verifyPods(ctx context.Context, pods []*corev1.Pod, wasReady map[string]bool) error {
+	for _, pod := range pods {
+		if wasReady[pod.Name] {
+			current, err := c.MeshPodLister.Pods(pod.Namespace).Get(pod.Name)
+			if err == nil && !isPodReady(current) {
+				return fmt.Errorf("pod %s is not ready anymore", pod.Name)
+			}
+		}
+
+		rawData, err := getMeshNodeRawData(ctx, c.meshNodes, pod.Status.PodIP)
+		if err != nil {
+			return fmt.Errorf("unable to verify pod %s: %v", pod.Name, err)
+		}
+
+		if errs := rawData.Errors(); len(errs) > 0 {
+			return fmt.Errorf("pod %s reports configuration errors: %s", pod.Name, strings.Join(errs, ", "))
+		}
+	}
+
+	return nil
+}
+
 // deployConfigurationToUnreadyNodes deploys the configuration to the mesh pods.
-func (c *Controller) deployConfigurationToUnreadyNodes(config *dynamic.Configuration) error {
+func (c *Controller) deployConfigurationToUnreadyNodes(ctx context.Context, config *dynamic.Configuration) error {
 	sel := labels.Everything()
 
 	r, err := labels.NewRequirement("component", selection.Equals, []string{"maesh-mesh"})
@@ -569,7 +1082,7 @@ func (c *Controller) deployConfigurationToUnreadyNodes(config *dynamic.Configura
 
 	sel = sel.Add(*r)
 
-	podList, err := c.PodLister.Pods(c.meshNamespace).List(sel)
+	podList, err := c.MeshPodLister.Pods(c.meshNamespace).List(sel)
 	if err != nil {
 		return fmt.Errorf("unable to get pods: %w", err)
 	}
@@ -589,14 +1102,22 @@ func (c *Controller) deployConfigurationToUnreadyNodes(config *dynamic.Configura
 		}
 	}
 
-	if err := c.deployToPods(unreadyPods, config); err != nil {
+	if err := c.deployToPods(ctx, unreadyPods, config); err != nil {
 		return fmt.Errorf("error deploying configuration: %v", err)
 	}
 
 	return nil
 }
 
-func (c *Controller) deployToPods(pods []*corev1.Pod, config *dynamic.Configuration) error {
+// deployToPods deploys the configuration to the given pods, skipping the ones already running its version.
+func (c *Controller) deployToPods(ctx context.Context, pods []*corev1.Pod, config *dynamic.Configuration) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("unable to marshal configuration: %v", err)
+	}
+
+	version := getConfigVersion(config)
+
 	var errg errgroup.Group
 
 	for _, p := range pods {
@@ -606,65 +1127,110 @@ func (c *Controller) deployToPods(pods []*corev1.Pod, config *dynamic.Configurat
 
 		errg.Go(func() error {
 			b := backoff.NewExponentialBackOff()
-			b.MaxElapsedTime = 15 * time.Second
+			b.MaxElapsedTime = c.deployRetryTimeout
 
 			op := func() error {
-				return c.deployToPod(pod.Name, pod.Status.PodIP, config)
+				return c.deployToPod(ctx, pod.Name, pod.Status.PodIP, version, data)
 			}
 
-			return backoff.Retry(safe.OperationWithRecover(op), b)
+			return backoff.Retry(safe.OperationWithRecover(op), backoff.WithContext(b, ctx))
 		})
 	}
 
 	return errg.Wait()
 }
 
-func (c *Controller) deployToPod(name, ip string, config *dynamic.Configuration) error {
+func (c *Controller) deployToPod(ctx context.Context, name, ip, version string, data []byte) error {
 	if name == "" || ip == "" {
 		// If there is no name or ip, then just return.
 		return fmt.Errorf("pod has no name or IP")
 	}
 
-	b, err := json.Marshal(config)
-	if err != nil {
-		return fmt.Errorf("unable to marshal configuration: %v", err)
-	}
+	if version != "" {
+		// A node that cannot report its version is deployed to anyway.
+		rawData, err := getMeshNodeRawData(ctx, c.meshNodes, ip)
+		if err != nil {
+			log.Debugf("Unable to get configuration version of pod (%s:%s): %v", name, ip, err)
+		}
 
-	url := fmt.Sprintf("http://%s:8080/api/providers/rest", ip)
+		if rawData != nil && rawData.ConfigVersion() == version {
+			c.configVersions.Set(name, version)
+			log.Debugf("Pod (%s:%s) already has configuration version %s", name, ip, version)
 
-	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(b))
+			return nil
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodPut, c.meshNodes.URL(ip, "/api/providers/rest"), bytes.NewBuffer(data))
 	if err != nil {
 		return fmt.Errorf("unable to create request: %v", err)
 	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	req = req.WithContext(ctx)
+
+	resp, err := c.meshNodes.Do(req, 10*time.Second)
 
 	if resp != nil {
 		defer resp.Body.Close()
 
 		if _, bodyErr := ioutil.ReadAll(resp.Body); bodyErr != nil {
-			c.deployLog.LogDeploy(time.Now(), name, ip, false, fmt.Sprintf("unable to read response body: %v", bodyErr))
+			c.logDeploy(name, ip, false, fmt.Sprintf("unable to read response body: %v", bodyErr))
 			return fmt.Errorf("unable to read response body: %v", bodyErr)
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			c.deployLog.LogDeploy(time.Now(), name, ip, false, fmt.Sprintf("received non-ok response code: %d", resp.StatusCode))
+			c.logDeploy(name, ip, false, fmt.Sprintf("received non-ok response code: %d", resp.StatusCode))
 			return fmt.Errorf("received non-ok response code: %d", resp.StatusCode)
 		}
 	}
 
 	if err != nil {
-		c.deployLog.LogDeploy(time.Now(), name, ip, false, fmt.Sprintf("unable to deploy configuration: %v", err))
+		c.logDeploy(name, ip, false, fmt.Sprintf("unable to deploy configuration: %v", err))
 		return fmt.Errorf("unable to deploy configuration: %v", err)
 	}
 
-	c.deployLog.LogDeploy(time.Now(), name, ip, true, "")
-	log.Debugf("Successfully deployed configuration to pod (%s:%s)", name, ip)
+	c.logDeploy(name, ip, true, "")
+	c.configVersions.Set(name, version)
+	log.Debugf("Successfully deployed configuration version %s to pod (%s:%s)", version, name, ip)
 
 	return nil
 }
 
+// logDeploy records the result of a deploy to a mesh pod in the deploy log, and publishes it.
+func (c *Controller) logDeploy(name, ip string, success bool, reason string) {
+	timeStamp := time.Now()
+
+	c.deployLog.LogDeploy(timeStamp, name, ip, success, reason)
+
+	c.activity.Publish(ActivityEvent{
+		Type:      ActivityTypeDeploy,
+		TimeStamp: timeStamp,
+		Pod:       name,
+		Message:   reason,
+		Data: Entry{
+			TimeStamp:        timeStamp,
+			PodName:          name,
+			PodIP:            ip,
+			DeploySuccessful: success,
+			Reason:           reason,
+		},
+	})
+}
+
+// meshPodLabelSelector selects the mesh pods.
+const meshPodLabelSelector = "component=maesh-mesh"
+
+// isUserPod checks if the object is a pod which is not a mesh pod.
+func isUserPod(obj interface{}) bool {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	pod, ok := obj.(*corev1.Pod)
+
+	return ok && !isMeshPod(pod)
+}
+
 // isMeshPod checks if the pod is a mesh pod. Can be modified to use multiple metrics if needed.
 func isMeshPod(pod *corev1.Pod) bool {
 	return pod.Labels["component"] == "maesh-mesh"
@@ -0,0 +1,403 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/kv"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// meshControllerTest runs a controller on the fake clientsets of a fixture, deploying to fake mesh nodes.
+type meshControllerTest struct {
+	controller *Controller
+	nodes      map[string]*fakeMeshNode
+	stopCh     chan struct{}
+	done       chan error
+}
+
+// startMeshControllerTest starts a controller on the objects of the fixture, distributing the configuration with the given mode
+// to the fake mesh nodes of the mesh pod IPs. In kv mode, the configuration is written to an in-memory store.
+func startMeshControllerTest(t *testing.T, fixture, distributionMode string, nodes map[string]*fakeMeshNode) *meshControllerTest {
+	t.Helper()
+
+	test := &meshControllerTest{
+		nodes:  nodes,
+		stopCh: make(chan struct{}),
+		done:   make(chan error, 1),
+	}
+
+	clients := k8s.NewClientMock(test.stopCh, fixture, false).ClientWrapper()
+
+	test.controller = NewMeshController(clients, MeshControllerConfig{
+		DefaultMode:      k8s.ServiceTypeHTTP,
+		Namespace:        "maesh",
+		Rollout:          RolloutConfig{VerifyDelay: 10 * time.Millisecond},
+		ShutdownTimeout:  time.Second,
+		DistributionMode: distributionMode,
+		KVStore:          kv.NewMemoryStore(),
+		KVRootKey:        "traefik",
+	})
+	test.controller.unreadyDeployInterval = 100 * time.Millisecond
+	test.controller.deployRetryTimeout = 200 * time.Millisecond
+	test.controller.meshNodes.transport = fakeMeshNodeTransport(test.controller.meshNodes, test.nodes)
+
+	go func() {
+		test.done <- test.controller.Run(test.stopCh)
+	}()
+
+	return test
+}
+
+// Stop stops the controller and the fake mesh nodes.
+func (m *meshControllerTest) Stop(t *testing.T) {
+	t.Helper()
+
+	close(m.stopCh)
+
+	select {
+	case err := <-m.done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Error("controller did not stop")
+	}
+
+	for _, node := range m.nodes {
+		node.Close()
+	}
+}
+
+// Ready checks if the readiness endpoint reports the controller as ready.
+func (m *meshControllerTest) Ready() bool {
+	res := httptest.NewRecorder()
+	m.controller.api.getReadiness(res, httptest.NewRequest(http.MethodGet, readinessPath, nil))
+
+	return res.Code == http.StatusOK
+}
+
+func TestControllerRunDeploysToMeshNodes(t *testing.T) {
+	test := startMeshControllerTest(t, "mesh.yaml", DistributionModePush, map[string]*fakeMeshNode{
+		"10.0.0.1": newFakeMeshNode(),
+		"10.0.0.2": newFakeMeshNode(),
+	})
+	defer test.Stop(t)
+
+	assert.Eventually(t, test.Ready, 10*time.Second, 50*time.Millisecond)
+
+	for ip, node := range test.nodes {
+		config, _ := node.Configuration()
+		require.NotNil(t, config, ip)
+
+		assert.Contains(t, config.HTTP.Routers, "whoami-foo-80-4bdede6403f8d017", ip)
+		assert.NotEmpty(t, getConfigVersion(config), ip)
+	}
+
+	summaries := test.controller.deployLog.Summaries()
+	require.Len(t, summaries, 2)
+
+	for _, summary := range summaries {
+		assert.NotZero(t, summary.Successes, summary.PodName)
+		assert.Zero(t, summary.Failures, summary.PodName)
+	}
+}
+
+func TestControllerRunRetriesUnreadyNodes(t *testing.T) {
+	failing := newFakeMeshNode()
+	failing.FailDeploys(1000)
+
+	test := startMeshControllerTest(t, "mesh.yaml", DistributionModePush, map[string]*fakeMeshNode{
+		"10.0.0.1": newFakeMeshNode(),
+		"10.0.0.2": failing,
+	})
+	defer test.Stop(t)
+
+	// The initial rollout fails once the deploys to the failing node are not retried anymore.
+	assert.Eventually(t, func() bool {
+		return test.controller.rollout.Status().Phase == RolloutPhaseFailed
+	}, 10*time.Second, 50*time.Millisecond)
+
+	assert.False(t, test.Ready())
+
+	config, _ := test.nodes["10.0.0.1"].Configuration()
+	assert.NotNil(t, config)
+
+	// Once the node recovers, the configuration is deployed by the retries of the unready nodes.
+	failing.FailDeploys(0)
+
+	assert.Eventually(t, test.Ready, 20*time.Second, 50*time.Millisecond)
+
+	recovered, deploys := failing.Configuration()
+	require.NotNil(t, recovered)
+	assert.Equal(t, 1, deploys)
+	assert.Equal(t, getConfigVersion(config), getConfigVersion(recovered))
+
+	summaries := test.controller.deployLog.Summaries()
+	require.Len(t, summaries, 2)
+
+	for _, summary := range summaries {
+		if summary.PodName == "maesh-mesh-b" {
+			assert.NotZero(t, summary.Failures)
+			assert.NotZero(t, summary.Successes)
+			assert.Equal(t, "received non-ok response code: 500", summary.LastFailureReason)
+		}
+	}
+}
+
+func TestControllerRunRedeploysPinnedConfiguration(t *testing.T) {
+	test := startMeshControllerTest(t, "mesh.yaml", DistributionModePush, map[string]*fakeMeshNode{
+		"10.0.0.1": newFakeMeshNode(),
+		"10.0.0.2": newFakeMeshNode(),
+	})
+	defer test.Stop(t)
+
+	require.True(t, assert.Eventually(t, test.Ready, 10*time.Second, 50*time.Millisecond))
+
+	pinned, err := withConfigVersion(&dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{}})
+	require.NoError(t, err)
+
+	test.controller.redeployChan <- redeployRequest{config: pinned, pin: true}
+
+	for ip, node := range test.nodes {
+		assert.Eventually(t, func() bool {
+			config, _ := node.Configuration()
+			return getConfigVersion(config) == getConfigVersion(pinned)
+		}, 10*time.Second, 50*time.Millisecond, ip)
+	}
+
+	// The events of the configurations built while pinned are recorded by the deploy following the unpinning.
+	_, err = test.controller.clients.KubeClient.CoreV1().Services("foo").Create(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "whoami-bis", Namespace: "foo"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.1.0.2",
+			Ports:     []corev1.ServicePort{{Protocol: corev1.ProtocolTCP, Port: 80}},
+		},
+	})
+	require.NoError(t, err)
+
+	created := time.Now()
+
+	require.True(t, assert.Eventually(t, func() bool {
+		return test.controller.health.Status(time.Now(), nil, nil).LastRebuild.After(created)
+	}, 10*time.Second, 50*time.Millisecond))
+
+	test.controller.redeployChan <- redeployRequest{}
+
+	assert.Eventually(t, func() bool {
+		entry := lastHistoryEntry(t, test.controller.history)
+		return entry.Version != getConfigVersion(pinned) && containsString(entry.Events, "added Service foo/whoami-bis")
+	}, 10*time.Second, 50*time.Millisecond)
+}
+
+func TestControllerRunDoesNotRedeployRolledBackConfiguration(t *testing.T) {
+	test := startMeshControllerTest(t, "mesh.yaml", DistributionModePush, map[string]*fakeMeshNode{
+		"10.0.0.1": newFakeMeshNode(),
+		"10.0.0.2": newFakeMeshNode(),
+	})
+	defer test.Stop(t)
+
+	require.True(t, assert.Eventually(t, test.Ready, 10*time.Second, 50*time.Millisecond))
+
+	good, _ := test.nodes["10.0.0.1"].Configuration()
+	require.NotNil(t, good)
+
+	for _, node := range test.nodes {
+		node.ReportErrorsExcept(getConfigVersion(good))
+	}
+
+	// A new service builds a configuration the mesh nodes report errors for.
+	_, err := test.controller.clients.KubeClient.CoreV1().Services("foo").Create(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "whoami-bis", Namespace: "foo"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.1.0.2",
+			Ports:     []corev1.ServicePort{{Protocol: corev1.ProtocolTCP, Port: 80}},
+		},
+	})
+	require.NoError(t, err)
+
+	require.True(t, assert.Eventually(t, func() bool {
+		return test.controller.rollout.Status().Phase == RolloutPhaseRolledBack
+	}, 10*time.Second, 50*time.Millisecond))
+
+	deploys := make(map[string]int)
+	for ip, node := range test.nodes {
+		_, deploys[ip] = node.Configuration()
+	}
+
+	lastDeploy := lastHistoryEntry(t, test.controller.history).TimeStamp
+
+	// A mesh pod update forces a refresh, which deploys the last good configuration instead of the rolled back one.
+	pod, err := test.controller.clients.KubeClient.CoreV1().Pods("maesh").Get("maesh-mesh-a", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	pod = pod.DeepCopy()
+	pod.Labels["updated"] = "true"
+
+	_, err = test.controller.clients.KubeClient.CoreV1().Pods("maesh").Update(pod)
+	require.NoError(t, err)
+
+	require.True(t, assert.Eventually(t, func() bool {
+		return lastHistoryEntry(t, test.controller.history).TimeStamp.After(lastDeploy)
+	}, 10*time.Second, 50*time.Millisecond))
+
+	assert.Equal(t, getConfigVersion(good), test.controller.rollout.Status().Version)
+
+	for ip, node := range test.nodes {
+		config, count := node.Configuration()
+		assert.Equal(t, deploys[ip], count, ip)
+		assert.Equal(t, getConfigVersion(good), getConfigVersion(config), ip)
+	}
+}
+
+// lastHistoryEntry returns the latest entry of the history.
+func lastHistoryEntry(t *testing.T, history *ConfigHistory) HistoryEntry {
+	t.Helper()
+
+	entries := history.Entries()
+	require.NotEmpty(t, entries)
+
+	return entries[len(entries)-1]
+}
+
+func TestControllerRunPublishesConfigurationInPullMode(t *testing.T) {
+	// Nothing is pushed to the mesh nodes in pull mode.
+	node := newFakeMeshNode()
+	node.FailDeploys(1000)
+
+	test := startMeshControllerTest(t, "mesh.yaml", DistributionModePull, map[string]*fakeMeshNode{
+		"10.0.0.1": node,
+	})
+	defer test.Stop(t)
+
+	require.True(t, assert.Eventually(t, test.Ready, 10*time.Second, 50*time.Millisecond))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/configuration/mesh", nil)
+	req.RemoteAddr = "10.0.0.1:42000"
+
+	res := httptest.NewRecorder()
+	test.controller.api.distributionRouter.ServeHTTP(res, req)
+
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var config dynamic.Configuration
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &config))
+
+	assert.Contains(t, config.HTTP.Routers, "whoami-foo-80-4bdede6403f8d017")
+	assert.Equal(t, getConfigVersion(&config), test.controller.configVersions.Get("maesh-mesh-a"))
+	assert.Empty(t, test.controller.configVersions.Get("maesh-mesh-b"))
+
+	pushed, _ := node.Configuration()
+	assert.Nil(t, pushed)
+
+	// The mesh configuration is only served on the distribution listener.
+	res = httptest.NewRecorder()
+	test.controller.api.router.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestControllerRunWritesConfigurationToKVStore(t *testing.T) {
+	// Nothing is pushed to the mesh nodes in kv mode.
+	node := newFakeMeshNode()
+	node.FailDeploys(1000)
+
+	test := startMeshControllerTest(t, "mesh.yaml", DistributionModeKV, map[string]*fakeMeshNode{
+		"10.0.0.1": node,
+	})
+	defer test.Stop(t)
+
+	require.True(t, assert.Eventually(t, test.Ready, 10*time.Second, 50*time.Millisecond))
+
+	config, ok := test.controller.lastConfiguration.Get().(*dynamic.Configuration)
+	require.True(t, ok)
+
+	expected, err := kv.Flatten(config, "traefik")
+	require.NoError(t, err)
+
+	pairs := test.controller.kvStore.(*kv.MemoryStore).Pairs()
+	assert.Equal(t, expected, pairs)
+	assert.Contains(t, pairs, "traefik/http/routers/whoami-foo-80-4bdede6403f8d017/rule")
+
+	pushed, _ := node.Configuration()
+	assert.Nil(t, pushed)
+
+	// The versions run by the mesh nodes are not known in kv mode, they are never reported as stale.
+	assert.Empty(t, test.controller.api.getStalePods())
+}
+
+func TestControllerDeletesMeshServicesOfUnselectedNamespaces(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	clients := k8s.NewClientMock(stopCh, "mesh.yaml", false).ClientWrapper()
+
+	selector, err := labels.Parse("mesh!=disabled")
+	require.NoError(t, err)
+
+	c := NewMeshController(clients, MeshControllerConfig{
+		DefaultMode:       k8s.ServiceTypeHTTP,
+		Namespace:         "maesh",
+		NamespaceSelector: selector,
+	})
+
+	// Drain the configuration rebuilds triggered by the handler.
+	go func() {
+		for {
+			select {
+			case <-c.configRefreshChan:
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+
+	c.startInformers(stopCh, 10*time.Second)
+	require.NoError(t, c.createMeshServices())
+
+	meshServiceName := c.userServiceToMeshServiceName("whoami", "foo")
+
+	require.True(t, assert.Eventually(t, func() bool {
+		_, err := c.ServiceLister.Services("maesh").Get(meshServiceName)
+		return err == nil
+	}, 10*time.Second, 50*time.Millisecond))
+
+	namespace, exists, err := clients.GetNamespace("foo")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	namespace = namespace.DeepCopy()
+	namespace.Labels = map[string]string{"mesh": "disabled"}
+
+	_, err = clients.KubeClient.CoreV1().Namespaces().Update(namespace)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		_, exists, err := clients.GetService("maesh", meshServiceName)
+		return err == nil && !exists
+	}, 10*time.Second, 50*time.Millisecond)
+}
+
+func TestGetWatchedNamespaces(t *testing.T) {
+	// The spare capacity of the configured namespaces must not be written to.
+	watchNamespaces := make([]string, 1, 2)
+	watchNamespaces[0] = "foo"
+
+	c := &Controller{meshNamespace: "maesh", watchNamespaces: watchNamespaces}
+
+	assert.Equal(t, []string{"foo", "maesh"}, c.getWatchedNamespaces())
+	assert.Equal(t, []string{"foo", ""}, watchNamespaces[:2])
+
+	c.watchNamespaces = []string{"maesh", "foo"}
+	assert.Equal(t, []string{"maesh", "foo"}, c.getWatchedNamespaces())
+
+	c.watchNamespaces = nil
+	assert.Nil(t, c.getWatchedNamespaces())
+}
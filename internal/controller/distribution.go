@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+)
+
+// Distribution modes of the configuration to the mesh nodes.
+const (
+	// DistributionModePush deploys the configuration to the REST provider of each mesh node.
+	DistributionModePush = "push"
+	// DistributionModePull publishes the configuration on the API, polled by the mesh nodes with their HTTP provider.
+	DistributionModePull = "pull"
+	// DistributionModeKV writes the configuration to a KV store, watched by the mesh nodes with their KV provider.
+	DistributionModeKV = "kv"
+)
+
+// configPublisher publishes the configurations for the mesh nodes to get them by themselves.
+type configPublisher interface {
+	Publish(config *dynamic.Configuration) error
+}
+
+// PublishedConfiguration holds the configuration published to the mesh nodes in pull mode.
+type PublishedConfiguration struct {
+	mu      sync.RWMutex
+	version string
+	data    []byte
+}
+
+// NewPublishedConfiguration returns a PublishedConfiguration without configuration.
+func NewPublishedConfiguration() *PublishedConfiguration {
+	return &PublishedConfiguration{}
+}
+
+// Publish replaces the published configuration.
+func (p *PublishedConfiguration) Publish(config *dynamic.Configuration) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("unable to marshal configuration: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.version = getConfigVersion(config)
+	p.data = data
+
+	return nil
+}
+
+// Get returns the version and the JSON of the published configuration, nil if none was published yet.
+func (p *PublishedConfiguration) Get() (string, []byte) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.version, p.data
+}
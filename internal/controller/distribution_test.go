@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishedConfiguration(t *testing.T) {
+	published := NewPublishedConfiguration()
+
+	// Nothing is returned while nothing is published.
+	version, data := published.Get()
+	assert.Empty(t, version)
+	assert.Nil(t, data)
+
+	first, err := withConfigVersion(&dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{}})
+	require.NoError(t, err)
+
+	second, err := withConfigVersion(&dynamic.Configuration{TCP: &dynamic.TCPConfiguration{}})
+	require.NoError(t, err)
+
+	require.NoError(t, published.Publish(first))
+	require.NoError(t, published.Publish(second))
+
+	// The latest configuration replaces the previous one.
+	version, data = published.Get()
+	assert.Equal(t, getConfigVersion(second), version)
+
+	var served dynamic.Configuration
+	require.NoError(t, json.Unmarshal(data, &served))
+	assert.Equal(t, getConfigVersion(second), getConfigVersion(&served))
+}
@@ -0,0 +1,368 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/providers/base"
+	"github.com/containous/maesh/internal/providers/smi"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	access "github.com/deislabs/smi-sdk-go/pkg/apis/access/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ExplainRequest describes the traffic to explain, from a pod or a service account to a service.
+type ExplainRequest struct {
+	FromNamespace string
+	// FromName is the name of a pod, or of a service account if no pod has this name.
+	FromName    string
+	ToNamespace string
+	ToName      string
+	// Port is the service port, all the ports if 0.
+	Port   int32
+	Path   string
+	Method string
+}
+
+// Explanation is the decision of the mesh on some traffic, with the reasons and the SMI resources leading to it.
+type Explanation struct {
+	ServiceAccount string
+	SourceIP       string `json:",omitempty"`
+	Mode           string `json:",omitempty"`
+	Allowed        bool
+	Reasons        []string
+	TrafficTargets []TrafficTargetExplanation
+}
+
+// TrafficTargetExplanation details how a TrafficTarget applies to the explained traffic.
+type TrafficTargetExplanation struct {
+	Name string
+	// Applicable is true if the destination service has endpoints running as the TrafficTarget destination.
+	Applicable    bool
+	SourceAllowed bool
+	// Matches lists the HTTPRouteGroup matches of the path and method, as "group/match".
+	Matches []string
+	// Whitelists are the whitelist middlewares of the TrafficTarget on the service ports, from the current configuration.
+	Whitelists []WhitelistExplanation `json:",omitempty"`
+	Reasons    []string
+}
+
+// WhitelistExplanation is a whitelist middleware restricting the source IPs of a TrafficTarget.
+type WhitelistExplanation struct {
+	Name        string
+	SourceRange []string
+	// SourceAllowed is true if the source pod IP is in the source range.
+	SourceAllowed bool
+}
+
+// Explain returns the decision of the mesh on the requested traffic, given the current configuration.
+func (i *inspector) Explain(request ExplainRequest, config *dynamic.Configuration) (*Explanation, error) {
+	explanation := &Explanation{
+		ServiceAccount: request.FromName,
+		Reasons:        []string{},
+		TrafficTargets: []TrafficTargetExplanation{},
+	}
+
+	if err := i.resolveSource(request, explanation); err != nil {
+		return nil, err
+	}
+
+	service, err := i.serviceLister.Services(request.ToNamespace).Get(request.ToName)
+	if err != nil {
+		if kubeerror.IsNotFound(err) {
+			explanation.addReason("service %s/%s does not exist", request.ToNamespace, request.ToName)
+			return explanation, nil
+		}
+
+		return nil, fmt.Errorf("unable to get service: %v", err)
+	}
+
+	if i.ignored.IsIgnored(service.ObjectMeta) {
+		explanation.addReason("service %s/%s is not part of the mesh", service.Namespace, service.Name)
+		return explanation, nil
+	}
+
+	ports := explainedPorts(service, request.Port)
+	if len(ports) == 0 {
+		explanation.addReason("service %s/%s has no port %d", service.Namespace, service.Name, request.Port)
+		return explanation, nil
+	}
+
+	explanation.Mode = base.GetServiceMode(service.Annotations, i.defaultMode)
+
+	if !i.smiEnabled {
+		explanation.Allowed = true
+		explanation.addReason("SMI is disabled, all the traffic to the mesh services is allowed")
+
+		return explanation, nil
+	}
+
+	if err := i.explainTrafficTargets(request, service, ports, config, explanation); err != nil {
+		return nil, err
+	}
+
+	if i.hasTrafficSplit(service) {
+		explanation.addReason("the traffic of service %s/%s is split across its TrafficSplit backends", service.Namespace, service.Name)
+	}
+
+	return explanation, nil
+}
+
+// resolveSource resolves the service account and IP of the source pod, if the source is a pod.
+func (i *inspector) resolveSource(request ExplainRequest, explanation *Explanation) error {
+	if i.podLister == nil {
+		return nil
+	}
+
+	pod, err := i.podLister.Pods(request.FromNamespace).Get(request.FromName)
+	if err != nil {
+		if kubeerror.IsNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("unable to get pod: %v", err)
+	}
+
+	explanation.ServiceAccount = pod.Spec.ServiceAccountName
+	explanation.SourceIP = pod.Status.PodIP
+
+	return nil
+}
+
+func (i *inspector) explainTrafficTargets(request ExplainRequest, service *corev1.Service, ports []corev1.ServicePort, config *dynamic.Configuration, explanation *Explanation) error {
+	trafficTargets, err := i.trafficTargetLister.TrafficTargets(service.Namespace).List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("unable to get traffictargets: %v", err)
+	}
+
+	endpoints, err := base.GetEndpoints(i.endpointsLister, service.Name, service.Namespace)
+	if err != nil {
+		return fmt.Errorf("unable to get endpoints: %v", err)
+	}
+
+	for _, trafficTarget := range trafficTargets {
+		if trafficTarget.Destination.Namespace != service.Namespace {
+			continue
+		}
+
+		ttExplanation := i.explainTrafficTarget(request, service, ports, endpoints, trafficTarget, explanation, config)
+		explanation.TrafficTargets = append(explanation.TrafficTargets, ttExplanation)
+
+		if ttExplanation.allows(explanation.Mode) {
+			explanation.Allowed = true
+			explanation.addReason("allowed by TrafficTarget %s", ttExplanation.Name)
+		}
+	}
+
+	if !explanation.Allowed {
+		explanation.addReason("no TrafficTarget allows the traffic, it is denied by default")
+	}
+
+	return nil
+}
+
+func (i *inspector) explainTrafficTarget(request ExplainRequest, service *corev1.Service, ports []corev1.ServicePort, endpoints *corev1.Endpoints,
+	trafficTarget *access.TrafficTarget, explanation *Explanation, config *dynamic.Configuration) TrafficTargetExplanation {
+	ttExplanation := TrafficTargetExplanation{
+		Name:    trafficTarget.Namespace + "/" + trafficTarget.Name,
+		Matches: []string{},
+		Reasons: []string{},
+	}
+
+	ttExplanation.Applicable = smi.IsApplicableTrafficTarget(i.podLister, endpoints, trafficTarget)
+	if !ttExplanation.Applicable {
+		ttExplanation.addReason("service %s/%s has no endpoints running as service account %s on port %q",
+			service.Namespace, service.Name, trafficTarget.Destination.Name, trafficTarget.Destination.Port)
+	}
+
+	for _, source := range trafficTarget.Sources {
+		if source.Name == explanation.ServiceAccount && source.Namespace == request.FromNamespace {
+			ttExplanation.SourceAllowed = true
+		}
+	}
+
+	if ttExplanation.SourceAllowed {
+		ttExplanation.addReason("service account %s/%s is a source", request.FromNamespace, explanation.ServiceAccount)
+	} else {
+		ttExplanation.addReason("service account %s/%s is not a source", request.FromNamespace, explanation.ServiceAccount)
+	}
+
+	if explanation.Mode == k8s.ServiceTypeTCP {
+		i.explainTCPRoutes(trafficTarget, &ttExplanation)
+		return ttExplanation
+	}
+
+	i.explainHTTPRouteGroups(request, trafficTarget, &ttExplanation)
+
+	for _, port := range ports {
+		whitelist, ok := findWhitelist(config, trafficTarget, service, port.Port)
+		if !ok {
+			continue
+		}
+
+		whitelist.SourceAllowed = explanation.SourceIP != "" && containsString(whitelist.SourceRange, explanation.SourceIP)
+		ttExplanation.Whitelists = append(ttExplanation.Whitelists, whitelist)
+	}
+
+	// The whitelists of the deployed configuration may lag behind the listers, and have the final say for a source pod.
+	if ttExplanation.SourceAllowed && explanation.SourceIP != "" && len(ttExplanation.Whitelists) > 0 && !whitelistsAllow(ttExplanation.Whitelists) {
+		ttExplanation.SourceAllowed = false
+		ttExplanation.addReason("source IP %s is not whitelisted in the current configuration", explanation.SourceIP)
+	}
+
+	return ttExplanation
+}
+
+// explainHTTPRouteGroups lists the HTTPRouteGroup matches of the request path and method.
+// Like for the routers, the path regex of a match is used as a path prefix.
+func (i *inspector) explainHTTPRouteGroups(request ExplainRequest, trafficTarget *access.TrafficTarget, ttExplanation *TrafficTargetExplanation) {
+	for _, spec := range trafficTarget.Specs {
+		if spec.Kind != "HTTPRouteGroup" {
+			continue
+		}
+
+		routeGroup, err := i.httpRouteGroupLister.HTTPRouteGroups(trafficTarget.Namespace).Get(spec.Name)
+		if err != nil {
+			ttExplanation.addReason("unable to get HTTPRouteGroup %s: %v", spec.Name, err)
+			continue
+		}
+
+		for _, name := range spec.Matches {
+			for _, match := range routeGroup.Matches {
+				if match.Name != name {
+					continue
+				}
+
+				if request.Path != "" && !strings.HasPrefix(request.Path, match.PathRegex) {
+					ttExplanation.addReason("path %q does not match %s/%s", request.Path, spec.Name, name)
+					continue
+				}
+
+				if request.Method != "" && !matchesMethod(match.Methods, request.Method) {
+					ttExplanation.addReason("method %s does not match %s/%s", request.Method, spec.Name, name)
+					continue
+				}
+
+				ttExplanation.Matches = append(ttExplanation.Matches, spec.Name+"/"+name)
+			}
+		}
+	}
+
+	if len(ttExplanation.Matches) == 0 {
+		ttExplanation.addReason("no HTTPRouteGroup match applies")
+	}
+}
+
+// explainTCPRoutes checks the TCPRoutes of the TrafficTarget. TCP traffic is not filtered by source.
+func (i *inspector) explainTCPRoutes(trafficTarget *access.TrafficTarget, ttExplanation *TrafficTargetExplanation) {
+	for _, spec := range trafficTarget.Specs {
+		if spec.Kind != "TCPRoute" {
+			continue
+		}
+
+		if _, err := i.tcpRouteLister.TCPRoutes(trafficTarget.Namespace).Get(spec.Name); err != nil {
+			ttExplanation.addReason("unable to get TCPRoute %s: %v", spec.Name, err)
+			continue
+		}
+
+		ttExplanation.Matches = append(ttExplanation.Matches, spec.Name)
+	}
+
+	if len(ttExplanation.Matches) == 0 {
+		ttExplanation.addReason("no TCPRoute applies")
+		return
+	}
+
+	ttExplanation.addReason("TCP traffic is not filtered by source")
+}
+
+func (i *inspector) hasTrafficSplit(service *corev1.Service) bool {
+	if i.trafficSplitLister == nil {
+		return false
+	}
+
+	trafficSplits, err := i.trafficSplitLister.TrafficSplits(service.Namespace).List(labels.Everything())
+	if err != nil {
+		return false
+	}
+
+	return base.GetTrafficSplitFromList(service.Name, trafficSplits) != nil
+}
+
+// allows checks if the TrafficTarget allows the traffic. HTTP traffic must come from a source, and match a route.
+func (t TrafficTargetExplanation) allows(mode string) bool {
+	if !t.Applicable || len(t.Matches) == 0 {
+		return false
+	}
+
+	if mode == k8s.ServiceTypeTCP {
+		return true
+	}
+
+	return t.SourceAllowed
+}
+
+func (t *TrafficTargetExplanation) addReason(format string, args ...interface{}) {
+	t.Reasons = append(t.Reasons, fmt.Sprintf(format, args...))
+}
+
+func (e *Explanation) addReason(format string, args ...interface{}) {
+	e.Reasons = append(e.Reasons, fmt.Sprintf(format, args...))
+}
+
+// findWhitelist returns the whitelist middleware of the TrafficTarget on the service port, from the configuration.
+func findWhitelist(config *dynamic.Configuration, trafficTarget *access.TrafficTarget, service *corev1.Service, port int32) (WhitelistExplanation, bool) {
+	if config == nil || config.HTTP == nil {
+		return WhitelistExplanation{}, false
+	}
+
+	name := smi.BuildWhitelistKey(service.Name, service.Namespace, port, trafficTarget.Name, trafficTarget.Namespace)
+
+	middleware, ok := config.HTTP.Middlewares[name]
+	if !ok || middleware.IPWhiteList == nil {
+		return WhitelistExplanation{}, false
+	}
+
+	return WhitelistExplanation{Name: name, SourceRange: middleware.IPWhiteList.SourceRange}, true
+}
+
+func whitelistsAllow(whitelists []WhitelistExplanation) bool {
+	for _, whitelist := range whitelists {
+		if whitelist.SourceAllowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func explainedPorts(service *corev1.Service, port int32) []corev1.ServicePort {
+	if port == 0 {
+		return service.Spec.Ports
+	}
+
+	for _, sp := range service.Spec.Ports {
+		if sp.Port == port {
+			return []corev1.ServicePort{sp}
+		}
+	}
+
+	return nil
+}
+
+func matchesMethod(methods []string, method string) bool {
+	if len(methods) == 0 || methods[0] == "*" {
+		return true
+	}
+
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
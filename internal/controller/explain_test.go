@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/providers/smi"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	access "github.com/deislabs/smi-sdk-go/pkg/apis/access/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExplainerExplain(t *testing.T) {
+	config := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Middlewares: map[string]*dynamic.Middleware{
+				smi.BuildWhitelistKey("api", "ns", 80, "api-target", "ns"): {
+					IPWhiteList: &dynamic.IPWhiteList{SourceRange: []string{"10.1.0.2"}},
+				},
+			},
+		},
+	}
+
+	testCases := []struct {
+		desc            string
+		smiDisabled     bool
+		request         ExplainRequest
+		expectedAllowed bool
+		expectedReason  string
+	}{
+		{
+			desc:            "source pod matching a route",
+			request:         ExplainRequest{FromNamespace: "ns", FromName: "client-pod", ToNamespace: "ns", ToName: "api", Path: "/api/users", Method: "GET"},
+			expectedAllowed: true,
+			expectedReason:  "allowed by TrafficTarget ns/api-target",
+		},
+		{
+			desc:            "source service account with a method not matching the route",
+			request:         ExplainRequest{FromNamespace: "ns", FromName: "client", ToNamespace: "ns", ToName: "api", Path: "/api", Method: "POST"},
+			expectedAllowed: false,
+			expectedReason:  "no TrafficTarget allows the traffic, it is denied by default",
+		},
+		{
+			desc:            "pod which is not a source",
+			request:         ExplainRequest{FromNamespace: "ns", FromName: "other-pod", ToNamespace: "ns", ToName: "api", Path: "/api", Method: "GET"},
+			expectedAllowed: false,
+			expectedReason:  "no TrafficTarget allows the traffic, it is denied by default",
+		},
+		{
+			desc:            "source pod not whitelisted yet",
+			request:         ExplainRequest{FromNamespace: "ns", FromName: "new-client-pod", ToNamespace: "ns", ToName: "api", Path: "/api", Method: "GET"},
+			expectedAllowed: false,
+			expectedReason:  "no TrafficTarget allows the traffic, it is denied by default",
+		},
+		{
+			desc:            "tcp service",
+			request:         ExplainRequest{FromNamespace: "ns", FromName: "other-pod", ToNamespace: "ns", ToName: "db"},
+			expectedAllowed: true,
+			expectedReason:  "allowed by TrafficTarget ns/db-target",
+		},
+		{
+			desc:            "unknown port",
+			request:         ExplainRequest{FromNamespace: "ns", FromName: "client-pod", ToNamespace: "ns", ToName: "api", Port: 8443},
+			expectedAllowed: false,
+			expectedReason:  "service ns/api has no port 8443",
+		},
+		{
+			desc:            "unknown service",
+			request:         ExplainRequest{FromNamespace: "ns", FromName: "client-pod", ToNamespace: "ns", ToName: "foo"},
+			expectedAllowed: false,
+			expectedReason:  "service ns/foo does not exist",
+		},
+		{
+			desc:            "smi disabled",
+			smiDisabled:     true,
+			request:         ExplainRequest{FromNamespace: "ns", FromName: "other-pod", ToNamespace: "ns", ToName: "api"},
+			expectedAllowed: true,
+			expectedReason:  "SMI is disabled, all the traffic to the mesh services is allowed",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			i := buildTestInspector(stopCh)
+			i.smiEnabled = !test.smiDisabled
+
+			explanation, err := i.Explain(test.request, config)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expectedAllowed, explanation.Allowed)
+			assert.Contains(t, explanation.Reasons, test.expectedReason)
+		})
+	}
+}
+
+func TestFindWhitelist(t *testing.T) {
+	trafficTarget := &access.TrafficTarget{ObjectMeta: metav1.ObjectMeta{Name: "api-target", Namespace: "ns"}}
+
+	// The service names are truncated the same way in the middleware names.
+	v1Key := smi.BuildWhitelistKey("api-service-v1", "ns", 80, "api-target", "ns")
+	v2Key := smi.BuildWhitelistKey("api-service-v2", "ns", 80, "api-target", "ns")
+
+	config := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Middlewares: map[string]*dynamic.Middleware{
+				v1Key: {IPWhiteList: &dynamic.IPWhiteList{SourceRange: []string{"10.1.0.1"}}},
+				v2Key: {IPWhiteList: &dynamic.IPWhiteList{SourceRange: []string{"10.1.0.2"}}},
+			},
+		},
+	}
+
+	testCases := []struct {
+		desc              string
+		serviceName       string
+		port              int32
+		expectedFound     bool
+		expectedWhitelist WhitelistExplanation
+	}{
+		{
+			desc:              "first service",
+			serviceName:       "api-service-v1",
+			port:              80,
+			expectedFound:     true,
+			expectedWhitelist: WhitelistExplanation{Name: v1Key, SourceRange: []string{"10.1.0.1"}},
+		},
+		{
+			desc:              "second service",
+			serviceName:       "api-service-v2",
+			port:              80,
+			expectedFound:     true,
+			expectedWhitelist: WhitelistExplanation{Name: v2Key, SourceRange: []string{"10.1.0.2"}},
+		},
+		{
+			desc:        "unknown port",
+			serviceName: "api-service-v1",
+			port:        8080,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: test.serviceName, Namespace: "ns"}}
+
+			whitelist, ok := findWhitelist(config, trafficTarget, service, test.port)
+
+			assert.Equal(t, test.expectedFound, ok)
+			assert.Equal(t, test.expectedWhitelist, whitelist)
+		})
+	}
+}
+
+// buildTestInspector builds an inspector on the objects of the explain fixture.
+func buildTestInspector(stopCh <-chan struct{}) *inspector {
+	clientMock := k8s.NewClientMock(stopCh, "explain.yaml", true)
+
+	return &inspector{
+		defaultMode:          k8s.ServiceTypeHTTP,
+		ignored:              k8s.NewIgnored(),
+		serviceLister:        clientMock.ServiceLister,
+		endpointsLister:      clientMock.EndpointsLister,
+		podLister:            clientMock.PodLister,
+		trafficTargetLister:  clientMock.TrafficTargetLister,
+		httpRouteGroupLister: clientMock.HTTPRouteGroupLister,
+		tcpRouteLister:       clientMock.TCPRouteLister,
+	}
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/containous/traefik/v2/pkg/config/runtime"
+)
+
+// fakeMeshNode is an in-process mesh node, serving the REST provider and raw data endpoints of the Traefik API.
+type fakeMeshNode struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	config   *dynamic.Configuration
+	deploys  int
+	failures int
+	accepted string
+}
+
+func newFakeMeshNode() *fakeMeshNode {
+	node := &fakeMeshNode{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/providers/rest", node.putConfiguration)
+	mux.HandleFunc("/api/rawdata", node.getRawData)
+
+	node.server = httptest.NewServer(mux)
+
+	return node
+}
+
+// Close stops the node.
+func (n *fakeMeshNode) Close() {
+	n.server.Close()
+}
+
+// FailDeploys makes the next deploys to the node fail.
+func (n *fakeMeshNode) FailDeploys(count int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.failures = count
+}
+
+// ReportErrorsExcept makes the node report configuration errors while it runs another configuration version than the given one.
+func (n *fakeMeshNode) ReportErrorsExcept(version string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.accepted = version
+}
+
+// Configuration returns the last configuration deployed to the node, and the number of successful deploys.
+func (n *fakeMeshNode) Configuration() (*dynamic.Configuration, int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.config, n.deploys
+}
+
+func (n *fakeMeshNode) putConfiguration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var config dynamic.Configuration
+	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.failures > 0 {
+		n.failures--
+
+		http.Error(w, "deploy failure", http.StatusInternalServerError)
+
+		return
+	}
+
+	n.config = &config
+	n.deploys++
+}
+
+// getRawData reports the elements of the deployed configuration as the REST provider ones.
+func (n *fakeMeshNode) getRawData(w http.ResponseWriter, _ *http.Request) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	rawData := meshNodeRawData{
+		Routers:     make(map[string]*runtime.RouterInfo),
+		Middlewares: make(map[string]*runtime.MiddlewareInfo),
+		Services:    make(map[string]*runtime.ServiceInfo),
+	}
+
+	if n.config != nil && n.config.HTTP != nil {
+		var errs []string
+		if n.accepted != "" && getConfigVersion(n.config) != n.accepted {
+			errs = []string{"rejected configuration"}
+		}
+
+		for name, router := range n.config.HTTP.Routers {
+			rawData.Routers[name+"@"+configVersionProvider] = &runtime.RouterInfo{Router: router, Err: errs}
+		}
+
+		for name, middleware := range n.config.HTTP.Middlewares {
+			rawData.Middlewares[name+"@"+configVersionProvider] = &runtime.MiddlewareInfo{Middleware: middleware}
+		}
+
+		for name, service := range n.config.HTTP.Services {
+			rawData.Services[name+"@"+configVersionProvider] = &runtime.ServiceInfo{Service: service}
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(rawData); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// fakeMeshNodeTransport routes the requests to the mesh nodes API, sent to the mesh pod IPs, to their fake mesh nodes.
+func fakeMeshNodeTransport(meshNodes *MeshNodeClient, nodes map[string]*fakeMeshNode) http.RoundTripper {
+	var dialer net.Dialer
+
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			for ip, node := range nodes {
+				if addr == net.JoinHostPort(ip, fmt.Sprint(meshNodes.port)) {
+					return dialer.DialContext(ctx, network, node.server.Listener.Addr().String())
+				}
+			}
+
+			return nil, fmt.Errorf("no mesh node at %s", addr)
+		},
+	}
+}
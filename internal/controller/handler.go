@@ -1,24 +1,47 @@
 package controller
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
+
 	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/providers/base"
+	access "github.com/deislabs/smi-sdk-go/pkg/apis/access/v1alpha1"
+	split "github.com/deislabs/smi-sdk-go/pkg/apis/split/v1alpha2"
 	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers/core/v1"
 )
 
 // Handler is an implementation of a ResourceEventHandler.
 type Handler struct {
 	ignored               k8s.IgnoreWrapper
+	serviceLister         listers.ServiceLister
 	configRefreshChan     chan string
 	createMeshServiceFunc func(service *corev1.Service) error
 	updateMeshServiceFunc func(oldUserService *corev1.Service, newUserService *corev1.Service) (*corev1.Service, error)
 	deleteMeshServiceFunc func(serviceName, serviceNamespace string) error
+	syncMeshServicesFunc  func() error
+	invalidator           base.Invalidator
+	activity              *ActivityStream
+
+	eventsMu      sync.Mutex
+	events        []string
+	droppedEvents int
 }
 
+// maxPendingEvents is the maximum number of events recorded between two configuration builds.
+const maxPendingEvents = 20
+
 // NewHandler creates a handler.
-func NewHandler(ignored k8s.IgnoreWrapper, configRefreshChan chan string) *Handler {
+func NewHandler(ignored k8s.IgnoreWrapper, serviceLister listers.ServiceLister, configRefreshChan chan string) *Handler {
 	h := &Handler{
 		ignored:           ignored,
+		serviceLister:     serviceLister,
 		configRefreshChan: configRefreshChan,
 	}
 
@@ -43,6 +66,22 @@ func (h *Handler) RegisterMeshHandlers(createFunc func(service *corev1.Service)
 	h.deleteMeshServiceFunc = deleteFunc
 }
 
+// RegisterSyncMeshServicesHandler registers the function creating the missing mesh services,
+// and deleting the mesh services of the services which left the mesh.
+func (h *Handler) RegisterSyncMeshServicesHandler(syncFunc func() error) {
+	h.syncMeshServicesFunc = syncFunc
+}
+
+// RegisterInvalidator registers the invalidator of the configuration affected by the events.
+func (h *Handler) RegisterInvalidator(invalidator base.Invalidator) {
+	h.invalidator = invalidator
+}
+
+// RegisterActivityStream registers the stream publishing the events handled.
+func (h *Handler) RegisterActivityStream(activity *ActivityStream) {
+	h.activity = activity
+}
+
 // OnAdd executed when an object is added.
 func (h *Handler) OnAdd(obj interface{}) {
 	// assert the type to an object to pull out relevant data
@@ -57,12 +96,24 @@ func (h *Handler) OnAdd(obj interface{}) {
 		}
 	case *corev1.Endpoints:
 		return
+	case *unstructured.Unstructured:
+		// New EndpointSlices are added when services are scaled up.
+		if h.isIgnoredEndpointSlice(obj) {
+			return
+		}
 	case *corev1.Pod:
-		if !isMeshPod(obj) {
+		// User pods are SMI sources once they have an IP.
+		if !isMeshPod(obj) && obj.Status.PodIP == "" {
 			return
 		}
+	case *corev1.Namespace:
+		// Services of new namespaces are created afterwards.
+		return
 	}
 
+	h.invalidate(obj)
+	h.recordEvent("added", obj)
+
 	// Trigger a configuration rebuild.
 	h.configRefreshChan <- k8s.ConfigMessageChanRebuild
 }
@@ -72,36 +123,79 @@ func (h *Handler) OnUpdate(oldObj, newObj interface{}) {
 	// Assert the type to an object to pull out relevant data.
 	switch obj := newObj.(type) {
 	case *corev1.Service:
-		if h.ignored.IsIgnored(obj.ObjectMeta) {
-			return
-		}
-
 		oldService := oldObj.(*corev1.Service)
-		if _, err := h.updateMeshServiceFunc(oldService, obj); err != nil {
-			log.Errorf("Could not update mesh service: %v", err)
+		oldIgnored := h.ignored.IsIgnored(oldService.ObjectMeta)
+
+		switch {
+		case h.ignored.IsIgnored(obj.ObjectMeta):
+			if oldIgnored {
+				return
+			}
+
+			// The service left the mesh.
+			if err := h.deleteMeshServiceFunc(obj.Name, obj.Namespace); err != nil {
+				log.Errorf("Could not delete mesh service: %v", err)
+			}
+		case oldIgnored:
+			// The service joined the mesh.
+			if err := h.createMeshServiceFunc(obj); err != nil {
+				log.Errorf("Could not create mesh service: %v", err)
+			}
+		default:
+			if _, err := h.updateMeshServiceFunc(oldService, obj); err != nil {
+				log.Errorf("Could not update mesh service: %v", err)
+			}
 		}
 
 		log.Debugf("MeshControllerHandler ObjectUpdated with type: *corev1.Service: %s/%s", obj.Namespace, obj.Name)
 	case *corev1.Endpoints:
-		// We can use the same ignore for services and endpoints.
-		if h.ignored.IsIgnored(obj.ObjectMeta) {
+		if h.isIgnoredEndpoints(obj) {
 			return
 		}
 
 		log.Debugf("MeshControllerHandler ObjectUpdated with type: *corev1.Endpoints: %s/%s", obj.Namespace, obj.Name)
+	case *unstructured.Unstructured:
+		if h.isIgnoredEndpointSlice(obj) {
+			return
+		}
+
+		log.Debugf("MeshControllerHandler ObjectUpdated with type: %s: %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
 	case *corev1.Pod:
 		if !isMeshPod(obj) {
-			// We don't track updates of user pods, updates are done through endpoints.
-			return
+			// Only the IP of the user pods is used, as an SMI source, other updates are done through endpoints.
+			if oldObj.(*corev1.Pod).Status.PodIP == obj.Status.PodIP {
+				return
+			}
+
+			log.Debugf("MeshControllerHandler ObjectUpdated with type: *corev1.Pod: %s/%s", obj.Namespace, obj.Name)
+
+			break
 		}
 
 		log.Debugf("MeshControllerHandler ObjectUpdated with type: *corev1.Pod: %s/%s", obj.Namespace, obj.Name)
 		// Since this is a mesh pod update, trigger a force deploy.
+		h.recordEvent("updated", obj)
 		h.configRefreshChan <- k8s.ConfigMessageChanForce
 
 		return
+	case *corev1.Namespace:
+		oldNamespace := oldObj.(*corev1.Namespace)
+		if labels.Equals(oldNamespace.Labels, obj.Labels) {
+			return
+		}
+
+		log.Debugf("MeshControllerHandler ObjectUpdated with type: *corev1.Namespace: %s", obj.Name)
+
+		// The namespace may have been selected or unselected, create or delete the mesh services of its services.
+		if err := h.syncMeshServicesFunc(); err != nil {
+			log.Errorf("Could not sync mesh services: %v", err)
+		}
 	}
 
+	h.invalidate(oldObj)
+	h.invalidate(newObj)
+	h.recordEvent("updated", newObj)
+
 	// Trigger a configuration rebuild.
 	h.configRefreshChan <- k8s.ConfigMessageChanRebuild
 }
@@ -121,16 +215,162 @@ func (h *Handler) OnDelete(obj interface{}) {
 			log.Errorf("Could not delete mesh service: %v", err)
 		}
 	case *corev1.Endpoints:
-		// We can use the same ignore for services and endpoints.
-		if h.ignored.IsIgnored(obj.ObjectMeta) {
+		if h.isIgnoredEndpoints(obj) {
 			return
 		}
 
 		log.Debugf("MeshController ObjectDeleted with type: *corev1.Endpoints: %s/%s", obj.Namespace, obj.Name)
+	case *unstructured.Unstructured:
+		if h.isIgnoredEndpointSlice(obj) {
+			return
+		}
+
+		log.Debugf("MeshController ObjectDeleted with type: %s: %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
 	case *corev1.Pod:
+		// Deleted user pods are not SMI sources anymore.
+		if isMeshPod(obj) || obj.Status.PodIP == "" {
+			return
+		}
+
+		log.Debugf("MeshController ObjectDeleted with type: *corev1.Pod: %s/%s", obj.Namespace, obj.Name)
+	case *corev1.Namespace:
 		return
 	}
 
+	h.invalidate(obj)
+	h.recordEvent("deleted", obj)
+
 	// Trigger a configuration rebuild.
 	h.configRefreshChan <- k8s.ConfigMessageChanRebuild
 }
+
+// TakeEvents returns the events recorded since the last call, which triggered the configuration build.
+func (h *Handler) TakeEvents() []string {
+	h.eventsMu.Lock()
+	defer h.eventsMu.Unlock()
+
+	events := h.events
+	if h.droppedEvents > 0 {
+		events = append(events, fmt.Sprintf("and %d more events", h.droppedEvents))
+	}
+
+	h.events = nil
+	h.droppedEvents = 0
+
+	return events
+}
+
+// recordEvent records an event triggering a configuration build, like "updated Service default/foo", and publishes it.
+func (h *Handler) recordEvent(action string, obj interface{}) {
+	description := describeEvent(action, obj)
+
+	if accessor, err := meta.Accessor(obj); err == nil {
+		h.activity.Publish(ActivityEvent{
+			Type:      ActivityTypeInformer,
+			Namespace: accessor.GetNamespace(),
+			Service:   eventService(obj),
+			Message:   description,
+		})
+	}
+
+	h.eventsMu.Lock()
+	defer h.eventsMu.Unlock()
+
+	if len(h.events) >= maxPendingEvents {
+		h.droppedEvents++
+		return
+	}
+
+	h.events = append(h.events, description)
+}
+
+// eventService returns the name of the service an object belongs to, or an empty string if there is none.
+func eventService(obj interface{}) string {
+	switch obj := obj.(type) {
+	case *corev1.Service:
+		return obj.Name
+	case *corev1.Endpoints:
+		return obj.Name
+	case *unstructured.Unstructured:
+		return obj.GetLabels()[k8s.LabelEndpointSliceServiceName]
+	default:
+		return ""
+	}
+}
+
+// describeEvent describes an action on an object.
+func describeEvent(action string, obj interface{}) string {
+	kind := reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		kind = u.GetKind()
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return fmt.Sprintf("%s %s", action, kind)
+	}
+
+	if accessor.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s %s", action, kind, accessor.GetName())
+	}
+
+	return fmt.Sprintf("%s %s %s/%s", action, kind, accessor.GetNamespace(), accessor.GetName())
+}
+
+// isIgnoredEndpoints returns true if the service of the endpoints is not part of the mesh.
+func (h *Handler) isIgnoredEndpoints(endpoints *corev1.Endpoints) bool {
+	service, err := h.serviceLister.Services(endpoints.Namespace).Get(endpoints.Name)
+	if err != nil {
+		// The service is not known yet, fallback on the endpoints metadata, which are mostly the same.
+		return h.ignored.IsIgnored(endpoints.ObjectMeta)
+	}
+
+	return h.ignored.IsIgnored(service.ObjectMeta)
+}
+
+// isIgnoredEndpointSlice returns true if the object is not an EndpointSlice of a service part of the mesh.
+func (h *Handler) isIgnoredEndpointSlice(obj *unstructured.Unstructured) bool {
+	if !k8s.IsEndpointSlice(obj) {
+		return true
+	}
+
+	serviceName := obj.GetLabels()[k8s.LabelEndpointSliceServiceName]
+	if serviceName == "" {
+		return true
+	}
+
+	service, err := h.serviceLister.Services(obj.GetNamespace()).Get(serviceName)
+	if err != nil {
+		return true
+	}
+
+	return h.ignored.IsIgnored(service.ObjectMeta)
+}
+
+// invalidate invalidates the configuration affected by a change of the given object.
+func (h *Handler) invalidate(obj interface{}) {
+	if h.invalidator == nil {
+		return
+	}
+
+	switch obj := obj.(type) {
+	case *corev1.Service:
+		h.invalidator.InvalidateService(obj.Namespace, obj.Name)
+	case *corev1.Endpoints:
+		h.invalidator.InvalidateService(obj.Namespace, obj.Name)
+	case *unstructured.Unstructured:
+		h.invalidator.InvalidateService(obj.GetNamespace(), obj.GetLabels()[k8s.LabelEndpointSliceServiceName])
+	case *corev1.Pod:
+		// Mesh pods are not part of the configuration, user pods are the SMI sources of the services depending on their namespace.
+		if !isMeshPod(obj) {
+			h.invalidator.InvalidateNamespace(obj.Namespace)
+		}
+	case *access.TrafficTarget:
+		h.invalidator.InvalidateNamespace(obj.Destination.Namespace)
+	case *split.TrafficSplit:
+		h.invalidator.InvalidateNamespace(obj.Namespace)
+	default:
+		// Routes may be used by any traffic target, and namespace changes may change the ignored services.
+		h.invalidator.InvalidateAll()
+	}
+}
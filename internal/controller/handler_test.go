@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// recordingInvalidator records the invalidated namespaces.
+type recordingInvalidator struct {
+	namespaces []string
+}
+
+func (r *recordingInvalidator) InvalidateService(_, _ string) {}
+
+func (r *recordingInvalidator) InvalidateNamespace(namespace string) {
+	r.namespaces = append(r.namespaces, namespace)
+}
+
+func (r *recordingInvalidator) InvalidateAll() {}
+
+func TestHandlerUserPods(t *testing.T) {
+	pod := func(ip string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "foo"},
+			Status:     corev1.PodStatus{PodIP: ip},
+		}
+	}
+
+	ready := pod("10.0.0.1")
+	ready.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+
+	testCases := []struct {
+		desc       string
+		event      func(h *Handler)
+		invalidate bool
+	}{
+		{
+			desc:  "added without IP",
+			event: func(h *Handler) { h.OnAdd(pod("")) },
+		},
+		{
+			desc:       "added with IP",
+			event:      func(h *Handler) { h.OnAdd(pod("10.0.0.1")) },
+			invalidate: true,
+		},
+		{
+			desc:       "IP assigned",
+			event:      func(h *Handler) { h.OnUpdate(pod(""), pod("10.0.0.1")) },
+			invalidate: true,
+		},
+		{
+			desc:  "status updated",
+			event: func(h *Handler) { h.OnUpdate(pod("10.0.0.1"), ready) },
+		},
+		{
+			desc:       "deleted",
+			event:      func(h *Handler) { h.OnDelete(pod("10.0.0.1")) },
+			invalidate: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			refreshChan := make(chan string, 1)
+			invalidator := &recordingInvalidator{}
+
+			h := NewHandler(k8s.NewIgnored(), nil, refreshChan)
+			h.RegisterInvalidator(invalidator)
+			h.RegisterActivityStream(NewActivityStream())
+
+			test.event(h)
+
+			if !test.invalidate {
+				assert.Empty(t, invalidator.namespaces)
+				assert.Empty(t, refreshChan)
+
+				return
+			}
+
+			assert.Contains(t, invalidator.namespaces, "foo")
+			assert.Equal(t, k8s.ConfigMessageChanRebuild, <-refreshChan)
+		})
+	}
+}
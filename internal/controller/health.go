@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// HealthStatus is the health report of the controller.
+type HealthStatus struct {
+	// Healthy is false if an informer is not synced, or if the readiness is degraded.
+	Healthy  bool
+	Degraded bool
+	// Informers holds the sync state of the informers, keyed by resource type.
+	Informers map[string]bool
+
+	LastRebuild      time.Time
+	SinceLastRebuild string `json:",omitempty"`
+	RebuildFailures  int
+	LastRebuildError string `json:",omitempty"`
+
+	LastDeploy      time.Time
+	SinceLastDeploy string `json:",omitempty"`
+	DeployFailures  int
+	LastDeployError string `json:",omitempty"`
+
+	// StalePods lists the mesh pods not running the current configuration.
+	StalePods []string
+
+	TCPStateErrors    int
+	LastTCPStateError string `json:",omitempty"`
+}
+
+// Health tracks the outcome of the configuration rebuilds and deploys, and of the TCP state persistence.
+type Health struct {
+	mu sync.RWMutex
+	// failureThreshold is the number of consecutive rebuild or deploy failures degrading the readiness, never if 0.
+	failureThreshold int
+
+	lastRebuild      time.Time
+	rebuildFailures  int
+	lastRebuildError string
+
+	lastDeploy      time.Time
+	deployFailures  int
+	lastDeployError string
+
+	tcpStateErrors    int
+	lastTCPStateError string
+}
+
+// NewHealth returns an initialized Health, degrading the readiness after failureThreshold consecutive failures.
+func NewHealth(failureThreshold int) *Health {
+	return &Health{
+		failureThreshold: failureThreshold,
+	}
+}
+
+// RecordRebuild records the outcome of a configuration rebuild.
+func (h *Health) RecordRebuild(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err != nil {
+		h.rebuildFailures++
+		h.lastRebuildError = err.Error()
+
+		return
+	}
+
+	h.lastRebuild = time.Now()
+	h.rebuildFailures = 0
+	h.lastRebuildError = ""
+}
+
+// RecordDeploy records the outcome of a configuration deployment.
+func (h *Health) RecordDeploy(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err != nil {
+		h.deployFailures++
+		h.lastDeployError = err.Error()
+
+		return
+	}
+
+	h.lastDeploy = time.Now()
+	h.deployFailures = 0
+	h.lastDeployError = ""
+}
+
+// RecordTCPStateError records a failure to persist the TCP state table.
+func (h *Health) RecordTCPStateError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.tcpStateErrors++
+	h.lastTCPStateError = err.Error()
+}
+
+// Degraded returns true if the rebuilds or the deploys failed failureThreshold times in a row.
+func (h *Health) Degraded() bool {
+	if h == nil {
+		return false
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.degraded()
+}
+
+func (h *Health) degraded() bool {
+	if h.failureThreshold <= 0 {
+		return false
+	}
+
+	return h.rebuildFailures >= h.failureThreshold || h.deployFailures >= h.failureThreshold
+}
+
+// Status returns the health report, given the informer sync states and the stale pods.
+func (h *Health) Status(now time.Time, informers map[string]bool, stalePods []string) HealthStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	status := HealthStatus{
+		Degraded:          h.degraded(),
+		Informers:         informers,
+		LastRebuild:       h.lastRebuild,
+		RebuildFailures:   h.rebuildFailures,
+		LastRebuildError:  h.lastRebuildError,
+		LastDeploy:        h.lastDeploy,
+		DeployFailures:    h.deployFailures,
+		LastDeployError:   h.lastDeployError,
+		StalePods:         stalePods,
+		TCPStateErrors:    h.tcpStateErrors,
+		LastTCPStateError: h.lastTCPStateError,
+	}
+
+	if !h.lastRebuild.IsZero() {
+		status.SinceLastRebuild = now.Sub(h.lastRebuild).Round(time.Second).String()
+	}
+
+	if !h.lastDeploy.IsZero() {
+		status.SinceLastDeploy = now.Sub(h.lastDeploy).Round(time.Second).String()
+	}
+
+	status.Healthy = !status.Degraded
+
+	for _, synced := range informers {
+		status.Healthy = status.Healthy && synced
+	}
+
+	return status
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthDegraded(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		failureThreshold int
+		rebuildErrs      []error
+		deployErrs       []error
+		expected         bool
+	}{
+		{
+			desc:             "no failure",
+			failureThreshold: 2,
+			rebuildErrs:      []error{nil},
+			deployErrs:       []error{nil},
+			expected:         false,
+		},
+		{
+			desc:             "consecutive rebuild failures",
+			failureThreshold: 2,
+			rebuildErrs:      []error{errors.New("boom"), errors.New("boom")},
+			expected:         true,
+		},
+		{
+			desc:             "consecutive deploy failures",
+			failureThreshold: 2,
+			deployErrs:       []error{nil, errors.New("boom"), errors.New("boom")},
+			expected:         true,
+		},
+		{
+			desc:             "failures interrupted by a success",
+			failureThreshold: 2,
+			deployErrs:       []error{errors.New("boom"), nil, errors.New("boom")},
+			expected:         false,
+		},
+		{
+			desc:        "disabled threshold",
+			rebuildErrs: []error{errors.New("boom"), errors.New("boom")},
+			expected:    false,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			health := NewHealth(test.failureThreshold)
+
+			for _, err := range test.rebuildErrs {
+				health.RecordRebuild(err)
+			}
+
+			for _, err := range test.deployErrs {
+				health.RecordDeploy(err)
+			}
+
+			assert.Equal(t, test.expected, health.Degraded())
+		})
+	}
+}
+
+func TestHealthStatus(t *testing.T) {
+	health := NewHealth(0)
+
+	health.RecordRebuild(nil)
+	health.RecordDeploy(errors.New("boom"))
+	health.RecordTCPStateError(errors.New("conflict"))
+
+	status := health.Status(time.Now().Add(time.Minute), map[string]bool{"*v1.Service": true, "*v1.Pod": false}, []string{"maesh-mesh-abc"})
+
+	assert.False(t, status.Healthy)
+	assert.False(t, status.Degraded)
+	assert.Equal(t, "1m0s", status.SinceLastRebuild)
+	assert.Empty(t, status.SinceLastDeploy)
+	assert.Equal(t, 1, status.DeployFailures)
+	assert.Equal(t, "boom", status.LastDeployError)
+	assert.Equal(t, []string{"maesh-mesh-abc"}, status.StalePods)
+	assert.Equal(t, 1, status.TCPStateErrors)
+	assert.Equal(t, "conflict", status.LastTCPStateError)
+
+	status = health.Status(time.Now(), map[string]bool{"*v1.Service": true}, nil)
+
+	assert.True(t, status.Healthy)
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+)
+
+// HistoryEntry holds the details of a configuration deployed by the controller.
+type HistoryEntry struct {
+	Version      string
+	TimeStamp    time.Time
+	Events       []string
+	RolloutPhase string
+}
+
+type historyRecord struct {
+	entry  HistoryEntry
+	config *dynamic.Configuration
+}
+
+// ConfigHistory holds a bounded history of the deployed configurations, in a ring buffer.
+type ConfigHistory struct {
+	mu         sync.RWMutex
+	records    []historyRecord
+	oldest     int
+	maxEntries int
+}
+
+// NewConfigHistory returns an initialized ConfigHistory.
+func NewConfigHistory(maxEntries int) *ConfigHistory {
+	return &ConfigHistory{
+		records:    make([]historyRecord, 0, maxEntries),
+		maxEntries: maxEntries,
+	}
+}
+
+// Add records a deployed configuration, overwriting the oldest one if the history is full.
+// Deploying the latest configuration again, like for new mesh nodes, updates its entry instead.
+func (h *ConfigHistory) Add(entry HistoryEntry, config *dynamic.Configuration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.records) > 0 {
+		latest := h.record(len(h.records) - 1)
+
+		if latest.entry.Version == entry.Version {
+			latest.entry.TimeStamp = entry.TimeStamp
+			latest.entry.RolloutPhase = entry.RolloutPhase
+			latest.entry.Events = append(latest.entry.Events, entry.Events...)
+
+			if len(latest.entry.Events) > maxPendingEvents {
+				latest.entry.Events = append([]string(nil), latest.entry.Events[len(latest.entry.Events)-maxPendingEvents:]...)
+			}
+
+			return
+		}
+	}
+
+	record := historyRecord{entry: entry, config: config}
+
+	if len(h.records) < h.maxEntries {
+		h.records = append(h.records, record)
+		return
+	}
+
+	h.records[h.oldest] = record
+	h.oldest = (h.oldest + 1) % h.maxEntries
+}
+
+// Entries returns the history entries, from the oldest to the latest.
+func (h *ConfigHistory) Entries() []HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	entries := make([]HistoryEntry, 0, len(h.records))
+	for i := 0; i < len(h.records); i++ {
+		entries = append(entries, h.record(i).entry)
+	}
+
+	return entries
+}
+
+// Get returns the configuration with the given version, if it is still in the history.
+func (h *ConfigHistory) Get(version string) (*dynamic.Configuration, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for i := len(h.records) - 1; i >= 0; i-- {
+		if record := h.record(i); record.entry.Version == version {
+			return record.config, true
+		}
+	}
+
+	return nil, false
+}
+
+// record returns the i-th record, from the oldest to the latest.
+func (h *ConfigHistory) record(i int) *historyRecord {
+	return &h.records[(h.oldest+i)%len(h.records)]
+}
+
+// ConfigDiff lists the elements which differ between two configurations.
+// Elements are named after their kind and name, like "http/routers/foo".
+type ConfigDiff struct {
+	From    string
+	To      string
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// diffConfigurations returns the elements added, removed and changed from one configuration to another.
+func diffConfigurations(from, to *dynamic.Configuration) ConfigDiff {
+	diff := ConfigDiff{
+		From: getConfigVersion(from),
+		To:   getConfigVersion(to),
+	}
+
+	fromElements := configElements(from)
+	toElements := configElements(to)
+
+	for name, element := range toElements {
+		previous, ok := fromElements[name]
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, name)
+		case !reflect.DeepEqual(previous, element):
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+
+	for name := range fromElements {
+		if _, ok := toElements[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
+
+// configElements flattens the configuration into its elements, keyed by kind and name.
+func configElements(config *dynamic.Configuration) map[string]interface{} {
+	elements := make(map[string]interface{})
+
+	if config == nil {
+		return elements
+	}
+
+	if config.HTTP != nil {
+		for name, router := range config.HTTP.Routers {
+			elements["http/routers/"+name] = router
+		}
+
+		for name, service := range config.HTTP.Services {
+			elements["http/services/"+name] = service
+		}
+
+		for name, middleware := range config.HTTP.Middlewares {
+			if name == configVersionMiddleware {
+				continue
+			}
+
+			elements["http/middlewares/"+name] = middleware
+		}
+	}
+
+	if config.TCP != nil {
+		for name, router := range config.TCP.Routers {
+			elements["tcp/routers/"+name] = router
+		}
+
+		for name, service := range config.TCP.Services {
+			elements["tcp/services/"+name] = service
+		}
+	}
+
+	return elements
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigHistory(t *testing.T) {
+	history := NewConfigHistory(2)
+
+	foo := buildVersionedConfig(t, "foo")
+	bar := buildVersionedConfig(t, "bar")
+	baz := buildVersionedConfig(t, "baz")
+
+	history.Add(HistoryEntry{Version: "foo", TimeStamp: time.Now(), Events: []string{"added Service default/foo"}}, foo)
+	history.Add(HistoryEntry{Version: "bar", TimeStamp: time.Now(), Events: []string{"added Service default/bar"}}, bar)
+
+	// Deploying the latest configuration again updates its entry.
+	history.Add(HistoryEntry{Version: "bar", TimeStamp: time.Now(), Events: []string{"updated Pod maesh/maesh-mesh-abcde"}, RolloutPhase: RolloutPhaseSucceeded}, bar)
+
+	entries := history.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, []string{"added Service default/bar", "updated Pod maesh/maesh-mesh-abcde"}, entries[1].Events)
+	assert.Equal(t, RolloutPhaseSucceeded, entries[1].RolloutPhase)
+
+	// The oldest configuration is dropped.
+	history.Add(HistoryEntry{Version: "baz", TimeStamp: time.Now()}, baz)
+
+	entries = history.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "bar", entries[0].Version)
+	assert.Equal(t, "baz", entries[1].Version)
+
+	_, ok := history.Get("foo")
+	assert.False(t, ok)
+
+	// The dropped configuration is overwritten, not kept in the backing array.
+	assert.Equal(t, 2, cap(history.records))
+
+	for _, record := range history.records {
+		assert.False(t, record.config == foo)
+	}
+
+	config, ok := history.Get("bar")
+	require.True(t, ok)
+	assert.True(t, config == bar)
+}
+
+func TestDiffConfigurations(t *testing.T) {
+	from := buildVersionedConfig(t, "from")
+	from.HTTP.Routers = map[string]*dynamic.Router{
+		"removed": {Service: "removed"},
+		"changed": {Service: "changed", Rule: "Host(`foo`)"},
+		"same":    {Service: "same"},
+	}
+
+	to := buildVersionedConfig(t, "to")
+	to.HTTP.Routers = map[string]*dynamic.Router{
+		"changed": {Service: "changed", Rule: "Host(`bar`)"},
+		"same":    {Service: "same"},
+	}
+	to.TCP = &dynamic.TCPConfiguration{
+		Services: map[string]*dynamic.TCPService{"added": {}},
+	}
+
+	expected := ConfigDiff{
+		From:    "from",
+		To:      "to",
+		Added:   []string{"tcp/services/added"},
+		Removed: []string{"http/routers/removed"},
+		Changed: []string{"http/routers/changed"},
+	}
+
+	assert.Equal(t, expected, diffConfigurations(from, to))
+}
@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"github.com/containous/maesh/internal/k8s"
+	accessLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/access/listers/access/v1alpha1"
+	specsLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/specs/listers/specs/v1alpha1"
+	splitLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/listers/split/v1alpha2"
+	listers "k8s.io/client-go/listers/core/v1"
+)
+
+// inspector evaluates the listers and the current configuration to explain the mesh decisions, and describe the mesh services.
+// The SMI listers are nil when SMI is disabled.
+type inspector struct {
+	smiEnabled           bool
+	defaultMode          string
+	meshNamespace        string
+	ignored              k8s.IgnoreWrapper
+	serviceLister        listers.ServiceLister
+	endpointsLister      listers.EndpointsLister
+	podLister            listers.PodLister
+	trafficTargetLister  accessLister.TrafficTargetLister
+	httpRouteGroupLister specsLister.HTTPRouteGroupLister
+	tcpRouteLister       specsLister.TCPRouteLister
+	trafficSplitLister   splitLister.TrafficSplitLister
+}
@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"sort"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -15,10 +17,57 @@ type Entry struct {
 	Reason           string
 }
 
-// DeployLog holds a slice of log entries.
+// PodSummary holds the last deployment results of a pod.
+type PodSummary struct {
+	PodName           string
+	PodIP             string
+	Successes         int
+	Failures          int
+	LastSuccess       time.Time
+	LastFailure       time.Time
+	LastFailureReason string
+}
+
+// DeployLogQuery filters the entries of a DeployLog. Zero values match all the entries.
+type DeployLogQuery struct {
+	PodName string
+	// Successful selects the successful deployments if true, the failed ones if false.
+	Successful *bool
+	Since      time.Time
+	Until      time.Time
+	// Limit is the maximum number of entries returned, the latest ones being kept.
+	Limit int
+}
+
+// matches checks if the entry matches the query.
+func (q DeployLogQuery) matches(entry Entry) bool {
+	if q.PodName != "" && entry.PodName != q.PodName {
+		return false
+	}
+
+	if q.Successful != nil && entry.DeploySuccessful != *q.Successful {
+		return false
+	}
+
+	if !q.Since.IsZero() && entry.TimeStamp.Before(q.Since) {
+		return false
+	}
+
+	if !q.Until.IsZero() && entry.TimeStamp.After(q.Until) {
+		return false
+	}
+
+	return true
+}
+
+// DeployLog holds a ring buffer of log entries, and a summary of the deployments of each pod.
+// It is safe for concurrent use.
 type DeployLog struct {
+	mu         sync.RWMutex
 	entries    []Entry
+	oldest     int
 	maxEntries int
+	summaries  map[string]*PodSummary
 }
 
 // NewDeployLog returns an initialized DeployLog.
@@ -38,10 +87,13 @@ func NewDeployLog(maxEntries int) *DeployLog {
 func (d *DeployLog) Init() error {
 	log.Debug("DeployLog.Init")
 
+	d.entries = make([]Entry, 0, d.maxEntries)
+	d.summaries = make(map[string]*PodSummary)
+
 	return nil
 }
 
-// LogDeploy adds a record to the entries list.
+// LogDeploy adds a record to the entries list, overwriting the oldest one if the list is full.
 func (d *DeployLog) LogDeploy(timeStamp time.Time, podName string, podIP string, deploySuccessful bool, reason string) {
 	newEntry := Entry{
 		TimeStamp:        timeStamp,
@@ -51,15 +103,107 @@ func (d *DeployLog) LogDeploy(timeStamp time.Time, podName string, podIP string,
 		Reason:           reason,
 	}
 
-	for len(d.entries) >= d.maxEntries {
-		// Pull elements off the front of the slice to make sure that the newly appended record is one under the max record value.
-		d.entries = d.entries[1:]
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.entries) < d.maxEntries {
+		d.entries = append(d.entries, newEntry)
+	} else {
+		d.entries[d.oldest] = newEntry
+		d.oldest = (d.oldest + 1) % d.maxEntries
 	}
 
-	d.entries = append(d.entries, newEntry)
+	d.updateSummary(newEntry)
 }
 
-// GetLog returns a json representation of the entries list.
+// GetLog returns a copy of the entries list, from the oldest to the latest.
 func (d *DeployLog) GetLog() []Entry {
-	return d.entries
+	return d.Query(DeployLogQuery{})
+}
+
+// Query returns a copy of the entries matching the query, from the oldest to the latest.
+func (d *DeployLog) Query(query DeployLogQuery) []Entry {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(d.entries))
+
+	for i := 0; i < len(d.entries); i++ {
+		entry := d.entries[(d.oldest+i)%len(d.entries)]
+
+		if query.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+
+	return entries
+}
+
+// Summaries returns the deployment summary of each pod, sorted by pod name.
+func (d *DeployLog) Summaries() []PodSummary {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	summaries := make([]PodSummary, 0, len(d.summaries))
+	for _, summary := range d.summaries {
+		summaries = append(summaries, *summary)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].PodName < summaries[j].PodName
+	})
+
+	return summaries
+}
+
+// updateSummary records the entry in the summary of its pod.
+// The summaries are bounded like the entries, the pod with the oldest deployment being dropped first.
+func (d *DeployLog) updateSummary(entry Entry) {
+	summary, ok := d.summaries[entry.PodName]
+	if !ok {
+		if len(d.summaries) >= d.maxEntries {
+			d.dropOldestSummary()
+		}
+
+		summary = &PodSummary{PodName: entry.PodName}
+		d.summaries[entry.PodName] = summary
+	}
+
+	summary.PodIP = entry.PodIP
+
+	if entry.DeploySuccessful {
+		summary.Successes++
+		summary.LastSuccess = entry.TimeStamp
+
+		return
+	}
+
+	summary.Failures++
+	summary.LastFailure = entry.TimeStamp
+	summary.LastFailureReason = entry.Reason
+}
+
+func (d *DeployLog) dropOldestSummary() {
+	var (
+		oldestName string
+		oldestTime time.Time
+	)
+
+	for name, summary := range d.summaries {
+		last := summary.LastSuccess
+		if summary.LastFailure.After(last) {
+			last = summary.LastFailure
+		}
+
+		if oldestName == "" || last.Before(oldestTime) {
+			oldestName = name
+			oldestTime = last
+		}
+	}
+
+	delete(d.summaries, oldestName)
 }
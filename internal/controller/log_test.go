@@ -46,3 +46,105 @@ func TestLogRotationAndGetLogLength(t *testing.T) {
 
 	assert.Equal(t, 10, len(log.entries))
 }
+
+func TestLogRotationOrder(t *testing.T) {
+	log := NewDeployLog(3)
+
+	for i := 0; i < 5; i++ {
+		log.LogDeploy(time.Now(), fmt.Sprintf("pod-%d", i), "bar", true, "")
+	}
+
+	var names []string
+	for _, entry := range log.GetLog() {
+		names = append(names, entry.PodName)
+	}
+
+	assert.Equal(t, []string{"pod-2", "pod-3", "pod-4"}, names)
+}
+
+func TestQuery(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	log := NewDeployLog(10)
+	log.LogDeploy(start, "foo", "10.0.0.1", true, "")
+	log.LogDeploy(start.Add(time.Minute), "bar", "10.0.0.2", false, "boom")
+	log.LogDeploy(start.Add(2*time.Minute), "foo", "10.0.0.1", false, "boom")
+	log.LogDeploy(start.Add(3*time.Minute), "foo", "10.0.0.1", true, "")
+
+	successful := true
+	failed := false
+
+	testCases := []struct {
+		desc     string
+		query    DeployLogQuery
+		expected []time.Duration
+	}{
+		{
+			desc:     "no filter",
+			expected: []time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute},
+		},
+		{
+			desc:     "pod name",
+			query:    DeployLogQuery{PodName: "foo"},
+			expected: []time.Duration{0, 2 * time.Minute, 3 * time.Minute},
+		},
+		{
+			desc:     "successful deployments",
+			query:    DeployLogQuery{Successful: &successful},
+			expected: []time.Duration{0, 3 * time.Minute},
+		},
+		{
+			desc:     "failed deployments of a pod",
+			query:    DeployLogQuery{PodName: "foo", Successful: &failed},
+			expected: []time.Duration{2 * time.Minute},
+		},
+		{
+			desc:     "time range",
+			query:    DeployLogQuery{Since: start.Add(time.Minute), Until: start.Add(2 * time.Minute)},
+			expected: []time.Duration{time.Minute, 2 * time.Minute},
+		},
+		{
+			desc:     "limit keeps the latest entries",
+			query:    DeployLogQuery{Limit: 2},
+			expected: []time.Duration{2 * time.Minute, 3 * time.Minute},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var actual []time.Duration
+			for _, entry := range log.Query(test.query) {
+				actual = append(actual, entry.TimeStamp.Sub(start))
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestSummaries(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	log := NewDeployLog(2)
+	log.LogDeploy(start, "foo", "10.0.0.1", true, "")
+	log.LogDeploy(start.Add(time.Minute), "foo", "10.0.0.1", false, "boom")
+	log.LogDeploy(start.Add(2*time.Minute), "bar", "10.0.0.2", true, "")
+
+	expected := []PodSummary{
+		{PodName: "bar", PodIP: "10.0.0.2", Successes: 1, LastSuccess: start.Add(2 * time.Minute)},
+		{PodName: "foo", PodIP: "10.0.0.1", Successes: 1, Failures: 1, LastSuccess: start, LastFailure: start.Add(time.Minute), LastFailureReason: "boom"},
+	}
+
+	assert.Equal(t, expected, log.Summaries())
+
+	// The summary of the pod with the oldest deployment is dropped.
+	log.LogDeploy(start.Add(3*time.Minute), "baz", "10.0.0.3", true, "")
+
+	summaries := log.Summaries()
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, "bar", summaries[0].PodName)
+	assert.Equal(t, "baz", summaries[1].PodName)
+}
@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defaults of the mesh nodes API, as exposed by the mesh node daemonset.
+const (
+	DefaultMeshNodePort   = 8080
+	DefaultMeshNodeScheme = "http"
+)
+
+// MeshNodeConfig holds the settings of the API of the mesh nodes.
+type MeshNodeConfig struct {
+	Port   int
+	Scheme string
+	// CAFile is the path to the CA bundle verifying the certificates of the mesh nodes, the system CAs being used if not set.
+	CAFile string
+	// ServerName is the name verified in the certificates of the mesh nodes, which are reached by IP. Their IP if not set.
+	ServerName string
+	// Username and Password are the basic authentication credentials of the mesh nodes API.
+	Username string
+	Password string
+	// Token is the bearer token of the mesh nodes API, used if there are no basic authentication credentials.
+	Token string
+}
+
+// MeshNodeClient sends requests to the API of the mesh nodes.
+type MeshNodeClient struct {
+	port      int
+	scheme    string
+	username  string
+	password  string
+	token     string
+	transport http.RoundTripper
+}
+
+// NewMeshNodeClient returns a client of the mesh nodes API. The defaults are used for the zero port and scheme.
+func NewMeshNodeClient(cfg MeshNodeConfig) (*MeshNodeClient, error) {
+	client := newDefaultMeshNodeClient()
+
+	if cfg.Port != 0 {
+		client.port = cfg.Port
+	}
+
+	if cfg.Scheme != "" {
+		client.scheme = cfg.Scheme
+	}
+
+	switch client.scheme {
+	case "http":
+		if cfg.CAFile != "" || cfg.ServerName != "" {
+			return nil, errors.New("the mesh nodes CA and server name require the https scheme")
+		}
+	case "https":
+		tlsConfig, err := buildMeshNodeTLSConfig(cfg.CAFile, cfg.ServerName)
+		if err != nil {
+			return nil, err
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+
+		client.transport = transport
+	default:
+		return nil, fmt.Errorf("invalid mesh nodes scheme %q", client.scheme)
+	}
+
+	if cfg.Username != "" && cfg.Token != "" {
+		return nil, errors.New("the mesh nodes basic authentication and bearer token are mutually exclusive")
+	}
+
+	client.username = cfg.Username
+	client.password = cfg.Password
+	client.token = cfg.Token
+
+	return client, nil
+}
+
+// newDefaultMeshNodeClient returns a client of the mesh nodes API served with the defaults, without authentication.
+func newDefaultMeshNodeClient() *MeshNodeClient {
+	return &MeshNodeClient{
+		port:      DefaultMeshNodePort,
+		scheme:    DefaultMeshNodeScheme,
+		transport: http.DefaultTransport,
+	}
+}
+
+func buildMeshNodeTLSConfig(caFile, serverName string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+
+	ca, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the mesh nodes CA: %v", err)
+	}
+
+	tlsConfig.RootCAs = x509.NewCertPool()
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in the mesh nodes CA %q", caFile)
+	}
+
+	return tlsConfig, nil
+}
+
+// URL returns the URL of a path of the API of the mesh node with the given IP.
+func (m *MeshNodeClient) URL(ip, path string) string {
+	return fmt.Sprintf("%s://%s%s", m.scheme, net.JoinHostPort(ip, strconv.Itoa(m.port)), path)
+}
+
+// Do sends an authenticated request to a mesh node, failing after the given timeout.
+func (m *MeshNodeClient) Do(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	switch {
+	case m.username != "":
+		req.SetBasicAuth(m.username, m.password)
+	case m.token != "":
+		req.Header.Set("Authorization", "Bearer "+m.token)
+	}
+
+	client := &http.Client{
+		Transport: m.transport,
+		Timeout:   timeout,
+	}
+
+	return client.Do(req)
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeshNodeClientURL(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		config   MeshNodeConfig
+		ip       string
+		expected string
+	}{
+		{
+			desc:     "defaults",
+			ip:       "10.0.0.1",
+			expected: "http://10.0.0.1:8080/api/rawdata",
+		},
+		{
+			desc:     "scheme and port",
+			config:   MeshNodeConfig{Scheme: "https", Port: 8443},
+			ip:       "10.0.0.1",
+			expected: "https://10.0.0.1:8443/api/rawdata",
+		},
+		{
+			desc:     "IPv6",
+			ip:       "fd00::1",
+			expected: "http://[fd00::1]:8080/api/rawdata",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewMeshNodeClient(test.config)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, client.URL(test.ip, "/api/rawdata"))
+		})
+	}
+}
+
+func TestNewMeshNodeClientInvalidConfig(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config MeshNodeConfig
+	}{
+		{
+			desc:   "invalid scheme",
+			config: MeshNodeConfig{Scheme: "ftp"},
+		},
+		{
+			desc:   "CA without https",
+			config: MeshNodeConfig{CAFile: "ca.crt"},
+		},
+		{
+			desc:   "missing CA",
+			config: MeshNodeConfig{Scheme: "https", CAFile: "missing.crt"},
+		},
+		{
+			desc:   "basic authentication and bearer token",
+			config: MeshNodeConfig{Username: "maesh", Password: "secret", Token: "token"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewMeshNodeClient(test.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMeshNodeClientDo(t *testing.T) {
+	var authorization string
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "maesh")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, ca, 0600))
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	meshNodePort, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		desc          string
+		config        MeshNodeConfig
+		expected      string
+		expectedError bool
+	}{
+		{
+			desc:     "basic authentication",
+			config:   MeshNodeConfig{Username: "maesh", Password: "secret"},
+			expected: "Basic bWFlc2g6c2VjcmV0",
+		},
+		{
+			desc:     "bearer token",
+			config:   MeshNodeConfig{Token: "token"},
+			expected: "Bearer token",
+		},
+		{
+			desc:     "server name",
+			config:   MeshNodeConfig{ServerName: "example.com"},
+			expected: "",
+		},
+		{
+			desc:          "unknown server name",
+			config:        MeshNodeConfig{ServerName: "maesh-mesh-api"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test.config.Scheme = "https"
+		test.config.Port = meshNodePort
+		test.config.CAFile = caFile
+
+		client, err := NewMeshNodeClient(test.config)
+		require.NoError(t, err, test.desc)
+
+		req, err := http.NewRequest(http.MethodGet, client.URL(host, "/api/rawdata"), nil)
+		require.NoError(t, err, test.desc)
+
+		resp, err := client.Do(req, time.Second)
+		if test.expectedError {
+			assert.Error(t, err, test.desc)
+			continue
+		}
+
+		require.NoError(t, err, test.desc)
+		resp.Body.Close()
+
+		assert.Equal(t, test.expected, authorization, test.desc)
+	}
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containous/traefik/v2/pkg/config/runtime"
+)
+
+// meshNodeRawData is the runtime configuration reported by a mesh node on its /api/rawdata endpoint.
+type meshNodeRawData struct {
+	Routers     map[string]*runtime.RouterInfo     `json:"routers,omitempty"`
+	Middlewares map[string]*runtime.MiddlewareInfo `json:"middlewares,omitempty"`
+	Services    map[string]*runtime.ServiceInfo    `json:"services,omitempty"`
+	TCPRouters  map[string]*runtime.TCPRouterInfo  `json:"tcpRouters,omitempty"`
+	TCPServices map[string]*runtime.TCPServiceInfo `json:"tcpServices,omitempty"`
+}
+
+// getMeshNodeRawData returns the runtime configuration of a mesh node.
+func getMeshNodeRawData(ctx context.Context, meshNodes *MeshNodeClient, ip string) (*meshNodeRawData, error) {
+	req, err := http.NewRequest(http.MethodGet, meshNodes.URL(ip, "/api/rawdata"), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %v", err)
+	}
+
+	resp, err := meshNodes.Do(req.WithContext(ctx), 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get raw data: %v", err)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received non-ok response code: %d", resp.StatusCode)
+	}
+
+	return parseMeshNodeRawData(resp.Body)
+}
+
+// parseMeshNodeRawData decodes the runtime configuration of a mesh node.
+func parseMeshNodeRawData(r io.Reader) (*meshNodeRawData, error) {
+	var rawData meshNodeRawData
+
+	if err := json.NewDecoder(r).Decode(&rawData); err != nil {
+		return nil, fmt.Errorf("unable to decode raw data: %v", err)
+	}
+
+	return &rawData, nil
+}
+
+// ConfigVersion returns the version of the configuration applied on the mesh node.
+func (d *meshNodeRawData) ConfigVersion() string {
+	middleware, ok := d.Middlewares[configVersionMiddleware+"@"+configVersionProvider]
+	if !ok || middleware == nil {
+		return ""
+	}
+
+	return middlewareConfigVersion(middleware.Middleware)
+}
+
+// Errors returns the errors reported by the mesh node for the elements of the deployed configuration, sorted by element name.
+func (d *meshNodeRawData) Errors() []string {
+	var errs []string
+
+	addErrors := func(name string, elementErrs []string) {
+		if !strings.HasSuffix(name, "@"+configVersionProvider) {
+			return
+		}
+
+		for _, err := range elementErrs {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+		}
+	}
+
+	for name, info := range d.Routers {
+		addErrors(name, info.Err)
+	}
+
+	for name, info := range d.Middlewares {
+		addErrors(name, info.Err)
+	}
+
+	for name, info := range d.Services {
+		addErrors(name, info.Err)
+	}
+
+	for name, info := range d.TCPRouters {
+		addErrors(name, info.Err)
+	}
+
+	for name, info := range d.TCPServices {
+		addErrors(name, info.Err)
+	}
+
+	sort.Strings(errs)
+
+	return errs
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMeshNodeRawData(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		rawData         string
+		expectedVersion string
+		expectedErrors  []string
+		wantErr         bool
+	}{
+		{
+			desc:            "versioned configuration",
+			rawData:         `{"middlewares":{"maesh-config-version@rest":{"headers":{"customRequestHeaders":{"X-Maesh-Config-Version":"abcdef"}},"status":"enabled"}}}`,
+			expectedVersion: "abcdef",
+		},
+		{
+			desc:            "no configuration",
+			rawData:         `{"routers":{}}`,
+			expectedVersion: "",
+		},
+		{
+			desc: "configuration errors",
+			rawData: `{
+				"routers":{
+					"foo@rest":{"rule":"Host(","status":"disabled","error":["invalid rule"]},
+					"bar@rest":{"rule":"Host(` + "`bar`" + `)","status":"enabled"},
+					"api@internal":{"status":"disabled","error":["not from the controller"]}
+				},
+				"tcpServices":{"baz@rest":{"status":"disabled","error":["no servers"]}}
+			}`,
+			expectedErrors: []string{"baz@rest: no servers", "foo@rest: invalid rule"},
+		},
+		{
+			desc:    "invalid raw data",
+			rawData: `{`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := parseMeshNodeRawData(strings.NewReader(test.rawData))
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedVersion, actual.ConfigVersion())
+			assert.Equal(t, test.expectedErrors, actual.Errors())
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Rollout phases.
+const (
+	RolloutPhaseInProgress = "InProgress"
+	RolloutPhaseSucceeded  = "Succeeded"
+	RolloutPhaseFailed     = "Failed"
+	RolloutPhaseRolledBack = "RolledBack"
+)
+
+// RolloutConfig holds the strategy used to roll out a new configuration across the mesh nodes.
+type RolloutConfig struct {
+	// CanaryNodes is the number of mesh nodes receiving a new configuration first. No canary is used if 0.
+	CanaryNodes int
+	// BatchSize is the number of mesh nodes receiving the configuration at once after the canaries. All the remaining nodes if 0.
+	BatchSize int
+	// VerifyDelay is the time waited after deploying to a batch before verifying its nodes.
+	VerifyDelay time.Duration
+}
+
+// RolloutStatus holds the state of the last configuration rollout.
+type RolloutStatus struct {
+	Version         string
+	LastGoodVersion string
+	Phase           string
+	StartedAt       time.Time
+	FinishedAt      time.Time
+	TotalNodes      int
+	UpdatedNodes    int
+	Batch           int
+	TotalBatches    int
+	Error           string
+}
+
+// deployFunc deploys a configuration to the given mesh pods.
+type deployFunc func(ctx context.Context, pods []*corev1.Pod, config *dynamic.Configuration) error
+
+// verifyFunc verifies that the given mesh pods run properly with the deployed configuration.
+// wasReady holds the names of the pods which were ready before the deployment.
+type verifyFunc func(ctx context.Context, pods []*corev1.Pod, wasReady map[string]bool) error
+
+// Rollout rolls out configurations across the mesh nodes, and tracks the state of the last rollout.
+type Rollout struct {
+	config RolloutConfig
+
+	mu       sync.RWMutex
+	status   RolloutStatus
+	lastGood *dynamic.Configuration
+	deploy   deployFunc
+	verify   verifyFunc
+}
+
+// NewRollout returns an initialized Rollout.
+func NewRollout(config RolloutConfig, deploy deployFunc, verify verifyFunc) *Rollout {
+	return &Rollout{
+		config: config,
+		deploy: deploy,
+		verify: verify,
+	}
+}
+
+// Status returns the state of the last rollout.
+func (r *Rollout) Status() RolloutStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.status
+}
+
+// LastGoodConfiguration returns the last configuration successfully rolled out, nil if there is none.
+func (r *Rollout) LastGoodConfiguration() *dynamic.Configuration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lastGood
+}
+
+// Run rolls out the configuration to the given mesh pods, batch by batch.
+// The pods of each batch are verified before deploying to the next one.
+// If the verification fails, the pods already updated are rolled back to the last good configuration.
+func (r *Rollout) Run(ctx context.Context, pods []*corev1.Pod, config *dynamic.Configuration) error {
+	batches := splitRolloutBatches(pods, r.config.CanaryNodes, r.config.BatchSize)
+	lastGood := r.LastGoodConfiguration()
+
+	r.updateStatus(func(status *RolloutStatus) {
+		*status = RolloutStatus{
+			Version:         getConfigVersion(config),
+			LastGoodVersion: getConfigVersion(lastGood),
+			Phase:           RolloutPhaseInProgress,
+			StartedAt:       time.Now(),
+			TotalNodes:      len(pods),
+			TotalBatches:    len(batches),
+		}
+	})
+
+	var updated []*corev1.Pod
+
+	for i, batch := range batches {
+		r.updateStatus(func(status *RolloutStatus) {
+			status.Batch = i + 1
+		})
+
+		wasReady := make(map[string]bool)
+
+		for _, pod := range batch {
+			wasReady[pod.Name] = isPodReady(pod)
+		}
+
+		updated = append(updated, batch...)
+
+		if err := r.deploy(ctx, batch, config); err != nil {
+			// The configuration has not been proven bad, the nodes are left as is.
+			r.finish(RolloutPhaseFailed, err)
+			return err
+		}
+
+		if err := r.waitVerifyDelay(ctx); err != nil {
+			r.finish(RolloutPhaseFailed, err)
+			return err
+		}
+
+		if err := r.verify(ctx, batch, wasReady); err != nil {
+			return r.rollback(ctx, updated, lastGood, err)
+		}
+
+		r.updateStatus(func(status *RolloutStatus) {
+			status.UpdatedNodes += len(batch)
+		})
+	}
+
+	r.mu.Lock()
+	r.lastGood = config
+	r.mu.Unlock()
+
+	r.finish(RolloutPhaseSucceeded, nil)
+
+	return nil
+}
+
+// rollback deploys the last good configuration to the given pods, after a failed verification.
+func (r *Rollout) rollback(ctx context.Context, pods []*corev1.Pod, lastGood *dynamic.Configuration, verifyErr error) error {
+	err := fmt.Errorf("verification failed: %v", verifyErr)
+
+	if lastGood == nil {
+		r.finish(RolloutPhaseFailed, err)
+		return err
+	}
+
+	log.Warnf("Rolling back %d mesh nodes to configuration version %s: %v", len(pods), getConfigVersion(lastGood), verifyErr)
+
+	if deployErr := r.deploy(ctx, pods, lastGood); deployErr != nil {
+		err = fmt.Errorf("%v, and rollback failed: %v", err, deployErr)
+		r.finish(RolloutPhaseFailed, err)
+
+		return err
+	}
+
+	r.finish(RolloutPhaseRolledBack, err)
+
+	return err
+}
+
+// waitVerifyDelay waits for the verify delay, or until the context is done.
+func (r *Rollout) waitVerifyDelay(ctx context.Context) error {
+	if r.config.VerifyDelay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(r.config.VerifyDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Rollout) finish(phase string, err error) {
+	r.updateStatus(func(status *RolloutStatus) {
+		status.Phase = phase
+		status.FinishedAt = time.Now()
+
+		if err != nil {
+			status.Error = err.Error()
+		}
+	})
+}
+
+func (r *Rollout) updateStatus(update func(status *RolloutStatus)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	update(&r.status)
+}
+
+// splitRolloutBatches splits the pods, sorted by name, into the canary batch followed by batches of the given size.
+func splitRolloutBatches(pods []*corev1.Pod, canaryNodes, batchSize int) [][]*corev1.Pod {
+	sorted := make([]*corev1.Pod, len(pods))
+	copy(sorted, pods)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var batches [][]*corev1.Pod
+
+	if canaryNodes > 0 && canaryNodes < len(sorted) {
+		batches = append(batches, sorted[:canaryNodes])
+		sorted = sorted[canaryNodes:]
+	}
+
+	if batchSize <= 0 {
+		batchSize = len(sorted)
+	}
+
+	for len(sorted) > 0 {
+		size := batchSize
+		if size > len(sorted) {
+			size = len(sorted)
+		}
+
+		batches = append(batches, sorted[:size])
+		sorted = sorted[size:]
+	}
+
+	return batches
+}
+
+// isPodReady checks if all the containers of the pod are ready.
+func isPodReady(pod *corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if !status.Ready {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSplitRolloutBatches(t *testing.T) {
+	pods := []*corev1.Pod{buildMeshPod("e"), buildMeshPod("d"), buildMeshPod("c"), buildMeshPod("b"), buildMeshPod("a")}
+
+	testCases := []struct {
+		desc        string
+		canaryNodes int
+		batchSize   int
+		expected    [][]string
+	}{
+		{
+			desc:     "no canary and no batch size",
+			expected: [][]string{{"a", "b", "c", "d", "e"}},
+		},
+		{
+			desc:        "canary and remaining nodes",
+			canaryNodes: 1,
+			expected:    [][]string{{"a"}, {"b", "c", "d", "e"}},
+		},
+		{
+			desc:        "canary and batches",
+			canaryNodes: 1,
+			batchSize:   3,
+			expected:    [][]string{{"a"}, {"b", "c", "d"}, {"e"}},
+		},
+		{
+			desc:        "canary covering all nodes",
+			canaryNodes: 10,
+			expected:    [][]string{{"a", "b", "c", "d", "e"}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			batches := splitRolloutBatches(pods, test.canaryNodes, test.batchSize)
+
+			var actual [][]string
+
+			for _, batch := range batches {
+				var names []string
+				for _, pod := range batch {
+					names = append(names, pod.Name)
+				}
+
+				actual = append(actual, names)
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestRolloutRun(t *testing.T) {
+	pods := []*corev1.Pod{buildMeshPod("a"), buildMeshPod("b"), buildMeshPod("c")}
+
+	good := buildVersionedConfig(t, "good")
+	bad := buildVersionedConfig(t, "bad")
+
+	testCases := []struct {
+		desc             string
+		lastGood         *dynamic.Configuration
+		deployErr        error
+		verifyFailsOn    string
+		expectedPhase    string
+		expectedDeploys  []string
+		expectedLastGood *dynamic.Configuration
+		expectedUpdated  int
+	}{
+		{
+			desc:             "successful rollout",
+			lastGood:         good,
+			expectedPhase:    RolloutPhaseSucceeded,
+			expectedDeploys:  []string{"bad:a", "bad:b", "bad:c"},
+			expectedLastGood: bad,
+			expectedUpdated:  3,
+		},
+		{
+			desc:             "canary verification fails",
+			lastGood:         good,
+			verifyFailsOn:    "a",
+			expectedPhase:    RolloutPhaseRolledBack,
+			expectedDeploys:  []string{"bad:a", "good:a"},
+			expectedLastGood: good,
+		},
+		{
+			desc:             "batch verification fails",
+			lastGood:         good,
+			verifyFailsOn:    "c",
+			expectedPhase:    RolloutPhaseRolledBack,
+			expectedDeploys:  []string{"bad:a", "bad:b", "bad:c", "good:a", "good:b", "good:c"},
+			expectedLastGood: good,
+			expectedUpdated:  1,
+		},
+		{
+			desc:            "verification fails without good configuration",
+			verifyFailsOn:   "a",
+			expectedPhase:   RolloutPhaseFailed,
+			expectedDeploys: []string{"bad:a"},
+		},
+		{
+			desc:             "deploy fails",
+			lastGood:         good,
+			deployErr:        errors.New("boom"),
+			expectedPhase:    RolloutPhaseFailed,
+			expectedDeploys:  []string{"bad:a"},
+			expectedLastGood: good,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var deploys []string
+
+			deploy := func(_ context.Context, pods []*corev1.Pod, config *dynamic.Configuration) error {
+				for _, pod := range pods {
+					deploys = append(deploys, getConfigVersion(config)+":"+pod.Name)
+				}
+
+				return test.deployErr
+			}
+
+			verify := func(_ context.Context, pods []*corev1.Pod, wasReady map[string]bool) error {
+				for _, pod := range pods {
+					if pod.Name == test.verifyFailsOn {
+						return errors.New("router errors")
+					}
+				}
+
+				return nil
+			}
+
+			rollout := NewRollout(RolloutConfig{CanaryNodes: 1, BatchSize: 2}, deploy, verify)
+			rollout.lastGood = test.lastGood
+
+			err := rollout.Run(context.Background(), pods, bad)
+			if test.expectedPhase == RolloutPhaseSucceeded {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+
+			status := rollout.Status()
+			assert.Equal(t, test.expectedPhase, status.Phase)
+			assert.Equal(t, "bad", status.Version)
+			assert.Equal(t, 3, status.TotalNodes)
+			assert.Equal(t, 2, status.TotalBatches)
+			assert.Equal(t, test.expectedUpdated, status.UpdatedNodes)
+			assert.Equal(t, test.expectedDeploys, deploys)
+			assert.True(t, test.expectedLastGood == rollout.LastGoodConfiguration())
+		})
+	}
+}
+
+func buildMeshPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "maesh",
+		},
+	}
+}
+
+func buildVersionedConfig(t *testing.T, version string) *dynamic.Configuration {
+	t.Helper()
+
+	config, err := withConfigVersion(&dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{}})
+	require.NoError(t, err)
+
+	config.HTTP.Middlewares[configVersionMiddleware].Headers.CustomRequestHeaders[configVersionHeader] = version
+
+	return config
+}
@@ -0,0 +1,252 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/providers/base"
+	"github.com/containous/maesh/internal/providers/smi"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ServiceView is the mesh configuration of a user service.
+type ServiceView struct {
+	Namespace string
+	Name      string
+	// Ignored is true if the service is not part of the mesh.
+	Ignored bool
+	Mode    string
+	Scheme  string
+	// MeshService is the name of the mesh service, in the maesh namespace.
+	MeshService   string
+	MeshServiceIP string `json:",omitempty"`
+	Ports         []ServiceViewPort
+	Endpoints     []corev1.EndpointSubset
+
+	// The elements of the current configuration generated for the service.
+	Routers     map[string]*dynamic.Router     `json:",omitempty"`
+	Services    map[string]*dynamic.Service    `json:",omitempty"`
+	Middlewares map[string]*dynamic.Middleware `json:",omitempty"`
+	TCPRouters  map[string]*dynamic.TCPRouter  `json:",omitempty"`
+	TCPServices map[string]*dynamic.TCPService `json:",omitempty"`
+
+	// The SMI resources applicable to the service, as "namespace/name".
+	TrafficTargets  []string `json:",omitempty"`
+	HTTPRouteGroups []string `json:",omitempty"`
+	TCPRoutes       []string `json:",omitempty"`
+	TrafficSplits   []string `json:",omitempty"`
+}
+
+// ServiceViewPort is a port of a user service, and the mesh port allocated to it.
+type ServiceViewPort struct {
+	Name     string `json:",omitempty"`
+	Port     int32
+	MeshPort int32
+}
+
+// ViewService returns the mesh configuration of a user service, or nil if the service does not exist.
+func (i *inspector) ViewService(namespace, name string, config *dynamic.Configuration) (*ServiceView, error) {
+	service, err := i.serviceLister.Services(namespace).Get(name)
+	if err != nil {
+		if kubeerror.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("unable to get service: %v", err)
+	}
+
+	view := &ServiceView{
+		Namespace:   service.Namespace,
+		Name:        service.Name,
+		Ignored:     i.ignored.IsIgnored(service.ObjectMeta),
+		Mode:        base.GetServiceMode(service.Annotations, i.defaultMode),
+		Scheme:      base.GetScheme(service.Annotations),
+		MeshService: k8s.MeshServiceName(i.meshNamespace, service.Name, service.Namespace),
+		Ports:       []ServiceViewPort{},
+		Endpoints:   []corev1.EndpointSubset{},
+	}
+
+	if err := i.addMeshServicePorts(view); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := base.GetEndpoints(i.endpointsLister, service.Name, service.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get endpoints: %v", err)
+	}
+
+	if endpoints != nil {
+		view.Endpoints = endpoints.Subsets
+	}
+
+	addConfigurationElements(view, config)
+
+	if i.smiEnabled {
+		if err := i.addSMIResources(view, endpoints); err != nil {
+			return nil, err
+		}
+	}
+
+	return view, nil
+}
+
+// addMeshServicePorts adds the service ports, with the mesh ports targeted by the mesh service.
+func (i *inspector) addMeshServicePorts(view *ServiceView) error {
+	meshService, err := i.serviceLister.Services(i.meshNamespace).Get(view.MeshService)
+	if err != nil {
+		if kubeerror.IsNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("unable to get mesh service: %v", err)
+	}
+
+	view.MeshServiceIP = meshService.Spec.ClusterIP
+
+	for _, sp := range meshService.Spec.Ports {
+		view.Ports = append(view.Ports, ServiceViewPort{
+			Name:     sp.Name,
+			Port:     sp.Port,
+			MeshPort: sp.TargetPort.IntVal,
+		})
+	}
+
+	return nil
+}
+
+// addConfigurationElements adds the routers of the service, with their services and middlewares.
+// HTTP routers match the service host, and TCP routers listen on the mesh ports, which are dedicated to the service.
+func addConfigurationElements(view *ServiceView, config *dynamic.Configuration) {
+	if config == nil {
+		return
+	}
+
+	if config.HTTP != nil && view.Mode == k8s.ServiceTypeHTTP {
+		host := fmt.Sprintf("Host(`%s.%s.maesh`)", view.Name, view.Namespace)
+
+		view.Routers = make(map[string]*dynamic.Router)
+		view.Services = make(map[string]*dynamic.Service)
+		view.Middlewares = make(map[string]*dynamic.Middleware)
+
+		for name, router := range config.HTTP.Routers {
+			if !strings.Contains(router.Rule, host) {
+				continue
+			}
+
+			view.Routers[name] = router
+
+			for _, middleware := range router.Middlewares {
+				if m, ok := config.HTTP.Middlewares[middleware]; ok {
+					view.Middlewares[middleware] = m
+				}
+			}
+
+			addHTTPService(view, config, router.Service)
+		}
+	}
+
+	if config.TCP != nil && view.Mode == k8s.ServiceTypeTCP {
+		view.TCPRouters = make(map[string]*dynamic.TCPRouter)
+		view.TCPServices = make(map[string]*dynamic.TCPService)
+
+		for _, port := range view.Ports {
+			entryPoint := fmt.Sprintf("tcp-%d", port.MeshPort)
+
+			for name, router := range config.TCP.Routers {
+				if !containsString(router.EntryPoints, entryPoint) {
+					continue
+				}
+
+				view.TCPRouters[name] = router
+
+				if service, ok := config.TCP.Services[router.Service]; ok {
+					view.TCPServices[router.Service] = service
+				}
+			}
+		}
+	}
+}
+
+// addHTTPService adds the service, and the services it is weighting for traffic splits.
+func addHTTPService(view *ServiceView, config *dynamic.Configuration, name string) {
+	service, ok := config.HTTP.Services[name]
+	if !ok {
+		return
+	}
+
+	if _, exists := view.Services[name]; exists {
+		return
+	}
+
+	view.Services[name] = service
+
+	if service.Weighted == nil {
+		return
+	}
+
+	for _, wrrService := range service.Weighted.Services {
+		addHTTPService(view, config, wrrService.Name)
+	}
+}
+
+// addSMIResources adds the TrafficTargets with a destination backing the service, their routes, and the TrafficSplits of the service.
+func (i *inspector) addSMIResources(view *ServiceView, endpoints *corev1.Endpoints) error {
+	trafficTargets, err := i.trafficTargetLister.TrafficTargets(view.Namespace).List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("unable to get traffictargets: %v", err)
+	}
+
+	for _, trafficTarget := range trafficTargets {
+		if !smi.IsApplicableTrafficTarget(i.podLister, endpoints, trafficTarget) {
+			continue
+		}
+
+		view.TrafficTargets = append(view.TrafficTargets, trafficTarget.Namespace+"/"+trafficTarget.Name)
+
+		for _, spec := range trafficTarget.Specs {
+			switch spec.Kind {
+			case "HTTPRouteGroup":
+				view.HTTPRouteGroups = appendUnique(view.HTTPRouteGroups, trafficTarget.Namespace+"/"+spec.Name)
+			case "TCPRoute":
+				view.TCPRoutes = appendUnique(view.TCPRoutes, trafficTarget.Namespace+"/"+spec.Name)
+			}
+		}
+	}
+
+	trafficSplits, err := i.trafficSplitLister.TrafficSplits(view.Namespace).List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("unable to get trafficsplits: %v", err)
+	}
+
+	for _, trafficSplit := range trafficSplits {
+		split := trafficSplit.Spec.Service == view.Name
+
+		for _, backend := range trafficSplit.Spec.Backends {
+			split = split || backend.Service == view.Name
+		}
+
+		if split {
+			view.TrafficSplits = append(view.TrafficSplits, trafficSplit.Namespace+"/"+trafficSplit.Name)
+		}
+	}
+
+	sort.Strings(view.TrafficTargets)
+	sort.Strings(view.HTTPRouteGroups)
+	sort.Strings(view.TCPRoutes)
+	sort.Strings(view.TrafficSplits)
+
+	return nil
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsString(values, value) {
+		return values
+	}
+
+	return append(values, value)
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestInspectorViewService(t *testing.T) {
+	meshServiceName := k8s.MeshServiceName("maesh", "api", "ns")
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	clientMock := k8s.NewClientMock(stopCh, "service_view.yaml", true)
+
+	inspector := &inspector{
+		smiEnabled:           true,
+		defaultMode:          k8s.ServiceTypeHTTP,
+		meshNamespace:        "maesh",
+		ignored:              k8s.NewIgnored(),
+		serviceLister:        clientMock.ServiceLister,
+		endpointsLister:      clientMock.EndpointsLister,
+		podLister:            clientMock.PodLister,
+		trafficTargetLister:  clientMock.TrafficTargetLister,
+		httpRouteGroupLister: clientMock.HTTPRouteGroupLister,
+		tcpRouteLister:       clientMock.TCPRouteLister,
+		trafficSplitLister:   clientMock.TrafficSplitLister,
+	}
+
+	router := &dynamic.Router{
+		Rule:        "Host(`api.ns.maesh`) || Host(`10.0.0.1`)",
+		EntryPoints: []string{"http-5000"},
+		Middlewares: []string{"api-whitelist"},
+		Service:     "api-split",
+	}
+	whitelist := &dynamic.Middleware{IPWhiteList: &dynamic.IPWhiteList{SourceRange: []string{"10.1.0.2"}}}
+	splitService := &dynamic.Service{Weighted: &dynamic.WeightedRoundRobin{Services: []dynamic.WRRService{{Name: "api-v1"}}}}
+	backendService := &dynamic.Service{LoadBalancer: &dynamic.ServersLoadBalancer{}}
+
+	config := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers: map[string]*dynamic.Router{
+				"api":   router,
+				"other": {Rule: "Host(`other.ns.maesh`)", Service: "other"},
+			},
+			Services: map[string]*dynamic.Service{
+				"api-split": splitService,
+				"api-v1":    backendService,
+				"other":     {LoadBalancer: &dynamic.ServersLoadBalancer{}},
+			},
+			Middlewares: map[string]*dynamic.Middleware{
+				"api-whitelist": whitelist,
+			},
+		},
+	}
+
+	view, err := inspector.ViewService("ns", "api", config)
+	require.NoError(t, err)
+
+	expected := &ServiceView{
+		Namespace:     "ns",
+		Name:          "api",
+		Mode:          k8s.ServiceTypeHTTP,
+		Scheme:        "http",
+		MeshService:   meshServiceName,
+		MeshServiceIP: "10.0.0.1",
+		Ports:         []ServiceViewPort{{Name: "web", Port: 80, MeshPort: 5000}},
+		Endpoints: []corev1.EndpointSubset{
+			{
+				Addresses: []corev1.EndpointAddress{
+					{IP: "10.1.0.1", TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: "api-pod", Namespace: "ns"}},
+				},
+				Ports: []corev1.EndpointPort{{Port: 8080}},
+			},
+		},
+		Routers:         map[string]*dynamic.Router{"api": router},
+		Services:        map[string]*dynamic.Service{"api-split": splitService, "api-v1": backendService},
+		Middlewares:     map[string]*dynamic.Middleware{"api-whitelist": whitelist},
+		TrafficTargets:  []string{"ns/api-target"},
+		HTTPRouteGroups: []string{"ns/api-routes"},
+		TrafficSplits:   []string{"ns/api-split"},
+	}
+
+	assert.Equal(t, expected, view)
+
+	view, err = inspector.ViewService("ns", "unknown", config)
+	require.NoError(t, err)
+	assert.Nil(t, view)
+}
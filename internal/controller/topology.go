@@ -0,0 +1,418 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/providers/base"
+	"github.com/containous/maesh/internal/providers/smi"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	access "github.com/deislabs/smi-sdk-go/pkg/apis/access/v1alpha1"
+	accessLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/access/listers/access/v1alpha1"
+	splitLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/listers/split/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers/core/v1"
+)
+
+// Topology node kinds.
+const (
+	TopologyKindService        = "Service"
+	TopologyKindServiceAccount = "ServiceAccount"
+)
+
+// Topology edge kinds.
+const (
+	TopologyEdgeTrafficTarget = "TrafficTarget"
+	TopologyEdgeTrafficSplit  = "TrafficSplit"
+)
+
+// Topology is a graph of the mesh services, and of the traffic allowed or split between them.
+type Topology struct {
+	Nodes []TopologyNode
+	Edges []TopologyEdge
+}
+
+// TopologyNode is a mesh service, or a service account allowed to reach mesh services.
+type TopologyNode struct {
+	// ID is the kind, namespace and name of the node, like "Service/default/whoami".
+	ID        string
+	Kind      string
+	Namespace string
+	Name      string
+	Mode      string         `json:",omitempty"`
+	Ports     []TopologyPort `json:",omitempty"`
+}
+
+// TopologyPort is a port of a mesh service.
+type TopologyPort struct {
+	Port     int32
+	MeshPort int
+	// Routers is the number of routers of the configuration serving the port.
+	Routers int
+}
+
+// TopologyEdge is the traffic allowed by a TrafficTarget, or split by a TrafficSplit, between two nodes.
+type TopologyEdge struct {
+	From string
+	To   string
+	Kind string
+	// Resource is the namespace and name of the SMI resource the edge is derived from.
+	Resource string
+	Weight   *int `json:",omitempty"`
+}
+
+// topologyCache holds the latest built configuration, and the topology built from it on demand.
+// The topology is cached by configuration version, so it is built at most once per configuration.
+type topologyCache struct {
+	mu            sync.Mutex
+	version       string
+	config        *dynamic.Configuration
+	tcpStateTable *k8s.State
+	builtVersion  string
+	topology      *Topology
+}
+
+// Update sets the configuration to build the topology from.
+// The TCP state table is copied, as the controller keeps assigning ports while the topology is built.
+func (c *topologyCache) Update(version string, config *dynamic.Configuration, tcpStateTable *k8s.State) {
+	table := make(map[int]*k8s.ServiceWithPort, len(tcpStateTable.Table))
+	for port, service := range tcpStateTable.Table {
+		table[port] = service
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version = version
+	c.config = config
+	c.tcpStateTable = &k8s.State{Table: table}
+}
+
+// Get returns the topology of the latest configuration, building it with the given builder if it is not cached yet.
+// It returns nil if no configuration has been built yet.
+func (c *topologyCache) Get(builder *topologyBuilder) (*Topology, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config == nil {
+		return nil, nil
+	}
+
+	if c.topology != nil && c.builtVersion == c.version {
+		return c.topology, nil
+	}
+
+	builder.tcpStateTable = c.tcpStateTable
+
+	topology, err := builder.Build(c.config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.builtVersion = c.version
+	c.topology = topology
+
+	return topology, nil
+}
+
+// topologyBuilder builds the topology of the mesh from the listers and the built configuration.
+// The SMI listers are nil when SMI is disabled, and the topology has no edges.
+type topologyBuilder struct {
+	defaultMode         string
+	ignored             k8s.IgnoreWrapper
+	tcpStateTable       *k8s.State
+	serviceLister       listers.ServiceLister
+	endpointsLister     listers.EndpointsLister
+	podLister           listers.PodLister
+	trafficTargetLister accessLister.TrafficTargetLister
+	trafficSplitLister  splitLister.TrafficSplitLister
+}
+
+// Build returns the topology of the mesh.
+func (b *topologyBuilder) Build(config *dynamic.Configuration) (*Topology, error) {
+	services, err := b.serviceLister.Services(metav1.NamespaceAll).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get services: %v", err)
+	}
+
+	topology := &Topology{
+		Nodes: []TopologyNode{},
+		Edges: []TopologyEdge{},
+	}
+
+	meshServices := make(map[string]*corev1.Service)
+
+	for _, service := range services {
+		if b.ignored.IsIgnored(service.ObjectMeta) {
+			continue
+		}
+
+		meshServices[service.Namespace+"/"+service.Name] = service
+		topology.Nodes = append(topology.Nodes, b.buildServiceNode(config, service))
+	}
+
+	if b.trafficTargetLister != nil {
+		if err := b.addTrafficTargetEdges(topology, meshServices); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.trafficSplitLister != nil {
+		if err := b.addTrafficSplitEdges(topology, meshServices); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(topology.Nodes, func(i, j int) bool {
+		return topology.Nodes[i].ID < topology.Nodes[j].ID
+	})
+
+	sort.Slice(topology.Edges, func(i, j int) bool {
+		if topology.Edges[i].From != topology.Edges[j].From {
+			return topology.Edges[i].From < topology.Edges[j].From
+		}
+
+		if topology.Edges[i].To != topology.Edges[j].To {
+			return topology.Edges[i].To < topology.Edges[j].To
+		}
+
+		return topology.Edges[i].Resource < topology.Edges[j].Resource
+	})
+
+	return topology, nil
+}
+
+// buildServiceNode returns the node of a mesh service, with its mesh ports as assigned by the providers.
+func (b *topologyBuilder) buildServiceNode(config *dynamic.Configuration, service *corev1.Service) TopologyNode {
+	node := TopologyNode{
+		ID:        topologyNodeID(TopologyKindService, service.Namespace, service.Name),
+		Kind:      TopologyKindService,
+		Namespace: service.Namespace,
+		Name:      service.Name,
+		Mode:      base.GetServiceMode(service.Annotations, b.defaultMode),
+	}
+
+	for id, sp := range service.Spec.Ports {
+		port := TopologyPort{Port: sp.Port}
+
+		if node.Mode == k8s.ServiceTypeTCP {
+			port.MeshPort = b.getMeshPort(service.Name, service.Namespace, sp.Port)
+			port.Routers = countTCPRouters(config, port.MeshPort)
+		} else {
+			port.MeshPort = base.GetHTTPMeshPort(id)
+			port.Routers = countHTTPRouters(config, service, port.MeshPort)
+		}
+
+		node.Ports = append(node.Ports, port)
+	}
+
+	return node
+}
+
+// addTrafficTargetEdges adds the edges from the TrafficTarget sources to the services backed by their destination.
+func (b *topologyBuilder) addTrafficTargetEdges(topology *Topology, meshServices map[string]*corev1.Service) error {
+	trafficTargets, err := b.trafficTargetLister.TrafficTargets(metav1.NamespaceAll).List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("unable to get traffictargets: %v", err)
+	}
+
+	sources := make(map[string]bool)
+
+	for _, trafficTarget := range trafficTargets {
+		destinations := b.getDestinationServices(trafficTarget, meshServices)
+		resource := trafficTarget.Namespace + "/" + trafficTarget.Name
+
+		for _, source := range trafficTarget.Sources {
+			sourceID := topologyNodeID(TopologyKindServiceAccount, source.Namespace, source.Name)
+
+			if !sources[sourceID] {
+				sources[sourceID] = true
+
+				topology.Nodes = append(topology.Nodes, TopologyNode{
+					ID:        sourceID,
+					Kind:      TopologyKindServiceAccount,
+					Namespace: source.Namespace,
+					Name:      source.Name,
+				})
+			}
+
+			for _, destination := range destinations {
+				topology.Edges = append(topology.Edges, TopologyEdge{
+					From:     sourceID,
+					To:       topologyNodeID(TopologyKindService, destination.Namespace, destination.Name),
+					Kind:     TopologyEdgeTrafficTarget,
+					Resource: resource,
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+// getDestinationServices returns the mesh services with endpoints running as the TrafficTarget destination service account.
+func (b *topologyBuilder) getDestinationServices(trafficTarget *access.TrafficTarget, meshServices map[string]*corev1.Service) []*corev1.Service {
+	var result []*corev1.Service
+
+	for _, service := range meshServices {
+		if service.Namespace != trafficTarget.Destination.Namespace {
+			continue
+		}
+
+		endpoints, err := base.GetEndpoints(b.endpointsLister, service.Name, service.Namespace)
+		if err != nil {
+			continue
+		}
+
+		if smi.IsApplicableTrafficTarget(b.podLister, endpoints, trafficTarget) {
+			result = append(result, service)
+		}
+	}
+
+	return result
+}
+
+// addTrafficSplitEdges adds the weighted edges from the TrafficSplit services to their backends.
+func (b *topologyBuilder) addTrafficSplitEdges(topology *Topology, meshServices map[string]*corev1.Service) error {
+	trafficSplits, err := b.trafficSplitLister.TrafficSplits(metav1.NamespaceAll).List(labels.Everything())
+	if err != nil {
+		return fmt.Errorf("unable to get trafficsplits: %v", err)
+	}
+
+	for _, trafficSplit := range trafficSplits {
+		if _, ok := meshServices[trafficSplit.Namespace+"/"+trafficSplit.Spec.Service]; !ok {
+			continue
+		}
+
+		for _, backend := range trafficSplit.Spec.Backends {
+			weight := backend.Weight
+
+			topology.Edges = append(topology.Edges, TopologyEdge{
+				From:     topologyNodeID(TopologyKindService, trafficSplit.Namespace, trafficSplit.Spec.Service),
+				To:       topologyNodeID(TopologyKindService, trafficSplit.Namespace, backend.Service),
+				Kind:     TopologyEdgeTrafficSplit,
+				Resource: trafficSplit.Namespace + "/" + trafficSplit.Name,
+				Weight:   &weight,
+			})
+		}
+	}
+
+	return nil
+}
+
+func (b *topologyBuilder) getMeshPort(serviceName, serviceNamespace string, servicePort int32) int {
+	if b.tcpStateTable == nil {
+		return 0
+	}
+
+	for port, v := range b.tcpStateTable.Table {
+		if v.Name == serviceName && v.Namespace == serviceNamespace && v.Port == servicePort {
+			return port
+		}
+	}
+
+	return 0
+}
+
+// countHTTPRouters returns the number of HTTP routers matching the service on the mesh port.
+func countHTTPRouters(config *dynamic.Configuration, service *corev1.Service, meshPort int) int {
+	if config == nil || config.HTTP == nil {
+		return 0
+	}
+
+	entryPoint := fmt.Sprintf("http-%d", meshPort)
+	host := fmt.Sprintf("Host(`%s.%s.maesh`)", service.Name, service.Namespace)
+
+	var count int
+
+	for _, router := range config.HTTP.Routers {
+		if strings.Contains(router.Rule, host) && containsString(router.EntryPoints, entryPoint) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// countTCPRouters returns the number of TCP routers on the mesh port, which is dedicated to a single service port.
+func countTCPRouters(config *dynamic.Configuration, meshPort int) int {
+	if config == nil || config.TCP == nil || meshPort == 0 {
+		return 0
+	}
+
+	entryPoint := fmt.Sprintf("tcp-%d", meshPort)
+
+	var count int
+
+	for _, router := range config.TCP.Routers {
+		if containsString(router.EntryPoints, entryPoint) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func topologyNodeID(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// WriteDOT writes the topology in the DOT language of Graphviz.
+// Services are drawn as boxes and service accounts as ellipses, TrafficSplit edges are dashed.
+func (t *Topology) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+
+	sb.WriteString("digraph maesh {\n")
+
+	for _, node := range t.Nodes {
+		label := node.Namespace + "/" + node.Name
+		shape := "ellipse"
+
+		if node.Kind == TopologyKindService {
+			shape = "box"
+
+			for _, port := range node.Ports {
+				label += fmt.Sprintf("\n%s %d -> %d", node.Mode, port.Port, port.MeshPort)
+			}
+		}
+
+		fmt.Fprintf(&sb, "  %q [label=%q, shape=%s];\n", node.ID, label, shape)
+	}
+
+	for _, edge := range t.Edges {
+		label := edge.Resource
+		style := "solid"
+
+		if edge.Kind == TopologyEdgeTrafficSplit {
+			style = "dashed"
+
+			if edge.Weight != nil {
+				label += fmt.Sprintf(" (%d)", *edge.Weight)
+			}
+		}
+
+		fmt.Fprintf(&sb, "  %q -> %q [label=%q, style=%s];\n", edge.From, edge.To, label, style)
+	}
+
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
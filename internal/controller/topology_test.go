@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologyBuilderBuild(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	builder := buildTestTopologyBuilder(stopCh)
+
+	config := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers: map[string]*dynamic.Router{
+				"api": {
+					Rule:        "Host(`api.ns.maesh`) || Host(`10.0.0.1`)",
+					EntryPoints: []string{"http-5000"},
+				},
+			},
+		},
+		TCP: &dynamic.TCPConfiguration{
+			Routers: map[string]*dynamic.TCPRouter{
+				"db": {
+					Rule:        "HostSNI(`*`)",
+					EntryPoints: []string{"tcp-10000"},
+				},
+			},
+		},
+	}
+
+	topology, err := builder.Build(config)
+	require.NoError(t, err)
+
+	eighty := 80
+	twenty := 20
+
+	expected := &Topology{
+		Nodes: []TopologyNode{
+			{ID: "Service/ns/api", Kind: "Service", Namespace: "ns", Name: "api", Mode: "http", Ports: []TopologyPort{{Port: 80, MeshPort: 5000, Routers: 1}}},
+			{ID: "Service/ns/api-v1", Kind: "Service", Namespace: "ns", Name: "api-v1", Mode: "http", Ports: []TopologyPort{{Port: 80, MeshPort: 5000}}},
+			{ID: "Service/ns/api-v2", Kind: "Service", Namespace: "ns", Name: "api-v2", Mode: "http", Ports: []TopologyPort{{Port: 80, MeshPort: 5000}}},
+			{ID: "Service/ns/db", Kind: "Service", Namespace: "ns", Name: "db", Mode: "tcp", Ports: []TopologyPort{{Port: 5432, MeshPort: 10000, Routers: 1}}},
+			{ID: "ServiceAccount/ns/client", Kind: "ServiceAccount", Namespace: "ns", Name: "client"},
+		},
+		Edges: []TopologyEdge{
+			{From: "Service/ns/api", To: "Service/ns/api-v1", Kind: "TrafficSplit", Resource: "ns/api-split", Weight: &eighty},
+			{From: "Service/ns/api", To: "Service/ns/api-v2", Kind: "TrafficSplit", Resource: "ns/api-split", Weight: &twenty},
+			{From: "ServiceAccount/ns/client", To: "Service/ns/api-v1", Kind: "TrafficTarget", Resource: "ns/api-target"},
+		},
+	}
+
+	assert.Equal(t, expected, topology)
+}
+
+func TestTopologyCache(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	builder := buildTestTopologyBuilder(stopCh)
+	tcpStateTable := builder.tcpStateTable
+
+	var cache topologyCache
+
+	topology, err := cache.Get(builder)
+	require.NoError(t, err)
+	assert.Nil(t, topology)
+
+	cache.Update("1", &dynamic.Configuration{}, tcpStateTable)
+
+	first, err := cache.Get(builder)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+	assert.Equal(t, 10000, first.Nodes[3].Ports[0].MeshPort)
+
+	// The ports assigned after the update do not change the topology being served.
+	tcpStateTable.Table[10001] = &k8s.ServiceWithPort{Name: "other", Namespace: "ns", Port: 80}
+
+	topology, err = cache.Get(builder)
+	require.NoError(t, err)
+	assert.True(t, first == topology)
+
+	cache.Update("2", &dynamic.Configuration{}, tcpStateTable)
+
+	topology, err = cache.Get(builder)
+	require.NoError(t, err)
+	assert.True(t, first != topology)
+	assert.Equal(t, first, topology)
+}
+
+func TestTopologyWriteDOT(t *testing.T) {
+	weight := 100
+
+	topology := &Topology{
+		Nodes: []TopologyNode{
+			{ID: "Service/ns/api", Kind: "Service", Namespace: "ns", Name: "api", Mode: "http", Ports: []TopologyPort{{Port: 80, MeshPort: 5000}}},
+			{ID: "Service/ns/api-v1", Kind: "Service", Namespace: "ns", Name: "api-v1", Mode: "http"},
+			{ID: "ServiceAccount/ns/client", Kind: "ServiceAccount", Namespace: "ns", Name: "client"},
+		},
+		Edges: []TopologyEdge{
+			{From: "Service/ns/api", To: "Service/ns/api-v1", Kind: "TrafficSplit", Resource: "ns/api-split", Weight: &weight},
+			{From: "ServiceAccount/ns/client", To: "Service/ns/api", Kind: "TrafficTarget", Resource: "ns/api-target"},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, topology.WriteDOT(&buf))
+
+	expected := `digraph maesh {
+  "Service/ns/api" [label="ns/api\nhttp 80 -> 5000", shape=box];
+  "Service/ns/api-v1" [label="ns/api-v1", shape=box];
+  "ServiceAccount/ns/client" [label="ns/client", shape=ellipse];
+  "Service/ns/api" -> "Service/ns/api-v1" [label="ns/api-split (100)", style=dashed];
+  "ServiceAccount/ns/client" -> "Service/ns/api" [label="ns/api-target", style=solid];
+}
+`
+
+	assert.Equal(t, expected, buf.String())
+}
+
+// buildTestTopologyBuilder builds a topology builder on the objects of the topology fixture.
+func buildTestTopologyBuilder(stopCh <-chan struct{}) *topologyBuilder {
+	clientMock := k8s.NewClientMock(stopCh, "topology.yaml", true)
+
+	return &topologyBuilder{
+		defaultMode: k8s.ServiceTypeHTTP,
+		ignored:     k8s.NewIgnored(),
+		tcpStateTable: &k8s.State{Table: map[int]*k8s.ServiceWithPort{
+			10000: {Name: "db", Namespace: "ns", Port: 5432},
+		}},
+		serviceLister:       clientMock.ServiceLister,
+		endpointsLister:     clientMock.EndpointsLister,
+		podLister:           clientMock.PodLister,
+		trafficTargetLister: clientMock.TrafficTargetLister,
+		trafficSplitLister:  clientMock.TrafficSplitLister,
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+)
+
+const (
+	// configVersionMiddleware is the name of the middleware carrying the configuration version.
+	// It is not used by any router, and is only there to be reported back by the mesh nodes in their raw data.
+	configVersionMiddleware = "maesh-config-version"
+	// configVersionHeader is the header holding the configuration version in the version middleware.
+	configVersionHeader = "X-Maesh-Config-Version"
+	// configVersionProvider is the name of the provider receiving the pushed configuration on the mesh nodes.
+	configVersionProvider = "rest"
+)
+
+// ConfigVersions holds the configuration version applied on each mesh node.
+type ConfigVersions struct {
+	mu       sync.RWMutex
+	versions map[string]string
+}
+
+// NewConfigVersions returns an initialized ConfigVersions.
+func NewConfigVersions() *ConfigVersions {
+	return &ConfigVersions{
+		versions: make(map[string]string),
+	}
+}
+
+// Set records the configuration version applied on a mesh node.
+func (v *ConfigVersions) Set(podName, version string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.versions[podName] = version
+}
+
+// Get returns the configuration version applied on a mesh node, or an empty string if unknown.
+func (v *ConfigVersions) Get(podName string) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	return v.versions[podName]
+}
+
+// withConfigVersion returns a copy of the configuration, holding its version in a dedicated middleware.
+func withConfigVersion(config *dynamic.Configuration) (*dynamic.Configuration, error) {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal configuration: %v", err)
+	}
+
+	sum := sha256.Sum256(b)
+	version := hex.EncodeToString(sum[:8])
+
+	versioned := *config
+
+	httpConfig := dynamic.HTTPConfiguration{}
+	if config.HTTP != nil {
+		httpConfig = *config.HTTP
+	}
+
+	httpConfig.Middlewares = make(map[string]*dynamic.Middleware, len(httpConfig.Middlewares)+1)
+	if config.HTTP != nil {
+		for name, middleware := range config.HTTP.Middlewares {
+			httpConfig.Middlewares[name] = middleware
+		}
+	}
+
+	httpConfig.Middlewares[configVersionMiddleware] = &dynamic.Middleware{
+		Headers: &dynamic.Headers{
+			CustomRequestHeaders: map[string]string{configVersionHeader: version},
+		},
+	}
+
+	versioned.HTTP = &httpConfig
+
+	return &versioned, nil
+}
+
+// getConfigVersion returns the version held by a configuration, or an empty string if it has none.
+func getConfigVersion(config *dynamic.Configuration) string {
+	if config == nil || config.HTTP == nil {
+		return ""
+	}
+
+	return middlewareConfigVersion(config.HTTP.Middlewares[configVersionMiddleware])
+}
+
+func middlewareConfigVersion(middleware *dynamic.Middleware) string {
+	if middleware == nil || middleware.Headers == nil {
+		return ""
+	}
+
+	return middleware.Headers.CustomRequestHeaders[configVersionHeader]
+}
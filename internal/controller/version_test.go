@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithConfigVersion(t *testing.T) {
+	config := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers:     map[string]*dynamic.Router{"foo": {Service: "foo"}},
+			Middlewares: map[string]*dynamic.Middleware{"bar": {}},
+		},
+	}
+
+	versioned, err := withConfigVersion(config)
+	require.NoError(t, err)
+
+	version := getConfigVersion(versioned)
+	assert.Len(t, version, 16)
+	assert.Contains(t, versioned.HTTP.Middlewares, "bar")
+
+	// The given configuration is left untouched.
+	assert.Empty(t, getConfigVersion(config))
+	assert.Len(t, config.HTTP.Middlewares, 1)
+
+	again, err := withConfigVersion(config)
+	require.NoError(t, err)
+	assert.Equal(t, version, getConfigVersion(again))
+
+	config.HTTP.Routers["foo"].Priority = 1
+
+	changed, err := withConfigVersion(config)
+	require.NoError(t, err)
+	assert.NotEqual(t, version, getConfigVersion(changed))
+}
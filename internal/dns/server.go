@@ -0,0 +1,172 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	listers "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// Zone is the DNS zone served for mesh services.
+	Zone = "maesh."
+
+	// ttl is the TTL of the answers, kept short as mesh services may be deleted at any time.
+	ttl = 5
+)
+
+// Server is a DNS server resolving <service>.<namespace>.maesh names to the ClusterIP of the associated mesh service.
+type Server struct {
+	port          int
+	meshNamespace string
+	serviceLister listers.ServiceLister
+	udpServer     *dns.Server
+	tcpServer     *dns.Server
+}
+
+// NewServer creates a new DNS server.
+func NewServer(port int, meshNamespace string, serviceLister listers.ServiceLister) *Server {
+	s := &Server{
+		port:          port,
+		meshNamespace: meshNamespace,
+		serviceLister: serviceLister,
+	}
+
+	mux := dns.NewServeMux()
+	mux.Handle(Zone, s)
+
+	addr := fmt.Sprintf(":%d", port)
+	s.udpServer = &dns.Server{Addr: addr, Net: "udp", Handler: mux}
+	s.tcpServer = &dns.Server{Addr: addr, Net: "tcp", Handler: mux}
+
+	return s
+}
+
+// Start runs the DNS server on both UDP and TCP.
+func (s *Server) Start() {
+	log.Debugf("Starting DNS server for zone %s on port %d", Zone, s.port)
+
+	go func() {
+		log.Error(s.udpServer.ListenAndServe())
+	}()
+
+	go func() {
+		log.Error(s.tcpServer.ListenAndServe())
+	}()
+}
+
+// Shutdown stops the UDP and TCP servers.
+func (s *Server) Shutdown(ctx context.Context) {
+	for _, server := range []*dns.Server{s.udpServer, s.tcpServer} {
+		if err := server.ShutdownContext(ctx); err != nil {
+			log.Errorf("Unable to shut down DNS server: %v", err)
+		}
+	}
+}
+
+// ServeDNS answers a DNS query for the maesh zone.
+func (s *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+
+	if len(r.Question) != 1 {
+		m.SetRcode(r, dns.RcodeFormatError)
+		s.writeMsg(w, m)
+
+		return
+	}
+
+	question := r.Question[0]
+
+	ip, found := s.resolve(question.Name)
+	if !found {
+		m.SetRcode(r, dns.RcodeNameError)
+		m.Ns = []dns.RR{soa()}
+		s.writeMsg(w, m)
+
+		return
+	}
+
+	if question.Qtype == dns.TypeA || question.Qtype == dns.TypeANY {
+		m.Answer = append(m.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: question.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+			A:   ip,
+		})
+	}
+
+	if len(m.Answer) == 0 {
+		// The name exists but has no record of the requested type.
+		m.Ns = []dns.RR{soa()}
+	}
+
+	s.writeMsg(w, m)
+}
+
+// resolve returns the ClusterIP of the mesh service associated to the given name.
+func (s *Server) resolve(name string) (net.IP, bool) {
+	serviceName, serviceNamespace, ok := parseName(name)
+	if !ok {
+		return nil, false
+	}
+
+	meshServiceName := k8s.MeshServiceName(s.meshNamespace, serviceName, serviceNamespace)
+
+	service, err := s.serviceLister.Services(s.meshNamespace).Get(meshServiceName)
+	if err != nil {
+		if !kubeerror.IsNotFound(err) {
+			log.Errorf("Unable to get mesh service %s/%s: %v", s.meshNamespace, meshServiceName, err)
+		}
+
+		return nil, false
+	}
+
+	ip := net.ParseIP(service.Spec.ClusterIP).To4()
+	if ip == nil {
+		log.Debugf("Mesh service %s/%s has no IPv4 ClusterIP", s.meshNamespace, meshServiceName)
+		return nil, false
+	}
+
+	return ip, true
+}
+
+func (s *Server) writeMsg(w dns.ResponseWriter, m *dns.Msg) {
+	if err := w.WriteMsg(m); err != nil {
+		log.Errorf("Unable to write DNS response: %v", err)
+	}
+}
+
+// parseName returns the service name and namespace of a <service>.<namespace>.maesh. name.
+func parseName(name string) (string, string, bool) {
+	name = strings.ToLower(dns.Fqdn(name))
+	if !strings.HasSuffix(name, "."+Zone) {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.TrimSuffix(name, "."+Zone), ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// soa returns the SOA record of the maesh zone, used for negative answers.
+func soa() dns.RR {
+	return &dns.SOA{
+		Hdr:     dns.RR_Header{Name: Zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: ttl},
+		Ns:      "ns.dns." + Zone,
+		Mbox:    "hostmaster." + Zone,
+		Serial:  1,
+		Refresh: 7200,
+		Retry:   1800,
+		Expire:  86400,
+		Minttl:  ttl,
+	}
+}
@@ -0,0 +1,114 @@
+package dns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type responseRecorder struct {
+	dns.ResponseWriter
+
+	msg *dns.Msg
+}
+
+func (r *responseRecorder) WriteMsg(m *dns.Msg) error {
+	r.msg = m
+	return nil
+}
+
+func TestServeDNS(t *testing.T) {
+	meshService := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "maesh-whoami-6d61657368-foo",
+			Namespace: "maesh",
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.0.0.10",
+		},
+	}
+
+	client := fake.NewSimpleClientset()
+	factory := informers.NewSharedInformerFactory(client, 0)
+	serviceInformer := factory.Core().V1().Services()
+
+	err := serviceInformer.Informer().GetIndexer().Add(meshService)
+	require.NoError(t, err)
+
+	server := NewServer(9053, "maesh", serviceInformer.Lister())
+
+	testCases := []struct {
+		desc           string
+		name           string
+		qtype          uint16
+		expectedRcode  int
+		expectedAnswer net.IP
+	}{
+		{
+			desc:           "A record of a mesh service",
+			name:           "whoami.foo.maesh.",
+			qtype:          dns.TypeA,
+			expectedRcode:  dns.RcodeSuccess,
+			expectedAnswer: net.ParseIP("10.0.0.10").To4(),
+		},
+		{
+			desc:           "A record of a mesh service with mixed case",
+			name:           "WhoAmI.Foo.maesh.",
+			qtype:          dns.TypeA,
+			expectedRcode:  dns.RcodeSuccess,
+			expectedAnswer: net.ParseIP("10.0.0.10").To4(),
+		},
+		{
+			desc:          "AAAA record of a mesh service",
+			name:          "whoami.foo.maesh.",
+			qtype:         dns.TypeAAAA,
+			expectedRcode: dns.RcodeSuccess,
+		},
+		{
+			desc:          "unknown service",
+			name:          "bar.foo.maesh.",
+			qtype:         dns.TypeA,
+			expectedRcode: dns.RcodeNameError,
+		},
+		{
+			desc:          "invalid name",
+			name:          "foo.maesh.",
+			qtype:         dns.TypeA,
+			expectedRcode: dns.RcodeNameError,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			req := new(dns.Msg)
+			req.SetQuestion(test.name, test.qtype)
+
+			rec := &responseRecorder{}
+			server.ServeDNS(rec, req)
+
+			require.NotNil(t, rec.msg)
+			assert.Equal(t, test.expectedRcode, rec.msg.Rcode)
+			assert.True(t, rec.msg.Authoritative)
+
+			if test.expectedAnswer == nil {
+				assert.Empty(t, rec.msg.Answer)
+				assert.Len(t, rec.msg.Ns, 1)
+
+				return
+			}
+
+			require.Len(t, rec.msg.Answer, 1)
+			assert.Equal(t, test.expectedAnswer, rec.msg.Answer[0].(*dns.A).A)
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	kubeerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -31,21 +32,13 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-var (
-	supportedCoreDNSVersions = []string{
-		"1.3",
-		"1.4",
-		"1.5",
-		"1.6",
-	}
-)
-
 // ClientWrapper holds the clients for the various resource controllers.
 type ClientWrapper struct {
-	KubeClient      *kubernetes.Clientset
-	SmiAccessClient *smiAccessClientset.Clientset
-	SmiSpecsClient  *smiSpecsClientset.Clientset
-	SmiSplitClient  *smiSplitClientset.Clientset
+	KubeClient      kubernetes.Interface
+	DynamicClient   dynamic.Interface
+	SmiAccessClient smiAccessClientset.Interface
+	SmiSpecsClient  smiSpecsClientset.Interface
+	SmiSplitClient  smiSplitClientset.Interface
 }
 
 // NewClientWrapper creates and returns both a kubernetes client, and a CRD client.
@@ -60,6 +53,11 @@ func NewClientWrapper(url string, kubeConfig string) (*ClientWrapper, error) {
 		return nil, err
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create dynamic client: %v", err)
+	}
+
 	smiAccessClient, err := buildSmiAccessClient(config)
 	if err != nil {
 		return nil, err
@@ -77,6 +75,7 @@ func NewClientWrapper(url string, kubeConfig string) (*ClientWrapper, error) {
 
 	return &ClientWrapper{
 		KubeClient:      kubeClient,
+		DynamicClient:   dynamicClient,
 		SmiAccessClient: smiAccessClient,
 		SmiSpecsClient:  smiSpecsClient,
 		SmiSplitClient:  smiSplitClient,
@@ -84,83 +83,35 @@ func NewClientWrapper(url string, kubeConfig string) (*ClientWrapper, error) {
 }
 
 // CheckCluster is used to check the cluster.
-func (w *ClientWrapper) CheckCluster() error {
+func (w *ClientWrapper) CheckCluster(dnsOptions DNSOptions) error {
 	log.Infoln("Checking Cluster...")
 
-	match, err := w.CoreDNSMatch()
+	dns, err := w.GetDNSDeployment(dnsOptions)
 	if err != nil {
-		return err
+		return fmt.Errorf("no DNS service available for installing maesh: %v", err)
 	}
 
-	if !match {
-		match, err = w.KubeDNSMatch()
-		if err != nil {
-			return err
-		}
+	if dns.Provider == DNSProviderCoreDNS {
+		return w.CoreDNSMatch(dns.Deployment, dnsOptions.SupportedCoreDNSVersions)
 	}
 
-	if !match {
-		return fmt.Errorf("no core dns service available for installing maesh: %v", err)
-	}
+	log.Info("KubeDNS match")
 
 	return nil
 }
 
-// CoreDNSMatch checks if CoreDNS service can match.
-func (w *ClientWrapper) CoreDNSMatch() (bool, error) {
+// CoreDNSMatch checks if the CoreDNS deployment runs a version satisfying the supported versions constraint.
+func (w *ClientWrapper) CoreDNSMatch(deployment *appsv1.Deployment, supportedVersions string) error {
 	log.Infoln("Checking CoreDNS...")
 	log.Debugln("Get CoreDNS version...")
 
-	deployment, exists, err := w.GetDeployment(metav1.NamespaceSystem, "coredns")
-	if err != nil {
-		return false, fmt.Errorf("unable to get deployment %q in namesapce %q: %v", "coredns", metav1.NamespaceSystem, err)
-	}
-
-	if !exists {
-		log.Debugf("%s does not exist in namespace %s", "coredns", metav1.NamespaceSystem)
-		return false, nil
-	}
-
-	var version string
-
-	for _, c := range deployment.Spec.Template.Spec.Containers {
-		if c.Name != "coredns" {
-			continue
-		}
-
-		split := strings.Split(c.Image, ":")
-		if len(split) == 2 {
-			version = split[1]
-		}
-	}
-
-	if !isCoreDNSVersionSupported(version) {
-		return false, fmt.Errorf("unsupported CoreDNS version %q, (supported versions are: %s)", version, strings.Join(supportedCoreDNSVersions, ","))
+	if err := checkCoreDNSVersion(getCoreDNSContainerImage(deployment), supportedVersions); err != nil {
+		return err
 	}
 
 	log.Info("CoreDNS match")
 
-	return true, nil
-}
-
-// KubeDNSMatch checks if KubeDNS service can match.
-func (w *ClientWrapper) KubeDNSMatch() (bool, error) {
-	log.Infoln("Checking KubeDNS...")
-	log.Debugln("Get KubeDNS version...")
-
-	_, exists, err := w.GetDeployment(metav1.NamespaceSystem, "kube-dns")
-	if err != nil {
-		return false, fmt.Errorf("unable to get deployment %q in namesapce %q: %v", "kube-dns", metav1.NamespaceSystem, err)
-	}
-
-	if !exists {
-		log.Debugf("%s does not exist in namespace %s", "kube-dns", metav1.NamespaceSystem)
-		return false, nil
-	}
-
-	log.Info("KubeDNS match")
-
-	return true, nil
+	return nil
 }
 
 // CheckInformersStart checks if the required informers can start and sync in a reasonable time.
@@ -222,23 +173,12 @@ func (w *ClientWrapper) CheckInformersStart(smi bool) error {
 	return nil
 }
 
-// isCoreDNSVersionSupported returns true if the provided string contains a supported CoreDNS version.
-func isCoreDNSVersionSupported(versionLine string) bool {
-	for _, v := range supportedCoreDNSVersions {
-		if strings.Contains(versionLine, v) || strings.Contains(versionLine, "v"+v) {
-			return true
-		}
-	}
-
-	return false
-}
-
 // InitCluster is used to initialize a kubernetes cluster with a variety of configuration options.
-func (w *ClientWrapper) InitCluster(namespace string, clusterDomain string) error {
+func (w *ClientWrapper) InitCluster(namespace string, clusterDomain string, dnsOptions DNSOptions) error {
 	log.Infoln("Preparing Cluster...")
 	log.Debugln("Patching DNS...")
 
-	if err := w.patchDNS(metav1.NamespaceSystem, clusterDomain, namespace); err != nil {
+	if err := w.patchDNS(dnsOptions, clusterDomain, namespace); err != nil {
 		return err
 	}
 
@@ -247,19 +187,19 @@ func (w *ClientWrapper) InitCluster(namespace string, clusterDomain string) erro
 	return nil
 }
 
-func (w *ClientWrapper) patchDNS(coreNamespace, clusterDomain, maeshNamespace string) error {
-	deployment, exist, err := w.GetDeployment(coreNamespace, "coredns")
+func (w *ClientWrapper) patchDNS(dnsOptions DNSOptions, clusterDomain, maeshNamespace string) error {
+	dns, err := w.GetDNSDeployment(dnsOptions)
 	if err != nil {
 		return err
 	}
 
 	// If CoreDNS exist we will patch it.
-	if exist {
+	if dns.Provider == DNSProviderCoreDNS {
 		log.Debugln("Patching CoreDNS configmap...")
 
 		var patched bool
 
-		patched, err = w.patchCoreDNSConfigMap(deployment, clusterDomain, maeshNamespace)
+		patched, err = w.patchCoreDNSConfigMap(dns.Deployment, dns.ConfigMapName, clusterDomain, maeshNamespace)
 		if err != nil {
 			return err
 		}
@@ -267,7 +207,7 @@ func (w *ClientWrapper) patchDNS(coreNamespace, clusterDomain, maeshNamespace st
 		if !patched {
 			log.Debugln("Restarting CoreDNS pods...")
 
-			if err = w.restartPods(deployment); err != nil {
+			if err = w.restartPods(dns.Deployment); err != nil {
 				return err
 			}
 
@@ -278,14 +218,10 @@ func (w *ClientWrapper) patchDNS(coreNamespace, clusterDomain, maeshNamespace st
 	}
 
 	log.Debugln("coredns not available fallback to kube-dns")
-	// If coreDNS does not exist we try to get the kube-dns
-	deployment, exist, err = w.GetDeployment(coreNamespace, "kube-dns")
-	if err != nil {
-		return err
-	}
 
-	if !exist {
-		return fmt.Errorf("neither CoreDNS or KubeDNS are available in namespace %q", coreNamespace)
+	coreDNSServiceNamespace := dnsOptions.CoreDNSServiceNamespace
+	if coreDNSServiceNamespace == "" {
+		coreDNSServiceNamespace = maeshNamespace
 	}
 
 	ebo := backoff.NewConstantBackOff(10 * time.Second)
@@ -295,9 +231,9 @@ func (w *ClientWrapper) patchDNS(coreNamespace, clusterDomain, maeshNamespace st
 	log.Debugln("Get CoreDNS service IP")
 
 	if err = backoff.Retry(safe.OperationWithRecover(func() error {
-		svc, exists, errSvc := w.GetService("maesh", "coredns")
+		svc, exists, errSvc := w.GetService(coreDNSServiceNamespace, "coredns")
 		if errSvc != nil {
-			return fmt.Errorf("unable get the service %q in namespace %q: %v", "coredns", "maesh", errSvc)
+			return fmt.Errorf("unable get the service %q in namespace %q: %v", "coredns", coreDNSServiceNamespace, errSvc)
 		}
 		if !exists {
 			return fmt.Errorf("service %q has not been yet created", "coredns")
@@ -309,13 +245,13 @@ func (w *ClientWrapper) patchDNS(coreNamespace, clusterDomain, maeshNamespace st
 		serviceIP = svc.Spec.ClusterIP
 		return nil
 	}), ebo); err != nil {
-		return fmt.Errorf("unable get the service %q in namespace %q: %v", "coredns", "maesh", err)
+		return fmt.Errorf("unable get the service %q in namespace %q: %v", "coredns", coreDNSServiceNamespace, err)
 	}
 
 	// Patch KubeDNS
 	log.Debugln("Patching KubeDNS configmap... with IP: ", serviceIP)
 
-	patched, err := w.patchKubeDNSConfigMap(deployment, serviceIP)
+	patched, err := w.patchKubeDNSConfigMap(dns.Deployment, dns.ConfigMapName, serviceIP)
 	if err != nil {
 		return err
 	}
@@ -323,7 +259,7 @@ func (w *ClientWrapper) patchDNS(coreNamespace, clusterDomain, maeshNamespace st
 	if !patched {
 		log.Debugln("Restarting KubeDNS pods...")
 
-		if err := w.restartPods(deployment); err != nil {
+		if err := w.restartPods(dns.Deployment); err != nil {
 			return err
 		}
 	}
@@ -331,55 +267,25 @@ func (w *ClientWrapper) patchDNS(coreNamespace, clusterDomain, maeshNamespace st
 	return nil
 }
 
-func (w *ClientWrapper) patchCoreDNSConfigMap(coreDeployment *appsv1.Deployment, clusterDomain, maeshNamespace string) (bool, error) {
-	var coreConfigMapName string
-
-	if len(coreDeployment.Spec.Template.Spec.Volumes) == 0 {
-		return false, errors.New("coreDNS configmap not defined")
-	}
-
-	coreConfigMapName = getCoreDNSConfigMapName(coreDeployment)
-
+func (w *ClientWrapper) patchCoreDNSConfigMap(coreDeployment *appsv1.Deployment, coreConfigMapName, clusterDomain, maeshNamespace string) (bool, error) {
 	coreConfigMap, err := w.KubeClient.CoreV1().ConfigMaps(coreDeployment.Namespace).Get(coreConfigMapName, metav1.GetOptions{})
 	if err != nil {
 		return false, err
 	}
 
-	if len(coreConfigMap.ObjectMeta.Labels) > 0 {
-		if _, ok := coreConfigMap.ObjectMeta.Labels["maesh-patched"]; ok {
-			log.Debugln("Configmap already patched...")
-			return true, nil
-		}
+	serverBlock := buildCoreDNSServerBlock(clusterDomain, maeshNamespace)
+
+	newCorefile, changed := patchCorefile(coreConfigMap.Data["Corefile"], serverBlock)
+	if !changed {
+		log.Debugln("Configmap already patched...")
+		return true, nil
 	}
 
-	serverBlock := fmt.Sprintf(
-		`
-maesh:53 {
-    errors
-    rewrite continue {
-        name regex ([a-zA-Z0-9-_]*)\.([a-zv0-9-_]*)\.maesh %[3]s-{1}-6d61657368-{2}.%[3]s.svc.%[1]s
-        answer name %[3]s-([a-zA-Z0-9-_]*)-6d61657368-([a-zA-Z0-9-_]*)\.%[3]s\.svc\.%[2]s {1}.{2}.maesh
-    }
-    kubernetes %[1]s in-addr.arpa ip6.arpa {
-        pods insecure
-        upstream
-    	fallthrough in-addr.arpa ip6.arpa
-    }
-    forward . /etc/resolv.conf
-    cache 30
-    loop
-    reload
-    loadbalance
-}
-`,
-		clusterDomain,
-		strings.Replace(clusterDomain, ".", "\\.", -1),
-		maeshNamespace,
-	)
+	if coreConfigMap.Data == nil {
+		coreConfigMap.Data = make(map[string]string)
+	}
 
-	originalBlock := coreConfigMap.Data["Corefile"]
-	newBlock := originalBlock + serverBlock
-	coreConfigMap.Data["Corefile"] = newBlock
+	coreConfigMap.Data["Corefile"] = newCorefile
 
 	if len(coreConfigMap.ObjectMeta.Labels) == 0 {
 		coreConfigMap.ObjectMeta.Labels = make(map[string]string)
@@ -394,27 +300,16 @@ maesh:53 {
 	return false, nil
 }
 
-func (w *ClientWrapper) patchKubeDNSConfigMap(deployment *appsv1.Deployment, coreDNSIp string) (bool, error) {
-	var configMapName string
-
-	if len(deployment.Spec.Template.Spec.Volumes) == 0 {
+func (w *ClientWrapper) patchKubeDNSConfigMap(deployment *appsv1.Deployment, configMapName, coreDNSIp string) (bool, error) {
+	if configMapName == "" {
 		return false, errors.New("kube-dns configmap not defined")
 	}
 
-	configMapName = deployment.Spec.Template.Spec.Volumes[0].ConfigMap.Name
-
 	configMap, err := w.KubeClient.CoreV1().ConfigMaps(deployment.Namespace).Get(configMapName, metav1.GetOptions{})
 	if err != nil {
 		return false, err
 	}
 
-	if len(configMap.ObjectMeta.Labels) > 0 {
-		if _, ok := configMap.ObjectMeta.Labels["maesh-patched"]; ok {
-			log.Debugln("Configmap already patched...")
-			return true, nil
-		}
-	}
-
 	stubDomains := make(map[string][]string)
 	originalBlock, exist := configMap.Data["stubDomains"]
 
@@ -426,6 +321,11 @@ func (w *ClientWrapper) patchKubeDNSConfigMap(deployment *appsv1.Deployment, cor
 		return false, err
 	}
 
+	if current := stubDomains["maesh"]; len(current) == 1 && current[0] == coreDNSIp {
+		log.Debugln("Configmap already patched...")
+		return true, nil
+	}
+
 	stubDomains["maesh"] = []string{coreDNSIp}
 
 	var newData []byte
@@ -473,31 +373,25 @@ func (w *ClientWrapper) restartPods(deployment *appsv1.Deployment) error {
 }
 
 // VerifyCluster is used to verify a kubernetes cluster has been initialized properly.
-func (w *ClientWrapper) VerifyCluster() error {
+func (w *ClientWrapper) VerifyCluster(dnsOptions DNSOptions) error {
 	log.Infoln("Verifying Cluster...")
 	defer log.Infoln("Cluster Verification Complete...")
 	log.Debugln("Verifying CoreDNS Patched...")
 
-	if err := w.isCoreDNSPatched("coredns", metav1.NamespaceSystem); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (w *ClientWrapper) isCoreDNSPatched(deploymentName string, namespace string) error {
-	coreDeployment, err := w.KubeClient.AppsV1().Deployments(namespace).Get(deploymentName, metav1.GetOptions{})
+	dns, err := w.GetDNSDeployment(dnsOptions)
 	if err != nil {
 		return err
 	}
 
-	if len(coreDeployment.Spec.Template.Spec.Volumes) == 0 {
-		return errors.New("coreDNS configmap not defined")
+	if dns.Provider != DNSProviderCoreDNS {
+		return nil
 	}
 
-	coreConfigMapName := getCoreDNSConfigMapName(coreDeployment)
+	return w.isCoreDNSPatched(dns.Deployment.Namespace, dns.ConfigMapName)
+}
 
-	coreConfigMap, err := w.KubeClient.CoreV1().ConfigMaps(coreDeployment.Namespace).Get(coreConfigMapName, metav1.GetOptions{})
+func (w *ClientWrapper) isCoreDNSPatched(namespace, coreConfigMapName string) error {
+	coreConfigMap, err := w.KubeClient.CoreV1().ConfigMaps(namespace).Get(coreConfigMapName, metav1.GetOptions{})
 	if err != nil {
 		return err
 	}
@@ -20,6 +20,7 @@ import (
 	fakeSMISplit "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/clientset/versioned/fake"
 	splitInformer "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/informers/externalversions"
 	splitLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/listers/split/v1alpha2"
+	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
@@ -80,7 +81,11 @@ func NewClientMock(stopCh <-chan struct{}, path string, smi bool) *ClientMock {
 		panic(err)
 	}
 
-	k8sObjects := MustParseYaml(yamlContent)
+	return NewClientMockFromObjects(stopCh, MustParseYaml(yamlContent), smi)
+}
+
+// NewClientMockFromObjects create a new client mock serving the given objects.
+func NewClientMockFromObjects(stopCh <-chan struct{}, k8sObjects []runtime.Object, smi bool) *ClientMock {
 	c := &ClientMock{}
 
 	c.client = fake.NewSimpleClientset(filterObjectsByKind(k8sObjects, CoreObjectKinds)...)
@@ -162,33 +167,63 @@ func NewClientMock(stopCh <-chan struct{}, path string, smi bool) *ClientMock {
 	return c
 }
 
+// ClientWrapper returns a ClientWrapper of the fake clientsets, holding the SMI clientsets if enabled.
+func (c *ClientMock) ClientWrapper() *ClientWrapper {
+	clients := &ClientWrapper{KubeClient: c.client}
+
+	if c.accessClient != nil {
+		clients.SmiAccessClient = c.accessClient
+		clients.SmiSpecsClient = c.specsClient
+		clients.SmiSplitClient = c.splitClient
+	}
+
+	return clients
+}
+
 // MustParseYaml parses a YAML to objects.
 func MustParseYaml(content []byte) []runtime.Object {
-	acceptedK8sTypes := regexp.MustCompile(`(Deployment|Endpoints|Service|Ingress|Middleware|Secret|TLSOption|Namespace|TrafficTarget|HTTPRouteGroup|TCPRoute|TrafficSplit|Pod)`)
+	objects, err := ParseYaml(content)
+	if err != nil {
+		panic(err)
+	}
+
+	return objects
+}
+
+// ParseYaml parses a YAML to objects.
+// The objects of unsupported kinds, like the workloads of a manifest, are skipped with a warning.
+func ParseYaml(content []byte) ([]runtime.Object, error) {
+	acceptedK8sTypes := regexp.MustCompile(`^(Deployment|Endpoints|Service|Ingress|Middleware|Secret|ConfigMap|TLSOption|Namespace|TrafficTarget|HTTPRouteGroup|TCPRoute|TrafficSplit|Pod)$`)
 
 	files := strings.Split(string(content), "---")
 	retVal := make([]runtime.Object, 0, len(files))
 
 	for _, file := range files {
-		if file == "\n" || file == "" {
+		if strings.TrimSpace(file) == "" {
 			continue
 		}
 
 		decode := scheme.Codecs.UniversalDeserializer().Decode
 		obj, groupVersionKind, err := decode([]byte(file), nil, nil)
 
+		if runtime.IsNotRegisteredError(err) {
+			log.Warnf("Skipping object of unknown type: %v", err)
+			continue
+		}
+
 		if err != nil {
-			panic(fmt.Sprintf("Error while decoding YAML object. Err was: %s", err))
+			return nil, fmt.Errorf("error while decoding YAML object: %v", err)
 		}
 
 		if !acceptedK8sTypes.MatchString(groupVersionKind.Kind) {
-			panic(fmt.Sprintf("The custom-roles configMap contained K8s object types which are not supported! Skipping object with type: %s", groupVersionKind.Kind))
-		} else {
-			retVal = append(retVal, obj)
+			log.Warnf("Skipping object of unsupported type: %s", groupVersionKind.Kind)
+			continue
 		}
+
+		retVal = append(retVal, obj)
 	}
 
-	return retVal
+	return retVal, nil
 }
 
 // filterObjectsByKind filters out objects that are not the selected kind.
@@ -10,7 +10,7 @@ const (
 	baseAnnotation string = "maesh.containo.us/"
 
 	// CoreObjectKinds is a filter for objects to process by the core client.
-	CoreObjectKinds = "Deployment|Endpoints|Service|Ingress|Secret|Namespace|Pod"
+	CoreObjectKinds = "Deployment|Endpoints|Service|Ingress|Secret|ConfigMap|Namespace|Pod"
 	// AccessObjectKinds is a filter for objects to process by the access client.
 	AccessObjectKinds = "TrafficTarget"
 	// SpecsObjectKinds is a filter for objects to process by the specs client.
@@ -18,6 +18,8 @@ const (
 	// SplitObjectKinds is a filter for objects to process by the split client.
 	SplitObjectKinds = "TrafficSplit"
 
+	// AnnotationEnabled explicitly adds or removes a service from the mesh.
+	AnnotationEnabled = baseAnnotation + "enabled"
 	// AnnotationServiceType service type annotation.
 	AnnotationServiceType = baseAnnotation + "traffic-type"
 	// AnnotationScheme scheme.
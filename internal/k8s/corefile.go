@@ -0,0 +1,121 @@
+package k8s
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// coreDNSBlockVersion is bumped each time the structure of the maesh server block changes.
+	coreDNSBlockVersion = 1
+
+	coreDNSBlockBeginMarker = "#### Begin Maesh Block"
+	coreDNSBlockEndMarker   = "#### End Maesh Block"
+)
+
+var (
+	coreDNSBlockRegexp       = regexp.MustCompile(`(?s)\n?` + regexp.QuoteMeta(coreDNSBlockBeginMarker) + `[^\n]*\n.*?` + regexp.QuoteMeta(coreDNSBlockEndMarker) + `\n?`)
+	coreDNSBlockHeaderRegexp = regexp.MustCompile(regexp.QuoteMeta(coreDNSBlockBeginMarker) + ` \(version: (\d+), hash: ([0-9a-f]+)\)`)
+)
+
+// buildCoreDNSServerBlock returns the maesh CoreDNS server block, delimited with versioned markers.
+func buildCoreDNSServerBlock(clusterDomain, maeshNamespace string) string {
+	body := fmt.Sprintf(
+		`maesh:53 {
+    errors
+    rewrite continue {
+        name regex ([a-zA-Z0-9-_]*)\.([a-zv0-9-_]*)\.maesh %[3]s-{1}-6d61657368-{2}.%[3]s.svc.%[1]s
+        answer name %[3]s-([a-zA-Z0-9-_]*)-6d61657368-([a-zA-Z0-9-_]*)\.%[3]s\.svc\.%[2]s {1}.{2}.maesh
+    }
+    kubernetes %[1]s in-addr.arpa ip6.arpa {
+        pods insecure
+        upstream
+    	fallthrough in-addr.arpa ip6.arpa
+    }
+    forward . /etc/resolv.conf
+    cache 30
+    loop
+    reload
+    loadbalance
+}
+`,
+		clusterDomain,
+		strings.Replace(clusterDomain, ".", "\\.", -1),
+		maeshNamespace,
+	)
+
+	return fmt.Sprintf("%s (version: %d, hash: %s)\n%s%s\n", coreDNSBlockBeginMarker, coreDNSBlockVersion, hashCoreDNSBlock(body), body, coreDNSBlockEndMarker)
+}
+
+// hashCoreDNSBlock returns a short hash of the given block body.
+func hashCoreDNSBlock(body string) string {
+	sum := sha256.Sum256([]byte(body))
+
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// getCoreDNSBlockHeader returns the version and hash found in the maesh block header of the given Corefile.
+func getCoreDNSBlockHeader(corefile string) (version, hash string, found bool) {
+	matches := coreDNSBlockHeaderRegexp.FindStringSubmatch(corefile)
+	if matches == nil {
+		return "", "", false
+	}
+
+	return matches[1], matches[2], true
+}
+
+// patchCorefile returns the given Corefile with the maesh server block up to date, and whether it has been modified.
+// An existing delimited block is replaced in place, and a block injected by a previous version of maesh without
+// markers is removed before the new block is appended.
+func patchCorefile(corefile, serverBlock string) (string, bool) {
+	if loc := coreDNSBlockRegexp.FindStringIndex(corefile); loc != nil {
+		currentVersion, currentHash, _ := getCoreDNSBlockHeader(corefile)
+		newVersion, newHash, _ := getCoreDNSBlockHeader(serverBlock)
+
+		if currentVersion == newVersion && currentHash == newHash {
+			return corefile, false
+		}
+
+		return corefile[:loc[0]] + "\n" + serverBlock + corefile[loc[1]:], true
+	}
+
+	cleaned := removeLegacyCoreDNSBlock(corefile)
+	if cleaned != "" && !strings.HasSuffix(cleaned, "\n") {
+		cleaned += "\n"
+	}
+
+	return cleaned + "\n" + serverBlock, true
+}
+
+// removeLegacyCoreDNSBlock removes the unmarked maesh server block injected by previous versions of maesh.
+func removeLegacyCoreDNSBlock(corefile string) string {
+	start := strings.Index(corefile, "\nmaesh:53 {")
+	if start < 0 {
+		return corefile
+	}
+
+	depth := 0
+
+	for i := start + 1; i < len(corefile); i++ {
+		switch corefile[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+
+			if depth == 0 {
+				end := i + 1
+				if end < len(corefile) && corefile[end] == '\n' {
+					end++
+				}
+
+				return corefile[:start] + corefile[end:]
+			}
+		}
+	}
+
+	return corefile
+}
@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const baseCorefile = `.:53 {
+    errors
+    health
+    kubernetes cluster.local in-addr.arpa ip6.arpa {
+       pods insecure
+       upstream
+       fallthrough in-addr.arpa ip6.arpa
+    }
+    forward . /etc/resolv.conf
+    cache 30
+}
+`
+
+func TestPatchCorefile(t *testing.T) {
+	block := buildCoreDNSServerBlock("cluster.local", "maesh")
+	otherBlock := buildCoreDNSServerBlock("cluster.local", "traefik-mesh")
+
+	legacyCorefile := baseCorefile + `
+maesh:53 {
+    errors
+    rewrite continue {
+        name regex ([a-zA-Z0-9-_]*)\.([a-zv0-9-_]*)\.maesh maesh-{1}-6d61657368-{2}.maesh.svc.cluster.local
+    }
+    kubernetes cluster.local in-addr.arpa ip6.arpa {
+        pods insecure
+    }
+    forward . /etc/resolv.conf
+}
+`
+
+	testCases := []struct {
+		desc            string
+		corefile        string
+		expectedChanged bool
+		expected        string
+	}{
+		{
+			desc:            "not patched",
+			corefile:        baseCorefile,
+			expectedChanged: true,
+			expected:        baseCorefile + "\n" + block,
+		},
+		{
+			desc:            "already patched",
+			corefile:        baseCorefile + "\n" + block,
+			expectedChanged: false,
+			expected:        baseCorefile + "\n" + block,
+		},
+		{
+			desc:            "patched with a different configuration",
+			corefile:        baseCorefile + "\n" + otherBlock,
+			expectedChanged: true,
+			expected:        baseCorefile + "\n" + block,
+		},
+		{
+			desc:            "patched with a different configuration followed by another block",
+			corefile:        baseCorefile + "\n" + otherBlock + "\nfoo:53 {\n    errors\n}\n",
+			expectedChanged: true,
+			expected:        baseCorefile + "\n" + block + "\nfoo:53 {\n    errors\n}\n",
+		},
+		{
+			desc:            "patched by a previous version without markers",
+			corefile:        legacyCorefile,
+			expectedChanged: true,
+			expected:        baseCorefile + "\n" + block,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			actual, changed := patchCorefile(test.corefile, block)
+			assert.Equal(t, test.expectedChanged, changed)
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, 1, strings.Count(actual, coreDNSBlockBeginMarker))
+		})
+	}
+}
+
+func TestBuildCoreDNSServerBlock(t *testing.T) {
+	block := buildCoreDNSServerBlock("cluster.local", "maesh")
+
+	version, hash, found := getCoreDNSBlockHeader(block)
+	assert.True(t, found)
+	assert.Equal(t, "1", version)
+	assert.Len(t, hash, 16)
+	assert.True(t, strings.HasSuffix(block, coreDNSBlockEndMarker+"\n"))
+
+	_, otherHash, _ := getCoreDNSBlockHeader(buildCoreDNSServerBlock("cluster.example", "maesh"))
+	assert.NotEqual(t, hash, otherHash)
+}
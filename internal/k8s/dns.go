@@ -0,0 +1,229 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DNSProvider is the kind of DNS server running in the cluster.
+type DNSProvider string
+
+const (
+	// DNSProviderCoreDNS is the CoreDNS DNS provider.
+	DNSProviderCoreDNS DNSProvider = "coredns"
+	// DNSProviderKubeDNS is the KubeDNS DNS provider.
+	DNSProviderKubeDNS DNSProvider = "kube-dns"
+
+	// DefaultSupportedCoreDNSVersions is the range of CoreDNS versions supported by default.
+	DefaultSupportedCoreDNSVersions = ">= 1.3, < 1.7"
+
+	// dnsLabelSelector is the label selector commonly set on cluster DNS deployments, whatever their name.
+	dnsLabelSelector = "k8s-app=kube-dns"
+)
+
+// imageTagVersionRegexp matches the version at the beginning of an image tag, ignoring vendor suffixes (e.g. v1.6.6-eksbuild.1).
+var imageTagVersionRegexp = regexp.MustCompile(`^v?(\d+\.\d+(?:\.\d+)?)`)
+
+// DNSOptions holds the options used to find and check the cluster DNS components.
+// Empty values are discovered.
+type DNSOptions struct {
+	// Namespace is the namespace of the DNS deployment.
+	Namespace string
+	// Deployment is the name of the DNS deployment.
+	Deployment string
+	// ConfigMap is the name of the DNS configmap.
+	ConfigMap string
+	// CoreDNSServiceNamespace is the namespace of the maesh CoreDNS service, used as KubeDNS stub domain.
+	CoreDNSServiceNamespace string
+	// SupportedCoreDNSVersions is the semver constraint the CoreDNS version must satisfy.
+	SupportedCoreDNSVersions string
+}
+
+// DNSDeployment holds a cluster DNS deployment and its configmap name.
+type DNSDeployment struct {
+	Provider      DNSProvider
+	Deployment    *appsv1.Deployment
+	ConfigMapName string
+}
+
+// GetDNSDeployment finds the cluster DNS deployment, either from the given options, from its well known names,
+// or by looking for the k8s-app=kube-dns label.
+func (w *ClientWrapper) GetDNSDeployment(options DNSOptions) (*DNSDeployment, error) {
+	deployment, err := w.findDNSDeployment(options)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := getDNSProvider(deployment)
+	if provider == "" {
+		return nil, fmt.Errorf("unable to detect the DNS provider of deployment %q in namespace %q", deployment.Name, deployment.Namespace)
+	}
+
+	configMapName := options.ConfigMap
+	if configMapName == "" {
+		configMapName = getCoreDNSConfigMapName(deployment)
+	}
+
+	if configMapName == "" && provider == DNSProviderCoreDNS {
+		return nil, errors.New("coreDNS configmap not defined")
+	}
+
+	log.Debugf("Found %s deployment %q in namespace %q with configmap %q", provider, deployment.Name, deployment.Namespace, configMapName)
+
+	return &DNSDeployment{
+		Provider:      provider,
+		Deployment:    deployment,
+		ConfigMapName: configMapName,
+	}, nil
+}
+
+func (w *ClientWrapper) findDNSDeployment(options DNSOptions) (*appsv1.Deployment, error) {
+	namespace := options.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceSystem
+	}
+
+	if options.Deployment != "" {
+		deployment, exists, err := w.GetDeployment(namespace, options.Deployment)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get deployment %q in namespace %q: %v", options.Deployment, namespace, err)
+		}
+
+		if !exists {
+			return nil, fmt.Errorf("deployment %q does not exist in namespace %q", options.Deployment, namespace)
+		}
+
+		return deployment, nil
+	}
+
+	for _, name := range []string{string(DNSProviderCoreDNS), string(DNSProviderKubeDNS)} {
+		deployment, exists, err := w.GetDeployment(namespace, name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get deployment %q in namespace %q: %v", name, namespace, err)
+		}
+
+		if exists {
+			return deployment, nil
+		}
+
+		log.Debugf("%s does not exist in namespace %s", name, namespace)
+	}
+
+	// When no namespace is given, look for the label in every namespace.
+	listNamespace := options.Namespace
+	if listNamespace == "" {
+		listNamespace = metav1.NamespaceAll
+	}
+
+	deployments, err := w.KubeClient.AppsV1().Deployments(listNamespace).List(metav1.ListOptions{LabelSelector: dnsLabelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list deployments with label %q: %v", dnsLabelSelector, err)
+	}
+
+	deployment := selectDNSDeployment(deployments.Items)
+	if deployment == nil {
+		return nil, fmt.Errorf("neither CoreDNS or KubeDNS deployments are available (searched names %q and %q in namespace %q, and label %q)", DNSProviderCoreDNS, DNSProviderKubeDNS, namespace, dnsLabelSelector)
+	}
+
+	return deployment, nil
+}
+
+// selectDNSDeployment returns the first CoreDNS deployment of the list, or the first KubeDNS deployment if there is no CoreDNS.
+func selectDNSDeployment(deployments []appsv1.Deployment) *appsv1.Deployment {
+	var kubeDNS *appsv1.Deployment
+
+	for i := range deployments {
+		switch getDNSProvider(&deployments[i]) {
+		case DNSProviderCoreDNS:
+			return &deployments[i]
+		case DNSProviderKubeDNS:
+			if kubeDNS == nil {
+				kubeDNS = &deployments[i]
+			}
+		}
+	}
+
+	return kubeDNS
+}
+
+// getDNSProvider returns the DNS provider run by the deployment, or an empty string if it can't be detected.
+func getDNSProvider(deployment *appsv1.Deployment) DNSProvider {
+	if getCoreDNSContainerImage(deployment) != "" {
+		return DNSProviderCoreDNS
+	}
+
+	for _, c := range deployment.Spec.Template.Spec.Containers {
+		if c.Name == "kubedns" || strings.Contains(c.Image, "kube-dns") {
+			return DNSProviderKubeDNS
+		}
+	}
+
+	return ""
+}
+
+// getCoreDNSContainerImage returns the image of the CoreDNS container of the deployment.
+func getCoreDNSContainerImage(deployment *appsv1.Deployment) string {
+	for _, c := range deployment.Spec.Template.Spec.Containers {
+		if c.Name == "coredns" || strings.Contains(c.Image, "coredns") {
+			return c.Image
+		}
+	}
+
+	return ""
+}
+
+// checkCoreDNSVersion returns an error if the CoreDNS image version does not satisfy the given semver constraint.
+func checkCoreDNSVersion(image, supportedVersions string) error {
+	if supportedVersions == "" {
+		supportedVersions = DefaultSupportedCoreDNSVersions
+	}
+
+	constraint, err := semver.NewConstraint(supportedVersions)
+	if err != nil {
+		return fmt.Errorf("invalid supported CoreDNS versions %q: %v", supportedVersions, err)
+	}
+
+	version, err := parseImageVersion(image)
+	if err != nil {
+		return fmt.Errorf("unable to detect CoreDNS version (supported versions are: %s): %v", supportedVersions, err)
+	}
+
+	if !constraint.Check(version) {
+		return fmt.Errorf("unsupported CoreDNS version %q detected in image %q (supported versions are: %s)", version, image, supportedVersions)
+	}
+
+	return nil
+}
+
+// parseImageVersion returns the semantic version of the given container image tag.
+// Registry prefixes, digests and vendor suffixes are ignored.
+func parseImageVersion(image string) (*semver.Version, error) {
+	// Remove the digest, if any.
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+
+	// The tag is after the last colon of the last path segment, as the registry may have a port.
+	name := image[strings.LastIndex(image, "/")+1:]
+
+	i := strings.LastIndex(name, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("no tag in image %q", image)
+	}
+
+	tag := name[i+1:]
+
+	matches := imageTagVersionRegexp.FindStringSubmatch(tag)
+	if matches == nil {
+		return nil, fmt.Errorf("no version in tag %q of image %q", tag, image)
+	}
+
+	return semver.NewVersion(matches[1])
+}
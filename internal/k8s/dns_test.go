@@ -0,0 +1,205 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetDNSProvider(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		name     string
+		image    string
+		expected DNSProvider
+	}{
+		{
+			desc:     "coredns container",
+			name:     "coredns",
+			image:    "coredns/coredns:1.6.3",
+			expected: DNSProviderCoreDNS,
+		},
+		{
+			desc:     "coredns image with another container name",
+			name:     "dns",
+			image:    "602401143452.dkr.ecr.us-west-2.amazonaws.com/eks/coredns:v1.6.6-eksbuild.1",
+			expected: DNSProviderCoreDNS,
+		},
+		{
+			desc:     "kube-dns container",
+			name:     "kubedns",
+			image:    "gcr.io/google_containers/k8s-dns-kube-dns-amd64:1.14.7",
+			expected: DNSProviderKubeDNS,
+		},
+		{
+			desc:     "unknown container",
+			name:     "foo",
+			image:    "foo/bar:1.0.0",
+			expected: "",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			actual := getDNSProvider(buildDNSDeployment("dns", test.name, test.image))
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestSelectDNSDeployment(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		deployments  []appsv1.Deployment
+		expectedName string
+	}{
+		{
+			desc:         "no deployment",
+			expectedName: "",
+		},
+		{
+			desc: "coredns is preferred",
+			deployments: []appsv1.Deployment{
+				*buildDNSDeployment("kube-dns-autoscaler", "autoscaler", "k8s.gcr.io/cluster-proportional-autoscaler-amd64:1.7.1"),
+				*buildDNSDeployment("kube-dns", "kubedns", "gcr.io/google_containers/k8s-dns-kube-dns-amd64:1.14.7"),
+				*buildDNSDeployment("custom-coredns", "coredns", "coredns/coredns:1.6.3"),
+			},
+			expectedName: "custom-coredns",
+		},
+		{
+			desc: "kube-dns only",
+			deployments: []appsv1.Deployment{
+				*buildDNSDeployment("kube-dns-autoscaler", "autoscaler", "k8s.gcr.io/cluster-proportional-autoscaler-amd64:1.7.1"),
+				*buildDNSDeployment("custom-kube-dns", "kubedns", "gcr.io/google_containers/k8s-dns-kube-dns-amd64:1.14.7"),
+			},
+			expectedName: "custom-kube-dns",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			actual := selectDNSDeployment(test.deployments)
+			if test.expectedName == "" {
+				assert.Nil(t, actual)
+				return
+			}
+
+			assert.Equal(t, test.expectedName, actual.Name)
+		})
+	}
+}
+
+func buildDNSDeployment(name, containerName, image string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: metav1.NamespaceSystem,
+			Labels: map[string]string{
+				"k8s-app": "kube-dns",
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  containerName,
+							Image: image,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestCheckCoreDNSVersion(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		image             string
+		supportedVersions string
+		expectedError     bool
+	}{
+		{
+			desc:          "supported version",
+			image:         "coredns/coredns:1.6.3",
+			expectedError: false,
+		},
+		{
+			desc:          "supported version with v prefix",
+			image:         "k8s.gcr.io/coredns:v1.3.1",
+			expectedError: false,
+		},
+		{
+			desc:          "too old version",
+			image:         "coredns/coredns:1.2.6",
+			expectedError: true,
+		},
+		{
+			desc:          "1.30 is not 1.3",
+			image:         "coredns/coredns:1.30.0",
+			expectedError: true,
+		},
+		{
+			desc:          "vendor suffix containing a supported version",
+			image:         "coredns/coredns:1.7.0-eks-1.13",
+			expectedError: true,
+		},
+		{
+			desc:          "vendor suffix",
+			image:         "602401143452.dkr.ecr.us-west-2.amazonaws.com/eks/coredns:v1.6.6-eksbuild.1",
+			expectedError: false,
+		},
+		{
+			desc:          "registry with port and digest",
+			image:         "registry.local:5000/coredns/coredns:1.5.2@sha256:7ec975f167d815311a7136c32e70735f0d00b73781365df1befd46ed35bd4fe7",
+			expectedError: false,
+		},
+		{
+			desc:          "digest only",
+			image:         "coredns/coredns@sha256:7ec975f167d815311a7136c32e70735f0d00b73781365df1befd46ed35bd4fe7",
+			expectedError: true,
+		},
+		{
+			desc:          "no version in tag",
+			image:         "coredns/coredns:latest",
+			expectedError: true,
+		},
+		{
+			desc:              "custom supported versions",
+			image:             "coredns/coredns:1.8.0",
+			supportedVersions: ">= 1.3, < 1.9",
+			expectedError:     false,
+		},
+		{
+			desc:              "invalid supported versions",
+			image:             "coredns/coredns:1.6.3",
+			supportedVersions: "foo",
+			expectedError:     true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkCoreDNSVersion(test.image, test.supportedVersions)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
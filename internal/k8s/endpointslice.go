@@ -0,0 +1,392 @@
+package k8s
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// LabelEndpointSliceServiceName is the label holding the name of the service of an EndpointSlice.
+	LabelEndpointSliceServiceName = "kubernetes.io/service-name"
+
+	// endpointSliceServiceIndex indexes the EndpointSlices by service namespace and name.
+	endpointSliceServiceIndex = "service"
+
+	endpointSliceKind = "EndpointSlice"
+)
+
+// endpointSliceResources are the supported EndpointSlice resources, by order of preference.
+var endpointSliceResources = []schema.GroupVersionResource{
+	{Group: "discovery.k8s.io", Version: "v1", Resource: "endpointslices"},
+	{Group: "discovery.k8s.io", Version: "v1beta1", Resource: "endpointslices"},
+}
+
+// endpointSlice holds the fields of an EndpointSlice used to build the endpoints of a service.
+// They are common to all the supported versions of the resource.
+type endpointSlice struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	AddressType string                  `json:"addressType"`
+	Endpoints   []endpointSliceEndpoint `json:"endpoints"`
+	Ports       []endpointSlicePort     `json:"ports"`
+}
+
+type endpointSliceEndpoint struct {
+	Addresses  []string                `json:"addresses"`
+	Conditions endpointConditions      `json:"conditions,omitempty"`
+	Hostname   *string                 `json:"hostname,omitempty"`
+	NodeName   *string                 `json:"nodeName,omitempty"`
+	TargetRef  *corev1.ObjectReference `json:"targetRef,omitempty"`
+}
+
+type endpointConditions struct {
+	Ready       *bool `json:"ready,omitempty"`
+	Terminating *bool `json:"terminating,omitempty"`
+}
+
+type endpointSlicePort struct {
+	Name     *string          `json:"name,omitempty"`
+	Protocol *corev1.Protocol `json:"protocol,omitempty"`
+	Port     *int32           `json:"port,omitempty"`
+}
+
+// GetEndpointSliceResource returns the preferred EndpointSlice resource served by the cluster, if any.
+func GetEndpointSliceResource(client discovery.DiscoveryInterface) (schema.GroupVersionResource, bool) {
+	for _, resource := range endpointSliceResources {
+		resourceList, err := client.ServerResourcesForGroupVersion(resource.GroupVersion().String())
+		if err != nil {
+			continue
+		}
+
+		for _, apiResource := range resourceList.APIResources {
+			if apiResource.Name == resource.Resource {
+				return resource, true
+			}
+		}
+	}
+
+	return schema.GroupVersionResource{}, false
+}
+
+// EndpointSliceInformers watches the EndpointSlices of the watched namespaces.
+type EndpointSliceInformers struct {
+	resource  schema.GroupVersionResource
+	factories map[string]dynamicinformer.DynamicSharedInformerFactory
+}
+
+// NewEndpointSliceInformers creates the EndpointSlice informers of the given namespaces, or all of them if empty.
+func NewEndpointSliceInformers(client dynamic.Interface, resource schema.GroupVersionResource, namespaces []string, defaultResync time.Duration) (*EndpointSliceInformers, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	i := &EndpointSliceInformers{
+		resource:  resource,
+		factories: make(map[string]dynamicinformer.DynamicSharedInformerFactory),
+	}
+
+	for _, namespace := range namespaces {
+		if _, exists := i.factories[namespace]; exists {
+			continue
+		}
+
+		factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, defaultResync, namespace, nil)
+
+		err := factory.ForResource(resource).Informer().AddIndexers(cache.Indexers{
+			endpointSliceServiceIndex: endpointSliceServiceIndexFunc,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to add EndpointSlice indexers: %v", err)
+		}
+
+		i.factories[namespace] = factory
+	}
+
+	return i, nil
+}
+
+// AddEventHandler registers the handler on the EndpointSlice informers.
+func (i *EndpointSliceInformers) AddEventHandler(handler cache.ResourceEventHandler) {
+	for _, factory := range i.factories {
+		factory.ForResource(i.resource).Informer().AddEventHandler(handler)
+	}
+}
+
+// Lister returns an EndpointsLister building the endpoints of the services from their EndpointSlices.
+func (i *EndpointSliceInformers) Lister() listers.EndpointsLister {
+	l := make(endpointSliceLister)
+	for namespace, factory := range i.factories {
+		l[namespace] = factory.ForResource(i.resource).Informer().GetIndexer()
+	}
+
+	return l
+}
+
+// Start starts the EndpointSlice informers.
+func (i *EndpointSliceInformers) Start(stopCh <-chan struct{}) {
+	for _, factory := range i.factories {
+		factory.Start(stopCh)
+	}
+}
+
+// WaitForCacheSync waits for the caches of all the EndpointSlice informers to be synced.
+func (i *EndpointSliceInformers) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	synced := true
+
+	for _, factory := range i.factories {
+		for _, ok := range factory.WaitForCacheSync(stopCh) {
+			synced = synced && ok
+		}
+	}
+
+	return map[reflect.Type]bool{reflect.TypeOf(endpointSlice{}): synced}
+}
+
+// IsEndpointSlice returns true if the object is an EndpointSlice.
+func IsEndpointSlice(obj *unstructured.Unstructured) bool {
+	return obj.GetKind() == endpointSliceKind
+}
+
+func endpointSliceServiceIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := metaAccessor(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := meta.GetLabels()[LabelEndpointSliceServiceName]
+	if serviceName == "" {
+		return nil, nil
+	}
+
+	return []string{meta.GetNamespace() + "/" + serviceName}, nil
+}
+
+func metaAccessor(obj interface{}) (metav1.Object, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	meta, ok := obj.(metav1.Object)
+	if !ok {
+		return nil, fmt.Errorf("object has no meta: %v", obj)
+	}
+
+	return meta, nil
+}
+
+// endpointSliceLister is an EndpointsLister over the EndpointSlice indexers of the watched namespaces.
+type endpointSliceLister map[string]cache.Indexer
+
+func (l endpointSliceLister) List(selector labels.Selector) ([]*corev1.Endpoints, error) {
+	return l.Endpoints(metav1.NamespaceAll).List(selector)
+}
+
+func (l endpointSliceLister) Endpoints(namespace string) listers.EndpointsNamespaceLister {
+	return endpointSliceNamespaceLister{indexers: l, namespace: namespace}
+}
+
+type endpointSliceNamespaceLister struct {
+	indexers  endpointSliceLister
+	namespace string
+}
+
+// List builds the endpoints of all the services with EndpointSlices matching the selector.
+func (l endpointSliceNamespaceLister) List(selector labels.Selector) ([]*corev1.Endpoints, error) {
+	slicesByService := make(map[string][]*endpointSlice)
+
+	var keys []string
+
+	for namespace, indexer := range l.indexers {
+		if l.namespace != metav1.NamespaceAll && namespace != metav1.NamespaceAll && namespace != l.namespace {
+			continue
+		}
+
+		objs := indexer.List()
+		if l.namespace != metav1.NamespaceAll {
+			var err error
+
+			objs, err = indexer.ByIndex(cache.NamespaceIndex, l.namespace)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, obj := range objs {
+			slice, err := toEndpointSlice(obj)
+			if err != nil {
+				return nil, err
+			}
+
+			serviceName := slice.Labels[LabelEndpointSliceServiceName]
+			if serviceName == "" || !selector.Matches(labels.Set(slice.Labels)) {
+				continue
+			}
+
+			key := slice.Namespace + "/" + serviceName
+			if _, exists := slicesByService[key]; !exists {
+				keys = append(keys, key)
+			}
+
+			slicesByService[key] = append(slicesByService[key], slice)
+		}
+	}
+
+	var result []*corev1.Endpoints
+
+	for _, key := range keys {
+		slices := slicesByService[key]
+		result = append(result, buildEndpointsFromSlices(slices[0].Labels[LabelEndpointSliceServiceName], slices[0].Namespace, slices))
+	}
+
+	return result, nil
+}
+
+// Get builds the endpoints of the given service from its EndpointSlices.
+func (l endpointSliceNamespaceLister) Get(name string) (*corev1.Endpoints, error) {
+	var slices []*endpointSlice
+
+	for namespace, indexer := range l.indexers {
+		if namespace != metav1.NamespaceAll && namespace != l.namespace {
+			continue
+		}
+
+		objs, err := indexer.ByIndex(endpointSliceServiceIndex, l.namespace+"/"+name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, obj := range objs {
+			slice, err := toEndpointSlice(obj)
+			if err != nil {
+				return nil, err
+			}
+
+			slices = append(slices, slice)
+		}
+	}
+
+	if len(slices) == 0 {
+		return nil, kubeerror.NewNotFound(corev1.Resource("endpoints"), name)
+	}
+
+	return buildEndpointsFromSlices(name, l.namespace, slices), nil
+}
+
+func toEndpointSlice(obj interface{}) (*endpointSlice, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected EndpointSlice object type %T", obj)
+	}
+
+	slice := &endpointSlice{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, slice); err != nil {
+		return nil, fmt.Errorf("unable to convert EndpointSlice %s/%s: %v", u.GetNamespace(), u.GetName(), err)
+	}
+
+	return slice, nil
+}
+
+// buildEndpointsFromSlices builds the endpoints of a service from its EndpointSlices, one subset per slice.
+// Terminating endpoints are left out, and endpoints which are not ready are added to the not ready addresses.
+// The subsets are sorted by slice name and their addresses by IP, as the slices are indexed in no particular order
+// and the same endpoints must build the same configuration.
+func buildEndpointsFromSlices(name, namespace string, slices []*endpointSlice) *corev1.Endpoints {
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+
+	sorted := make([]*endpointSlice, len(slices))
+	copy(sorted, slices)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	for _, slice := range sorted {
+		// Only IPv4 addresses are supported by the mesh nodes, "IP" is the IPv4 address type of the v1alpha1 API.
+		if slice.AddressType != "IPv4" && slice.AddressType != "IP" {
+			continue
+		}
+
+		var subset corev1.EndpointSubset
+
+		for _, port := range slice.Ports {
+			// A nil port means all ports, which can't be used to build servers.
+			if port.Port == nil {
+				continue
+			}
+
+			endpointPort := corev1.EndpointPort{Port: *port.Port, Protocol: corev1.ProtocolTCP}
+			if port.Name != nil {
+				endpointPort.Name = *port.Name
+			}
+
+			if port.Protocol != nil {
+				endpointPort.Protocol = *port.Protocol
+			}
+
+			subset.Ports = append(subset.Ports, endpointPort)
+		}
+
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Terminating != nil && *endpoint.Conditions.Terminating {
+				continue
+			}
+
+			for _, ip := range endpoint.Addresses {
+				address := corev1.EndpointAddress{
+					IP:        ip,
+					NodeName:  endpoint.NodeName,
+					TargetRef: endpoint.TargetRef,
+				}
+
+				if endpoint.Hostname != nil {
+					address.Hostname = *endpoint.Hostname
+				}
+
+				// A nil ready condition must be interpreted as ready.
+				if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+					subset.NotReadyAddresses = append(subset.NotReadyAddresses, address)
+					continue
+				}
+
+				subset.Addresses = append(subset.Addresses, address)
+			}
+		}
+
+		if len(subset.Ports) == 0 || len(subset.Addresses)+len(subset.NotReadyAddresses) == 0 {
+			continue
+		}
+
+		sortAddresses(subset.Addresses)
+		sortAddresses(subset.NotReadyAddresses)
+
+		endpoints.Subsets = append(endpoints.Subsets, subset)
+	}
+
+	return endpoints
+}
+
+func sortAddresses(addresses []corev1.EndpointAddress) {
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].IP < addresses[j].IP
+	})
+}
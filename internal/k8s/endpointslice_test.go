@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestGetEndpointSliceResource(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		resources        []*metav1.APIResourceList
+		expected         schema.GroupVersionResource
+		expectedSupports bool
+	}{
+		{
+			desc:             "no EndpointSlice API",
+			resources:        []*metav1.APIResourceList{{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "endpoints"}}}},
+			expectedSupports: false,
+		},
+		{
+			desc:             "v1beta1 API",
+			resources:        []*metav1.APIResourceList{{GroupVersion: "discovery.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "endpointslices"}}}},
+			expected:         schema.GroupVersionResource{Group: "discovery.k8s.io", Version: "v1beta1", Resource: "endpointslices"},
+			expectedSupports: true,
+		},
+		{
+			desc: "v1 API is preferred",
+			resources: []*metav1.APIResourceList{
+				{GroupVersion: "discovery.k8s.io/v1beta1", APIResources: []metav1.APIResource{{Name: "endpointslices"}}},
+				{GroupVersion: "discovery.k8s.io/v1", APIResources: []metav1.APIResource{{Name: "endpointslices"}}},
+			},
+			expected:         schema.GroupVersionResource{Group: "discovery.k8s.io", Version: "v1", Resource: "endpointslices"},
+			expectedSupports: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewSimpleClientset()
+			client.Discovery().(*fakediscovery.FakeDiscovery).Resources = test.resources
+
+			actual, supported := GetEndpointSliceResource(client.Discovery())
+			assert.Equal(t, test.expectedSupports, supported)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestEndpointSliceLister(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		cache.NamespaceIndex:      cache.MetaNamespaceIndexFunc,
+		endpointSliceServiceIndex: endpointSliceServiceIndexFunc,
+	})
+
+	slices := []*unstructured.Unstructured{
+		buildEndpointSlice("foo-abcde", "bar", "foo", "IPv4", []interface{}{
+			buildSliceEndpoint("10.0.0.1", nil, nil),
+			buildSliceEndpoint("10.0.0.2", false, nil),
+			buildSliceEndpoint("10.0.0.3", true, true),
+		}),
+		buildEndpointSlice("foo-fghij", "bar", "foo", "IPv4", []interface{}{
+			buildSliceEndpoint("10.0.0.4", true, false),
+		}),
+		buildEndpointSlice("foo-klmno", "bar", "foo", "IPv6", []interface{}{
+			buildSliceEndpoint("fd00::1", true, nil),
+		}),
+		buildEndpointSlice("foo-abcde", "other", "foo", "IPv4", []interface{}{
+			buildSliceEndpoint("10.0.1.1", true, nil),
+		}),
+	}
+
+	for _, slice := range slices {
+		require.NoError(t, indexer.Add(slice))
+	}
+
+	lister := endpointSliceLister{metav1.NamespaceAll: indexer}
+
+	endpoints, err := lister.Endpoints("bar").Get("foo")
+	require.NoError(t, err)
+
+	expected := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+		Subsets: []corev1.EndpointSubset{
+			{
+				Addresses:         []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+				NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}},
+				Ports:             []corev1.EndpointPort{{Name: "http", Port: 8080, Protocol: corev1.ProtocolTCP}},
+			},
+			{
+				Addresses: []corev1.EndpointAddress{{IP: "10.0.0.4"}},
+				Ports:     []corev1.EndpointPort{{Name: "http", Port: 8080, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+	}
+
+	assert.Equal(t, expected, endpoints)
+
+	_, err = lister.Endpoints("bar").Get("baz")
+	assert.True(t, kubeerror.IsNotFound(err))
+
+	list, err := lister.List(labels.Everything())
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	list, err = lister.Endpoints("other").List(labels.Everything())
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "other", list[0].Namespace)
+}
+
+func TestBuildEndpointsFromSlicesIsStable(t *testing.T) {
+	ready := true
+	port := int32(8080)
+
+	first := &endpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Name: "foo-abcde", Namespace: "bar"},
+		AddressType: "IPv4",
+		Endpoints: []endpointSliceEndpoint{
+			{Addresses: []string{"10.0.0.3"}, Conditions: endpointConditions{Ready: &ready}},
+			{Addresses: []string{"10.0.0.1"}, Conditions: endpointConditions{Ready: &ready}},
+		},
+		Ports: []endpointSlicePort{{Port: &port}},
+	}
+	second := &endpointSlice{
+		ObjectMeta:  metav1.ObjectMeta{Name: "foo-fghij", Namespace: "bar"},
+		AddressType: "IPv4",
+		Endpoints: []endpointSliceEndpoint{
+			{Addresses: []string{"10.0.0.4"}, Conditions: endpointConditions{Ready: &ready}},
+			{Addresses: []string{"10.0.0.2"}, Conditions: endpointConditions{Ready: &ready}},
+		},
+		Ports: []endpointSlicePort{{Port: &port}},
+	}
+
+	expected := []corev1.EndpointSubset{
+		{
+			Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}, {IP: "10.0.0.3"}},
+			Ports:     []corev1.EndpointPort{{Port: 8080, Protocol: corev1.ProtocolTCP}},
+		},
+		{
+			Addresses: []corev1.EndpointAddress{{IP: "10.0.0.2"}, {IP: "10.0.0.4"}},
+			Ports:     []corev1.EndpointPort{{Port: 8080, Protocol: corev1.ProtocolTCP}},
+		},
+	}
+
+	slices := []*endpointSlice{second, first}
+
+	assert.Equal(t, expected, buildEndpointsFromSlices("foo", "bar", []*endpointSlice{first, second}).Subsets)
+	assert.Equal(t, expected, buildEndpointsFromSlices("foo", "bar", slices).Subsets)
+
+	// The slices given are left untouched.
+	assert.Equal(t, []*endpointSlice{second, first}, slices)
+}
+
+func buildEndpointSlice(name, namespace, serviceName, addressType string, endpoints []interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "discovery.k8s.io/v1",
+			"kind":       "EndpointSlice",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+				"labels": map[string]interface{}{
+					LabelEndpointSliceServiceName: serviceName,
+				},
+			},
+			"addressType": addressType,
+			"endpoints":   endpoints,
+			"ports": []interface{}{
+				map[string]interface{}{
+					"name":     "http",
+					"port":     int64(8080),
+					"protocol": "TCP",
+				},
+			},
+		},
+	}
+}
+
+func buildSliceEndpoint(ip string, ready, terminating interface{}) map[string]interface{} {
+	conditions := map[string]interface{}{}
+
+	if ready != nil {
+		conditions["ready"] = ready
+	}
+
+	if terminating != nil {
+		conditions["terminating"] = terminating
+	}
+
+	return map[string]interface{}{
+		"addresses":  []interface{}{ip},
+		"conditions": conditions,
+	}
+}
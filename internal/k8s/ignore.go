@@ -1,27 +1,53 @@
 package k8s
 
 import (
+	log "github.com/sirupsen/logrus"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
+	listers "k8s.io/client-go/listers/core/v1"
 )
 
-// IgnoreWrapper holds namespaces and services to ignore.
+// IgnoreWrapper holds namespaces and services to ignore, and the selectors of the services part of the mesh.
 type IgnoreWrapper struct {
 	Namespaces Namespaces
 	Services   Services
 	Apps       []string
+	// NamespaceSelector selects the namespaces whose services are part of the mesh.
+	// NamespaceLister must be set if it is not empty.
+	NamespaceSelector labels.Selector
+	// ServiceSelector selects the services part of the mesh.
+	ServiceSelector labels.Selector
+	NamespaceLister listers.NamespaceLister
 }
 
 // NewIgnored returns a new IgnoreWrapper.
 func NewIgnored() IgnoreWrapper {
 	return IgnoreWrapper{
-		Namespaces: Namespaces{},
-		Services:   Services{},
-		Apps:       []string{},
+		Namespaces:        Namespaces{},
+		Services:          Services{},
+		Apps:              []string{},
+		NamespaceSelector: labels.Everything(),
+		ServiceSelector:   labels.Everything(),
 	}
 }
 
+// NewDefaultIgnored returns an IgnoreWrapper ignoring the given namespaces, and the objects never part of the mesh:
+// the kubernetes service, the kube-system namespace, and the maesh and jaeger apps.
+func NewDefaultIgnored(namespaces []string) IgnoreWrapper {
+	ignored := NewIgnored()
+
+	for _, ns := range namespaces {
+		ignored.AddIgnoredNamespace(ns)
+	}
+
+	ignored.AddIgnoredService("kubernetes", metav1.NamespaceDefault)
+	ignored.AddIgnoredNamespace(metav1.NamespaceSystem)
+	ignored.AddIgnoredApps("maesh", "jaeger")
+
+	return ignored
+}
+
 // AddIgnoredNamespace adds a namespace to the list of ignored namespaces.
 func (i *IgnoreWrapper) AddIgnoredNamespace(namespace string) {
 	i.Namespaces = append(i.Namespaces, namespace)
@@ -68,7 +94,40 @@ func (i *IgnoreWrapper) IsIgnored(obj metav1.ObjectMeta) bool {
 		return true
 	}
 
-	return false
+	// Is the object explicitly enabled or disabled?
+	switch obj.GetAnnotations()[AnnotationEnabled] {
+	case "true":
+		return false
+	case "false":
+		return true
+	}
+
+	// Is the object selected?
+	if i.ServiceSelector != nil && !i.ServiceSelector.Matches(labels.Set(obj.GetLabels())) {
+		return true
+	}
+
+	return !i.isNamespaceSelected(obj.GetNamespace())
+}
+
+// isNamespaceSelected returns true if the namespace labels match the namespace selector.
+func (i *IgnoreWrapper) isNamespaceSelected(name string) bool {
+	if i.NamespaceSelector == nil || i.NamespaceSelector.Empty() {
+		return true
+	}
+
+	if i.NamespaceLister == nil {
+		log.Errorf("Unable to check if namespace %q is selected: no namespace lister", name)
+		return false
+	}
+
+	namespace, err := i.NamespaceLister.Get(name)
+	if err != nil {
+		log.Debugf("Unable to get namespace %q: %v", name, err)
+		return false
+	}
+
+	return i.NamespaceSelector.Matches(labels.Set(namespace.Labels))
 }
 
 // IsIgnoredNamespace returns if the service's events should be ignored.
@@ -4,7 +4,12 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestIgnoredNamespace(t *testing.T) {
@@ -99,6 +104,17 @@ func TestIgnoredService(t *testing.T) {
 	}
 }
 
+func TestNewDefaultIgnored(t *testing.T) {
+	ignored := NewDefaultIgnored([]string{"foo"})
+
+	assert.True(t, ignored.IsIgnored(buildMeta("bar", "foo", "")))
+	assert.True(t, ignored.IsIgnored(buildMeta("bar", metav1.NamespaceSystem, "")))
+	assert.True(t, ignored.IsIgnored(buildMeta("kubernetes", metav1.NamespaceDefault, "")))
+	assert.True(t, ignored.IsIgnored(buildMeta("bar", metav1.NamespaceDefault, "maesh")))
+	assert.True(t, ignored.IsIgnored(buildMeta("bar", metav1.NamespaceDefault, "jaeger")))
+	assert.False(t, ignored.IsIgnored(buildMeta("bar", metav1.NamespaceDefault, "")))
+}
+
 func buildMeta(name, ns, app string) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Name:      name,
@@ -108,3 +124,105 @@ func buildMeta(name, ns, app string) metav1.ObjectMeta {
 		},
 	}
 }
+
+func TestIgnoredMembership(t *testing.T) {
+	namespaceInformer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Core().V1().Namespaces()
+
+	for _, namespace := range []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "meshed", Labels: map[string]string{"maesh": "enabled"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "notmeshed"}},
+	} {
+		err := namespaceInformer.Informer().GetIndexer().Add(namespace)
+		require.NoError(t, err)
+	}
+
+	testCases := []struct {
+		desc              string
+		namespaceSelector string
+		serviceSelector   string
+		meta              metav1.ObjectMeta
+		expected          bool
+	}{
+		{
+			desc:     "no selectors",
+			meta:     buildMeta("foo", "notmeshed", "foo"),
+			expected: false,
+		},
+		{
+			desc:              "selected namespace",
+			namespaceSelector: "maesh=enabled",
+			meta:              buildMeta("foo", "meshed", "foo"),
+			expected:          false,
+		},
+		{
+			desc:              "not selected namespace",
+			namespaceSelector: "maesh=enabled",
+			meta:              buildMeta("foo", "notmeshed", "foo"),
+			expected:          true,
+		},
+		{
+			desc:              "unknown namespace",
+			namespaceSelector: "maesh=enabled",
+			meta:              buildMeta("foo", "unknown", "foo"),
+			expected:          true,
+		},
+		{
+			desc:            "selected service",
+			serviceSelector: "app=foo",
+			meta:            buildMeta("foo", "notmeshed", "foo"),
+			expected:        false,
+		},
+		{
+			desc:            "not selected service",
+			serviceSelector: "app=bar",
+			meta:            buildMeta("foo", "notmeshed", "foo"),
+			expected:        true,
+		},
+		{
+			desc:              "enabled annotation overrides selectors",
+			namespaceSelector: "maesh=enabled",
+			serviceSelector:   "app=bar",
+			meta:              buildMetaWithEnabled("foo", "notmeshed", "foo", "true"),
+			expected:          false,
+		},
+		{
+			desc:     "disabled annotation",
+			meta:     buildMetaWithEnabled("foo", "meshed", "foo", "false"),
+			expected: true,
+		},
+		{
+			desc:     "enabled annotation does not override ignored apps",
+			meta:     buildMetaWithEnabled("foo", "meshed", "ignoredapp", "true"),
+			expected: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			i := NewIgnored()
+			i.AddIgnoredApps("ignoredapp")
+			i.NamespaceLister = namespaceInformer.Lister()
+
+			var err error
+			i.NamespaceSelector, err = labels.Parse(test.namespaceSelector)
+			require.NoError(t, err)
+
+			i.ServiceSelector, err = labels.Parse(test.serviceSelector)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, i.IsIgnored(test.meta))
+		})
+	}
+}
+
+func buildMetaWithEnabled(name, ns, app, enabled string) metav1.ObjectMeta {
+	meta := buildMeta(name, ns, app)
+	meta.Annotations = map[string]string{
+		AnnotationEnabled: enabled,
+	}
+
+	return meta
+}
@@ -0,0 +1,270 @@
+package k8s
+
+import (
+	"reflect"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NamespacedInformerFactories holds an informer factory per watched namespace.
+// A single factory keyed by metav1.NamespaceAll is used when all the namespaces are watched.
+type NamespacedInformerFactories map[string]informers.SharedInformerFactory
+
+// NewNamespacedInformerFactories creates the informer factories watching the given namespaces, or all of them if empty.
+func NewNamespacedInformerFactories(client kubernetes.Interface, namespaces []string, defaultResync time.Duration) NamespacedInformerFactories {
+	if len(namespaces) == 0 {
+		return NamespacedInformerFactories{
+			metav1.NamespaceAll: informers.NewSharedInformerFactoryWithOptions(client, defaultResync),
+		}
+	}
+
+	factories := make(NamespacedInformerFactories)
+
+	for _, namespace := range namespaces {
+		if _, exists := factories[namespace]; exists {
+			continue
+		}
+
+		factories[namespace] = informers.NewSharedInformerFactoryWithOptions(client, defaultResync, informers.WithNamespace(namespace))
+	}
+
+	return factories
+}
+
+// AddServiceEventHandler registers the handler on the service informers.
+func (f NamespacedInformerFactories) AddServiceEventHandler(handler cache.ResourceEventHandler) {
+	for _, factory := range f {
+		factory.Core().V1().Services().Informer().AddEventHandler(handler)
+	}
+}
+
+// AddEndpointsEventHandler registers the handler on the endpoints informers.
+func (f NamespacedInformerFactories) AddEndpointsEventHandler(handler cache.ResourceEventHandler) {
+	for _, factory := range f {
+		factory.Core().V1().Endpoints().Informer().AddEventHandler(handler)
+	}
+}
+
+// AddPodEventHandler registers the handler on the pod informers.
+func (f NamespacedInformerFactories) AddPodEventHandler(handler cache.ResourceEventHandler) {
+	for _, factory := range f {
+		factory.Core().V1().Pods().Informer().AddEventHandler(handler)
+	}
+}
+
+// ServiceLister returns a ServiceLister over all the watched namespaces.
+func (f NamespacedInformerFactories) ServiceLister() listers.ServiceLister {
+	if factory, ok := f[metav1.NamespaceAll]; ok {
+		return factory.Core().V1().Services().Lister()
+	}
+
+	l := make(multiNamespaceServiceLister)
+	for namespace, factory := range f {
+		l[namespace] = factory.Core().V1().Services().Lister()
+	}
+
+	return l
+}
+
+// EndpointsLister returns an EndpointsLister over all the watched namespaces.
+func (f NamespacedInformerFactories) EndpointsLister() listers.EndpointsLister {
+	if factory, ok := f[metav1.NamespaceAll]; ok {
+		return factory.Core().V1().Endpoints().Lister()
+	}
+
+	l := make(multiNamespaceEndpointsLister)
+	for namespace, factory := range f {
+		l[namespace] = factory.Core().V1().Endpoints().Lister()
+	}
+
+	return l
+}
+
+// PodLister returns a PodLister over all the watched namespaces.
+func (f NamespacedInformerFactories) PodLister() listers.PodLister {
+	if factory, ok := f[metav1.NamespaceAll]; ok {
+		return factory.Core().V1().Pods().Lister()
+	}
+
+	l := make(multiNamespacePodLister)
+	for namespace, factory := range f {
+		l[namespace] = factory.Core().V1().Pods().Lister()
+	}
+
+	return l
+}
+
+// Start starts the informers of all the factories.
+func (f NamespacedInformerFactories) Start(stopCh <-chan struct{}) {
+	for _, factory := range f {
+		factory.Start(stopCh)
+	}
+}
+
+// WaitForCacheSync waits for the caches of all the factories to be synced.
+// An informer type is reported as synced only if it is synced in every namespace.
+func (f NamespacedInformerFactories) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	result := make(map[reflect.Type]bool)
+
+	for _, factory := range f {
+		for t, ok := range factory.WaitForCacheSync(stopCh) {
+			if synced, exists := result[t]; exists && !synced {
+				continue
+			}
+
+			result[t] = ok
+		}
+	}
+
+	return result
+}
+
+// newNamespaceIndexer returns an empty indexer, used to answer lookups in namespaces which are not watched.
+func newNamespaceIndexer() cache.Indexer {
+	return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
+
+// multiNamespaceServiceLister is a ServiceLister over the listers of several namespaces.
+type multiNamespaceServiceLister map[string]listers.ServiceLister
+
+func (l multiNamespaceServiceLister) List(selector labels.Selector) ([]*corev1.Service, error) {
+	var result []*corev1.Service
+
+	for _, lister := range l {
+		services, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, services...)
+	}
+
+	return result, nil
+}
+
+func (l multiNamespaceServiceLister) Services(namespace string) listers.ServiceNamespaceLister {
+	if namespace == metav1.NamespaceAll {
+		return allNamespacesServiceLister{lister: l}
+	}
+
+	if lister, ok := l[namespace]; ok {
+		return lister.Services(namespace)
+	}
+
+	return listers.NewServiceLister(newNamespaceIndexer()).Services(namespace)
+}
+
+func (l multiNamespaceServiceLister) GetPodServices(pod *corev1.Pod) ([]*corev1.Service, error) {
+	if lister, ok := l[pod.Namespace]; ok {
+		return lister.GetPodServices(pod)
+	}
+
+	return nil, nil
+}
+
+// allNamespacesServiceLister lists the services of all the watched namespaces.
+type allNamespacesServiceLister struct {
+	lister multiNamespaceServiceLister
+}
+
+func (l allNamespacesServiceLister) List(selector labels.Selector) ([]*corev1.Service, error) {
+	return l.lister.List(selector)
+}
+
+func (l allNamespacesServiceLister) Get(name string) (*corev1.Service, error) {
+	return nil, kubeerror.NewNotFound(corev1.Resource("services"), name)
+}
+
+// multiNamespaceEndpointsLister is an EndpointsLister over the listers of several namespaces.
+type multiNamespaceEndpointsLister map[string]listers.EndpointsLister
+
+func (l multiNamespaceEndpointsLister) List(selector labels.Selector) ([]*corev1.Endpoints, error) {
+	var result []*corev1.Endpoints
+
+	for _, lister := range l {
+		endpoints, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, endpoints...)
+	}
+
+	return result, nil
+}
+
+func (l multiNamespaceEndpointsLister) Endpoints(namespace string) listers.EndpointsNamespaceLister {
+	if namespace == metav1.NamespaceAll {
+		return allNamespacesEndpointsLister{lister: l}
+	}
+
+	if lister, ok := l[namespace]; ok {
+		return lister.Endpoints(namespace)
+	}
+
+	return listers.NewEndpointsLister(newNamespaceIndexer()).Endpoints(namespace)
+}
+
+// allNamespacesEndpointsLister lists the endpoints of all the watched namespaces.
+type allNamespacesEndpointsLister struct {
+	lister multiNamespaceEndpointsLister
+}
+
+func (l allNamespacesEndpointsLister) List(selector labels.Selector) ([]*corev1.Endpoints, error) {
+	return l.lister.List(selector)
+}
+
+func (l allNamespacesEndpointsLister) Get(name string) (*corev1.Endpoints, error) {
+	return nil, kubeerror.NewNotFound(corev1.Resource("endpoints"), name)
+}
+
+// multiNamespacePodLister is a PodLister over the listers of several namespaces.
+type multiNamespacePodLister map[string]listers.PodLister
+
+func (l multiNamespacePodLister) List(selector labels.Selector) ([]*corev1.Pod, error) {
+	var result []*corev1.Pod
+
+	for _, lister := range l {
+		pods, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, pods...)
+	}
+
+	return result, nil
+}
+
+func (l multiNamespacePodLister) Pods(namespace string) listers.PodNamespaceLister {
+	if namespace == metav1.NamespaceAll {
+		return allNamespacesPodLister{lister: l}
+	}
+
+	if lister, ok := l[namespace]; ok {
+		return lister.Pods(namespace)
+	}
+
+	return listers.NewPodLister(newNamespaceIndexer()).Pods(namespace)
+}
+
+// allNamespacesPodLister lists the pods of all the watched namespaces.
+type allNamespacesPodLister struct {
+	lister multiNamespacePodLister
+}
+
+func (l allNamespacesPodLister) List(selector labels.Selector) ([]*corev1.Pod, error) {
+	return l.lister.List(selector)
+}
+
+func (l allNamespacesPodLister) Get(name string) (*corev1.Pod, error) {
+	return nil, kubeerror.NewNotFound(corev1.Resource("pods"), name)
+}
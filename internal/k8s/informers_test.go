@@ -0,0 +1,83 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNamespacedInformerFactories(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns1"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "ns2"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "baz", Namespace: "ns3"}},
+	)
+
+	testCases := []struct {
+		desc             string
+		namespaces       []string
+		expectedServices []string
+	}{
+		{
+			desc:             "all namespaces",
+			expectedServices: []string{"bar", "baz", "foo"},
+		},
+		{
+			desc:             "watched namespaces",
+			namespaces:       []string{"ns1", "ns2", "ns1"},
+			expectedServices: []string{"bar", "foo"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			factories := NewNamespacedInformerFactories(client, test.namespaces, 0)
+			serviceLister := factories.ServiceLister()
+
+			factories.Start(stopCh)
+
+			for _, ok := range factories.WaitForCacheSync(stopCh) {
+				require.True(t, ok)
+			}
+
+			services, err := serviceLister.List(labels.Everything())
+			require.NoError(t, err)
+
+			var names []string
+			for _, service := range services {
+				names = append(names, service.Name)
+			}
+
+			assert.ElementsMatch(t, test.expectedServices, names)
+
+			// Listing all the namespaces lists the watched ones.
+			services, err = serviceLister.Services(metav1.NamespaceAll).List(labels.Everything())
+			require.NoError(t, err)
+			assert.Len(t, services, len(test.expectedServices))
+
+			service, err := serviceLister.Services("ns1").Get("foo")
+			require.NoError(t, err)
+			assert.Equal(t, "foo", service.Name)
+
+			_, err = serviceLister.Services("ns3").Get("baz")
+			if len(test.namespaces) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, kubeerror.IsNotFound(err))
+		})
+	}
+}
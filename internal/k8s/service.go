@@ -1,5 +1,7 @@
 package k8s
 
+import "fmt"
+
 // Service holds a combination of service name and namespace.
 type Service struct {
 	Namespace string
@@ -31,3 +33,8 @@ func (s Services) Contains(name, namespace string) bool {
 
 	return false
 }
+
+// MeshServiceName returns the name of the mesh service associated to a user service.
+func MeshServiceName(meshNamespace, serviceName, serviceNamespace string) string {
+	return fmt.Sprintf("%s-%s-6d61657368-%s", meshNamespace, serviceName, serviceNamespace)
+}
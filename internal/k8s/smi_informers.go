@@ -0,0 +1,331 @@
+package k8s
+
+import (
+	"reflect"
+	"time"
+
+	access "github.com/deislabs/smi-sdk-go/pkg/apis/access/v1alpha1"
+	specs "github.com/deislabs/smi-sdk-go/pkg/apis/specs/v1alpha1"
+	split "github.com/deislabs/smi-sdk-go/pkg/apis/split/v1alpha2"
+	accessInformer "github.com/deislabs/smi-sdk-go/pkg/gen/client/access/informers/externalversions"
+	accessLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/access/listers/access/v1alpha1"
+	specsInformer "github.com/deislabs/smi-sdk-go/pkg/gen/client/specs/informers/externalversions"
+	specsLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/specs/listers/specs/v1alpha1"
+	splitInformer "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/informers/externalversions"
+	splitLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/listers/split/v1alpha2"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// SMIInformerFactories holds the SMI informer factories of a namespace.
+type SMIInformerFactories struct {
+	Access accessInformer.SharedInformerFactory
+	Specs  specsInformer.SharedInformerFactory
+	Split  splitInformer.SharedInformerFactory
+}
+
+// NamespacedSMIInformerFactories holds the SMI informer factories per watched namespace.
+// A single entry keyed by metav1.NamespaceAll is used when all the namespaces are watched.
+type NamespacedSMIInformerFactories map[string]SMIInformerFactories
+
+// NewNamespacedSMIInformerFactories creates the SMI informer factories watching the given namespaces, or all of them if empty.
+func NewNamespacedSMIInformerFactories(clients *ClientWrapper, namespaces []string, defaultResync time.Duration) NamespacedSMIInformerFactories {
+	if len(namespaces) == 0 {
+		return NamespacedSMIInformerFactories{
+			metav1.NamespaceAll: {
+				Access: accessInformer.NewSharedInformerFactoryWithOptions(clients.SmiAccessClient, defaultResync),
+				Specs:  specsInformer.NewSharedInformerFactoryWithOptions(clients.SmiSpecsClient, defaultResync),
+				Split:  splitInformer.NewSharedInformerFactoryWithOptions(clients.SmiSplitClient, defaultResync),
+			},
+		}
+	}
+
+	factories := make(NamespacedSMIInformerFactories)
+
+	for _, namespace := range namespaces {
+		if _, exists := factories[namespace]; exists {
+			continue
+		}
+
+		factories[namespace] = SMIInformerFactories{
+			Access: accessInformer.NewSharedInformerFactoryWithOptions(clients.SmiAccessClient, defaultResync, accessInformer.WithNamespace(namespace)),
+			Specs:  specsInformer.NewSharedInformerFactoryWithOptions(clients.SmiSpecsClient, defaultResync, specsInformer.WithNamespace(namespace)),
+			Split:  splitInformer.NewSharedInformerFactoryWithOptions(clients.SmiSplitClient, defaultResync, splitInformer.WithNamespace(namespace)),
+		}
+	}
+
+	return factories
+}
+
+// AddEventHandler registers the handler on the TrafficTarget, HTTPRouteGroup, TCPRoute and TrafficSplit informers.
+func (f NamespacedSMIInformerFactories) AddEventHandler(handler cache.ResourceEventHandler) {
+	for _, factories := range f {
+		factories.Access.Access().V1alpha1().TrafficTargets().Informer().AddEventHandler(handler)
+		factories.Specs.Specs().V1alpha1().HTTPRouteGroups().Informer().AddEventHandler(handler)
+		factories.Specs.Specs().V1alpha1().TCPRoutes().Informer().AddEventHandler(handler)
+		factories.Split.Split().V1alpha2().TrafficSplits().Informer().AddEventHandler(handler)
+	}
+}
+
+// TrafficTargetLister returns a TrafficTargetLister over all the watched namespaces.
+func (f NamespacedSMIInformerFactories) TrafficTargetLister() accessLister.TrafficTargetLister {
+	if factories, ok := f[metav1.NamespaceAll]; ok {
+		return factories.Access.Access().V1alpha1().TrafficTargets().Lister()
+	}
+
+	l := make(multiNamespaceTrafficTargetLister)
+	for namespace, factories := range f {
+		l[namespace] = factories.Access.Access().V1alpha1().TrafficTargets().Lister()
+	}
+
+	return l
+}
+
+// HTTPRouteGroupLister returns an HTTPRouteGroupLister over all the watched namespaces.
+func (f NamespacedSMIInformerFactories) HTTPRouteGroupLister() specsLister.HTTPRouteGroupLister {
+	if factories, ok := f[metav1.NamespaceAll]; ok {
+		return factories.Specs.Specs().V1alpha1().HTTPRouteGroups().Lister()
+	}
+
+	l := make(multiNamespaceHTTPRouteGroupLister)
+	for namespace, factories := range f {
+		l[namespace] = factories.Specs.Specs().V1alpha1().HTTPRouteGroups().Lister()
+	}
+
+	return l
+}
+
+// TCPRouteLister returns a TCPRouteLister over all the watched namespaces.
+func (f NamespacedSMIInformerFactories) TCPRouteLister() specsLister.TCPRouteLister {
+	if factories, ok := f[metav1.NamespaceAll]; ok {
+		return factories.Specs.Specs().V1alpha1().TCPRoutes().Lister()
+	}
+
+	l := make(multiNamespaceTCPRouteLister)
+	for namespace, factories := range f {
+		l[namespace] = factories.Specs.Specs().V1alpha1().TCPRoutes().Lister()
+	}
+
+	return l
+}
+
+// TrafficSplitLister returns a TrafficSplitLister over all the watched namespaces.
+func (f NamespacedSMIInformerFactories) TrafficSplitLister() splitLister.TrafficSplitLister {
+	if factories, ok := f[metav1.NamespaceAll]; ok {
+		return factories.Split.Split().V1alpha2().TrafficSplits().Lister()
+	}
+
+	l := make(multiNamespaceTrafficSplitLister)
+	for namespace, factories := range f {
+		l[namespace] = factories.Split.Split().V1alpha2().TrafficSplits().Lister()
+	}
+
+	return l
+}
+
+// Start starts the informers of all the factories.
+func (f NamespacedSMIInformerFactories) Start(stopCh <-chan struct{}) {
+	for _, factories := range f {
+		factories.Access.Start(stopCh)
+		factories.Specs.Start(stopCh)
+		factories.Split.Start(stopCh)
+	}
+}
+
+// WaitForCacheSync waits for the caches of all the factories to be synced.
+// An informer type is reported as synced only if it is synced in every namespace.
+func (f NamespacedSMIInformerFactories) WaitForCacheSync(stopCh <-chan struct{}) map[reflect.Type]bool {
+	result := make(map[reflect.Type]bool)
+
+	merge := func(synced map[reflect.Type]bool) {
+		for t, ok := range synced {
+			if previous, exists := result[t]; exists && !previous {
+				continue
+			}
+
+			result[t] = ok
+		}
+	}
+
+	for _, factories := range f {
+		merge(factories.Access.WaitForCacheSync(stopCh))
+		merge(factories.Specs.WaitForCacheSync(stopCh))
+		merge(factories.Split.WaitForCacheSync(stopCh))
+	}
+
+	return result
+}
+
+// multiNamespaceTrafficTargetLister is a TrafficTargetLister over the listers of several namespaces.
+type multiNamespaceTrafficTargetLister map[string]accessLister.TrafficTargetLister
+
+func (l multiNamespaceTrafficTargetLister) List(selector labels.Selector) ([]*access.TrafficTarget, error) {
+	var result []*access.TrafficTarget
+
+	for _, lister := range l {
+		trafficTargets, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, trafficTargets...)
+	}
+
+	return result, nil
+}
+
+func (l multiNamespaceTrafficTargetLister) TrafficTargets(namespace string) accessLister.TrafficTargetNamespaceLister {
+	if namespace == metav1.NamespaceAll {
+		return allNamespacesTrafficTargetLister{lister: l}
+	}
+
+	if lister, ok := l[namespace]; ok {
+		return lister.TrafficTargets(namespace)
+	}
+
+	return accessLister.NewTrafficTargetLister(newNamespaceIndexer()).TrafficTargets(namespace)
+}
+
+// allNamespacesTrafficTargetLister lists the TrafficTargets of all the watched namespaces.
+type allNamespacesTrafficTargetLister struct {
+	lister multiNamespaceTrafficTargetLister
+}
+
+func (l allNamespacesTrafficTargetLister) List(selector labels.Selector) ([]*access.TrafficTarget, error) {
+	return l.lister.List(selector)
+}
+
+func (l allNamespacesTrafficTargetLister) Get(name string) (*access.TrafficTarget, error) {
+	return nil, kubeerror.NewNotFound(access.Resource("traffictarget"), name)
+}
+
+// multiNamespaceHTTPRouteGroupLister is an HTTPRouteGroupLister over the listers of several namespaces.
+type multiNamespaceHTTPRouteGroupLister map[string]specsLister.HTTPRouteGroupLister
+
+func (l multiNamespaceHTTPRouteGroupLister) List(selector labels.Selector) ([]*specs.HTTPRouteGroup, error) {
+	var result []*specs.HTTPRouteGroup
+
+	for _, lister := range l {
+		routeGroups, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, routeGroups...)
+	}
+
+	return result, nil
+}
+
+func (l multiNamespaceHTTPRouteGroupLister) HTTPRouteGroups(namespace string) specsLister.HTTPRouteGroupNamespaceLister {
+	if namespace == metav1.NamespaceAll {
+		return allNamespacesHTTPRouteGroupLister{lister: l}
+	}
+
+	if lister, ok := l[namespace]; ok {
+		return lister.HTTPRouteGroups(namespace)
+	}
+
+	return specsLister.NewHTTPRouteGroupLister(newNamespaceIndexer()).HTTPRouteGroups(namespace)
+}
+
+// allNamespacesHTTPRouteGroupLister lists the HTTPRouteGroups of all the watched namespaces.
+type allNamespacesHTTPRouteGroupLister struct {
+	lister multiNamespaceHTTPRouteGroupLister
+}
+
+func (l allNamespacesHTTPRouteGroupLister) List(selector labels.Selector) ([]*specs.HTTPRouteGroup, error) {
+	return l.lister.List(selector)
+}
+
+func (l allNamespacesHTTPRouteGroupLister) Get(name string) (*specs.HTTPRouteGroup, error) {
+	return nil, kubeerror.NewNotFound(specs.Resource("httproutegroup"), name)
+}
+
+// multiNamespaceTCPRouteLister is a TCPRouteLister over the listers of several namespaces.
+type multiNamespaceTCPRouteLister map[string]specsLister.TCPRouteLister
+
+func (l multiNamespaceTCPRouteLister) List(selector labels.Selector) ([]*specs.TCPRoute, error) {
+	var result []*specs.TCPRoute
+
+	for _, lister := range l {
+		routes, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, routes...)
+	}
+
+	return result, nil
+}
+
+func (l multiNamespaceTCPRouteLister) TCPRoutes(namespace string) specsLister.TCPRouteNamespaceLister {
+	if namespace == metav1.NamespaceAll {
+		return allNamespacesTCPRouteLister{lister: l}
+	}
+
+	if lister, ok := l[namespace]; ok {
+		return lister.TCPRoutes(namespace)
+	}
+
+	return specsLister.NewTCPRouteLister(newNamespaceIndexer()).TCPRoutes(namespace)
+}
+
+// allNamespacesTCPRouteLister lists the TCPRoutes of all the watched namespaces.
+type allNamespacesTCPRouteLister struct {
+	lister multiNamespaceTCPRouteLister
+}
+
+func (l allNamespacesTCPRouteLister) List(selector labels.Selector) ([]*specs.TCPRoute, error) {
+	return l.lister.List(selector)
+}
+
+func (l allNamespacesTCPRouteLister) Get(name string) (*specs.TCPRoute, error) {
+	return nil, kubeerror.NewNotFound(specs.Resource("tcproute"), name)
+}
+
+// multiNamespaceTrafficSplitLister is a TrafficSplitLister over the listers of several namespaces.
+type multiNamespaceTrafficSplitLister map[string]splitLister.TrafficSplitLister
+
+func (l multiNamespaceTrafficSplitLister) List(selector labels.Selector) ([]*split.TrafficSplit, error) {
+	var result []*split.TrafficSplit
+
+	for _, lister := range l {
+		trafficSplits, err := lister.List(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, trafficSplits...)
+	}
+
+	return result, nil
+}
+
+func (l multiNamespaceTrafficSplitLister) TrafficSplits(namespace string) splitLister.TrafficSplitNamespaceLister {
+	if namespace == metav1.NamespaceAll {
+		return allNamespacesTrafficSplitLister{lister: l}
+	}
+
+	if lister, ok := l[namespace]; ok {
+		return lister.TrafficSplits(namespace)
+	}
+
+	return splitLister.NewTrafficSplitLister(newNamespaceIndexer()).TrafficSplits(namespace)
+}
+
+// allNamespacesTrafficSplitLister lists the TrafficSplits of all the watched namespaces.
+type allNamespacesTrafficSplitLister struct {
+	lister multiNamespaceTrafficSplitLister
+}
+
+func (l allNamespacesTrafficSplitLister) List(selector labels.Selector) ([]*split.TrafficSplit, error) {
+	return l.lister.List(selector)
+}
+
+func (l allNamespacesTrafficSplitLister) Get(name string) (*split.TrafficSplit, error) {
+	return nil, kubeerror.NewNotFound(split.Resource("trafficsplit"), name)
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"testing"
+
+	access "github.com/deislabs/smi-sdk-go/pkg/apis/access/v1alpha1"
+	fakeSMIAccess "github.com/deislabs/smi-sdk-go/pkg/gen/client/access/clientset/versioned/fake"
+	fakeSMISpecs "github.com/deislabs/smi-sdk-go/pkg/gen/client/specs/clientset/versioned/fake"
+	fakeSMISplit "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestNamespacedSMIInformerFactories(t *testing.T) {
+	clients := &ClientWrapper{
+		SmiAccessClient: fakeSMIAccess.NewSimpleClientset(
+			&access.TrafficTarget{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "ns1"}},
+			&access.TrafficTarget{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "ns2"}},
+			&access.TrafficTarget{ObjectMeta: metav1.ObjectMeta{Name: "baz", Namespace: "ns3"}},
+		),
+		SmiSpecsClient: fakeSMISpecs.NewSimpleClientset(),
+		SmiSplitClient: fakeSMISplit.NewSimpleClientset(),
+	}
+
+	testCases := []struct {
+		desc                   string
+		namespaces             []string
+		expectedTrafficTargets []string
+	}{
+		{
+			desc:                   "all namespaces",
+			expectedTrafficTargets: []string{"bar", "baz", "foo"},
+		},
+		{
+			desc:                   "watched namespaces",
+			namespaces:             []string{"ns1", "ns2", "ns1"},
+			expectedTrafficTargets: []string{"bar", "foo"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			factories := NewNamespacedSMIInformerFactories(clients, test.namespaces, 0)
+			trafficTargetLister := factories.TrafficTargetLister()
+			// The listers must be created before starting the factories, for their informers to be started.
+			factories.HTTPRouteGroupLister()
+			factories.TCPRouteLister()
+			factories.TrafficSplitLister()
+
+			factories.Start(stopCh)
+
+			synced := factories.WaitForCacheSync(stopCh)
+			assert.Len(t, synced, 4)
+
+			for _, ok := range synced {
+				require.True(t, ok)
+			}
+
+			trafficTargets, err := trafficTargetLister.TrafficTargets(metav1.NamespaceAll).List(labels.Everything())
+			require.NoError(t, err)
+
+			var names []string
+			for _, trafficTarget := range trafficTargets {
+				names = append(names, trafficTarget.Name)
+			}
+
+			assert.ElementsMatch(t, test.expectedTrafficTargets, names)
+
+			trafficTarget, err := trafficTargetLister.TrafficTargets("ns1").Get("foo")
+			require.NoError(t, err)
+			assert.Equal(t, "foo", trafficTarget.Name)
+
+			_, err = trafficTargetLister.TrafficTargets("ns3").Get("baz")
+			if len(test.namespaces) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.True(t, kubeerror.IsNotFound(err))
+		})
+	}
+}
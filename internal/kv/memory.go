@@ -0,0 +1,154 @@
+package kv
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/abronan/valkeyrie/store"
+)
+
+// MemoryStore is an in-memory KV store, implementing the operations used by the Publisher.
+// The watches and locks are not supported.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	pairs map[string][]byte
+	index uint64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		pairs: make(map[string][]byte),
+	}
+}
+
+// Put sets the value of a key.
+func (m *MemoryStore) Put(key string, value []byte, _ *store.WriteOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.index++
+	m.pairs[key] = append([]byte(nil), value...)
+
+	return nil
+}
+
+// Get returns the value of a key.
+func (m *MemoryStore) Get(key string, _ *store.ReadOptions) (*store.KVPair, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	value, ok := m.pairs[key]
+	if !ok {
+		return nil, store.ErrKeyNotFound
+	}
+
+	return &store.KVPair{Key: key, Value: value, LastIndex: m.index}, nil
+}
+
+// Delete deletes a key.
+func (m *MemoryStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.pairs[key]; !ok {
+		return store.ErrKeyNotFound
+	}
+
+	m.index++
+	delete(m.pairs, key)
+
+	return nil
+}
+
+// Exists checks if a key exists.
+func (m *MemoryStore) Exists(key string, _ *store.ReadOptions) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.pairs[key]
+
+	return ok, nil
+}
+
+// Watch is not supported.
+func (m *MemoryStore) Watch(string, <-chan struct{}, *store.ReadOptions) (<-chan *store.KVPair, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+// WatchTree is not supported.
+func (m *MemoryStore) WatchTree(string, <-chan struct{}, *store.ReadOptions) (<-chan []*store.KVPair, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+// NewLock is not supported.
+func (m *MemoryStore) NewLock(string, *store.LockOptions) (store.Locker, error) {
+	return nil, store.ErrCallNotSupported
+}
+
+// List returns the pairs under a directory, sorted by key.
+func (m *MemoryStore) List(directory string, _ *store.ReadOptions) ([]*store.KVPair, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var pairs []*store.KVPair
+
+	for key, value := range m.pairs {
+		if strings.HasPrefix(key, directory) {
+			pairs = append(pairs, &store.KVPair{Key: key, Value: value, LastIndex: m.index})
+		}
+	}
+
+	if len(pairs) == 0 {
+		return nil, store.ErrKeyNotFound
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key < pairs[j].Key
+	})
+
+	return pairs, nil
+}
+
+// DeleteTree deletes the keys under a directory.
+func (m *MemoryStore) DeleteTree(directory string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.pairs {
+		if strings.HasPrefix(key, directory) {
+			delete(m.pairs, key)
+		}
+	}
+
+	m.index++
+
+	return nil
+}
+
+// AtomicPut is not supported.
+func (m *MemoryStore) AtomicPut(string, []byte, *store.KVPair, *store.WriteOptions) (bool, *store.KVPair, error) {
+	return false, nil, store.ErrCallNotSupported
+}
+
+// AtomicDelete is not supported.
+func (m *MemoryStore) AtomicDelete(string, *store.KVPair) (bool, error) {
+	return false, store.ErrCallNotSupported
+}
+
+// Close does nothing.
+func (m *MemoryStore) Close() {}
+
+// Pairs returns a copy of the stored pairs.
+func (m *MemoryStore) Pairs() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pairs := make(map[string]string, len(m.pairs))
+	for key, value := range m.pairs {
+		pairs[key] = string(value)
+	}
+
+	return pairs
+}
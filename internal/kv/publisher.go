@@ -0,0 +1,211 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/abronan/valkeyrie/store"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	log "github.com/sirupsen/logrus"
+)
+
+// Publisher writes the configurations to a KV store, in the layout read by the Traefik KV providers.
+type Publisher struct {
+	mu      sync.Mutex
+	store   store.Store
+	rootKey string
+	// pairs are the pairs written by the last publication, nil until the first one.
+	pairs map[string]string
+}
+
+// NewPublisher returns a Publisher writing the configurations under the given root key.
+func NewPublisher(kvStore store.Store, rootKey string) *Publisher {
+	return &Publisher{
+		store:   kvStore,
+		rootKey: strings.Trim(rootKey, "/"),
+	}
+}
+
+// Publish writes the changed pairs of the configuration, then deletes the pairs of the previous configuration it does not hold anymore.
+// The pairs left under the root key by a previous controller are deleted by the first publication.
+//
+// As the stores have no transaction common to all of them, the pairs are written in the order they reference each other:
+// the removed routers are deleted first, then the services and middlewares are written before the routers using them,
+// and the other removed pairs are deleted last. A reader can still load a configuration in the middle of a publication,
+// with a router or service partially updated, or with the servers of a shrunk list left over until the end of it.
+func (p *Publisher) Publish(config *dynamic.Configuration) error {
+	pairs, err := Flatten(config, p.rootKey)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := p.pairs
+	if previous == nil {
+		previous, err = p.listPairs()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The store content is unknown after a failed publication, it is listed again by the next one.
+	p.pairs = nil
+
+	var changed, stale []string
+
+	for key, value := range pairs {
+		if previousValue, ok := previous[key]; !ok || previousValue != value {
+			changed = append(changed, key)
+		}
+	}
+
+	for key := range previous {
+		if _, ok := pairs[key]; !ok {
+			stale = append(stale, key)
+		}
+	}
+
+	sort.Strings(changed)
+	sort.Strings(stale)
+
+	if err := p.deletePairs(stale, p.isRouterKey); err != nil {
+		return err
+	}
+
+	if err := p.putPairs(pairs, changed, func(key string) bool { return !p.isRouterKey(key) }); err != nil {
+		return err
+	}
+
+	if err := p.putPairs(pairs, changed, p.isRouterKey); err != nil {
+		return err
+	}
+
+	if err := p.deletePairs(stale, func(key string) bool { return !p.isRouterKey(key) }); err != nil {
+		return err
+	}
+
+	log.Debugf("Published %d keys under %s", len(pairs), p.rootKey)
+
+	p.pairs = pairs
+
+	return nil
+}
+
+// putPairs writes the given keys matching the filter.
+func (p *Publisher) putPairs(pairs map[string]string, keys []string, filter func(key string) bool) error {
+	for _, key := range keys {
+		if !filter(key) {
+			continue
+		}
+
+		if err := p.store.Put(key, []byte(pairs[key]), nil); err != nil {
+			return fmt.Errorf("unable to put key %s: %v", key, err)
+		}
+	}
+
+	return nil
+}
+
+// deletePairs deletes the given keys matching the filter.
+func (p *Publisher) deletePairs(keys []string, filter func(key string) bool) error {
+	for _, key := range keys {
+		if !filter(key) {
+			continue
+		}
+
+		if err := p.store.Delete(key); err != nil && err != store.ErrKeyNotFound {
+			return fmt.Errorf("unable to delete key %s: %v", key, err)
+		}
+	}
+
+	return nil
+}
+
+// isRouterKey returns true if the key belongs to an HTTP, TCP or UDP router.
+func (p *Publisher) isRouterKey(key string) bool {
+	for _, protocol := range []string{"http", "tcp", "udp"} {
+		if strings.HasPrefix(key, p.rootKey+"/"+protocol+"/routers/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listPairs returns the pairs stored under the root key.
+func (p *Publisher) listPairs() (map[string]string, error) {
+	kvPairs, err := p.store.List(p.rootKey+"/", nil)
+	if err != nil && err != store.ErrKeyNotFound {
+		return nil, fmt.Errorf("unable to list keys under %s: %v", p.rootKey, err)
+	}
+
+	// Depending on the store, the listed keys have a leading slash or not.
+	pairs := make(map[string]string, len(kvPairs))
+	for _, pair := range kvPairs {
+		pairs[strings.TrimPrefix(pair.Key, "/")] = string(pair.Value)
+	}
+
+	return pairs, nil
+}
+
+// Flatten converts a configuration to the KV pairs read by the Traefik KV providers, under the given root key.
+// The keys are the JSON field names, the slice elements are keyed by their index, and the empty objects are set to true.
+func Flatten(config *dynamic.Configuration, rootKey string) (map[string]string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal configuration: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("unable to decode configuration: %v", err)
+	}
+
+	pairs := make(map[string]string)
+
+	// The root key and the empty sections of the configuration are never set.
+	root, _ := value.(map[string]interface{})
+	for name, child := range root {
+		if section, ok := child.(map[string]interface{}); ok && len(section) == 0 {
+			continue
+		}
+
+		flatten(pairs, strings.Trim(rootKey, "/")+"/"+name, child)
+	}
+
+	return pairs, nil
+}
+
+func flatten(pairs map[string]string, key string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			pairs[key] = "true"
+			return
+		}
+
+		for name, child := range v {
+			flatten(pairs, key+"/"+name, child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(pairs, key+"/"+strconv.Itoa(i), child)
+		}
+	case string:
+		pairs[key] = v
+	case json.Number:
+		pairs[key] = v.String()
+	case bool:
+		pairs[key] = strconv.FormatBool(v)
+	}
+}
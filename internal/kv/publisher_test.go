@@ -0,0 +1,229 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/abronan/valkeyrie/store"
+	"github.com/containous/maesh/internal/providers/base"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		config   *dynamic.Configuration
+		expected map[string]string
+	}{
+		{
+			desc:     "empty configuration",
+			config:   &dynamic.Configuration{HTTP: &dynamic.HTTPConfiguration{}},
+			expected: map[string]string{},
+		},
+		{
+			desc: "http router and service",
+			config: &dynamic.Configuration{
+				HTTP: &dynamic.HTTPConfiguration{
+					Routers: map[string]*dynamic.Router{
+						"foo": {
+							EntryPoints: []string{"http-5000"},
+							Service:     "foo",
+							Rule:        "Host(`foo.maesh`)",
+							Priority:    1001,
+							TLS:         &dynamic.RouterTLSConfig{},
+						},
+					},
+					Services: map[string]*dynamic.Service{
+						"foo": {
+							LoadBalancer: &dynamic.ServersLoadBalancer{
+								PassHostHeader: base.Bool(true),
+								Servers: []dynamic.Server{
+									{URL: "http://10.0.0.1:80"},
+									{URL: "http://10.0.0.2:80"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: map[string]string{
+				"traefik/http/routers/foo/entryPoints/0":                "http-5000",
+				"traefik/http/routers/foo/service":                      "foo",
+				"traefik/http/routers/foo/rule":                         "Host(`foo.maesh`)",
+				"traefik/http/routers/foo/priority":                     "1001",
+				"traefik/http/routers/foo/tls":                          "true",
+				"traefik/http/services/foo/loadBalancer/passHostHeader": "true",
+				"traefik/http/services/foo/loadBalancer/servers/0/url":  "http://10.0.0.1:80",
+				"traefik/http/services/foo/loadBalancer/servers/1/url":  "http://10.0.0.2:80",
+			},
+		},
+		{
+			desc: "tcp router and service",
+			config: &dynamic.Configuration{
+				TCP: &dynamic.TCPConfiguration{
+					Routers: map[string]*dynamic.TCPRouter{
+						"bar": {
+							EntryPoints: []string{"tcp-10000"},
+							Service:     "bar",
+							Rule:        "HostSNI(`*`)",
+						},
+					},
+					Services: map[string]*dynamic.TCPService{
+						"bar": {
+							LoadBalancer: &dynamic.TCPServersLoadBalancer{
+								Servers: []dynamic.TCPServer{
+									{Address: "10.0.0.3:8080"},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: map[string]string{
+				"traefik/tcp/routers/bar/entryPoints/0":                   "tcp-10000",
+				"traefik/tcp/routers/bar/service":                         "bar",
+				"traefik/tcp/routers/bar/rule":                            "HostSNI(`*`)",
+				"traefik/tcp/services/bar/loadBalancer/servers/0/address": "10.0.0.3:8080",
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			pairs, err := Flatten(test.config, "/traefik/")
+			require.NoError(t, err)
+
+			assert.Equal(t, test.expected, pairs)
+		})
+	}
+}
+
+func TestPublisherPublish(t *testing.T) {
+	kvStore := NewMemoryStore()
+
+	// Pairs left by a previous controller, and pairs outside of the root key.
+	require.NoError(t, kvStore.Put("traefik/http/routers/old/rule", []byte("Host(`old.maesh`)"), nil))
+	require.NoError(t, kvStore.Put("traefikee/foo", []byte("bar"), nil))
+
+	publisher := NewPublisher(kvStore, "traefik")
+
+	first := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers: map[string]*dynamic.Router{
+				"foo": {Service: "foo", Rule: "Host(`foo.maesh`)"},
+				"bar": {Service: "bar", Rule: "Host(`bar.maesh`)"},
+			},
+		},
+	}
+
+	require.NoError(t, publisher.Publish(first))
+
+	assert.Equal(t, map[string]string{
+		"traefik/http/routers/foo/service": "foo",
+		"traefik/http/routers/foo/rule":    "Host(`foo.maesh`)",
+		"traefik/http/routers/bar/service": "bar",
+		"traefik/http/routers/bar/rule":    "Host(`bar.maesh`)",
+		"traefikee/foo":                    "bar",
+	}, kvStore.Pairs())
+
+	second := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers: map[string]*dynamic.Router{
+				"foo": {Service: "foo", Rule: "Host(`foo.maesh`) && PathPrefix(`/api`)"},
+			},
+		},
+	}
+
+	require.NoError(t, publisher.Publish(second))
+
+	assert.Equal(t, map[string]string{
+		"traefik/http/routers/foo/service": "foo",
+		"traefik/http/routers/foo/rule":    "Host(`foo.maesh`) && PathPrefix(`/api`)",
+		"traefikee/foo":                    "bar",
+	}, kvStore.Pairs())
+}
+
+// recordingStore records the writes made to a MemoryStore.
+type recordingStore struct {
+	*MemoryStore
+
+	operations []string
+}
+
+func (r *recordingStore) Put(key string, value []byte, options *store.WriteOptions) error {
+	r.operations = append(r.operations, "put "+key)
+
+	return r.MemoryStore.Put(key, value, options)
+}
+
+func (r *recordingStore) Delete(key string) error {
+	r.operations = append(r.operations, "delete "+key)
+
+	return r.MemoryStore.Delete(key)
+}
+
+func TestPublisherPublishOrder(t *testing.T) {
+	kvStore := &recordingStore{MemoryStore: NewMemoryStore()}
+	publisher := NewPublisher(kvStore, "traefik")
+
+	first := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers: map[string]*dynamic.Router{
+				"bar": {Service: "bar", Rule: "Host(`bar.maesh`)"},
+			},
+			Services: map[string]*dynamic.Service{
+				"bar": {
+					LoadBalancer: &dynamic.ServersLoadBalancer{
+						Servers: []dynamic.Server{{URL: "http://10.0.0.1:80"}, {URL: "http://10.0.0.2:80"}},
+					},
+				},
+			},
+		},
+	}
+
+	require.NoError(t, publisher.Publish(first))
+
+	second := &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers: map[string]*dynamic.Router{
+				"foo": {Service: "foo", Middlewares: []string{"foo-retry"}, Rule: "Host(`foo.maesh`)"},
+			},
+			Services: map[string]*dynamic.Service{
+				"bar": {
+					LoadBalancer: &dynamic.ServersLoadBalancer{
+						Servers: []dynamic.Server{{URL: "http://10.0.0.1:80"}},
+					},
+				},
+				"foo": {
+					LoadBalancer: &dynamic.ServersLoadBalancer{
+						Servers: []dynamic.Server{{URL: "http://10.0.0.3:80"}},
+					},
+				},
+			},
+			Middlewares: map[string]*dynamic.Middleware{
+				"foo-retry": {Retry: &dynamic.Retry{Attempts: 2}},
+			},
+		},
+	}
+
+	kvStore.operations = nil
+
+	require.NoError(t, publisher.Publish(second))
+
+	// The removed routers are deleted first, the routers are written after the services and middlewares they use,
+	// and the other removed pairs are deleted last.
+	assert.Equal(t, []string{
+		"delete traefik/http/routers/bar/rule",
+		"delete traefik/http/routers/bar/service",
+		"put traefik/http/middlewares/foo-retry/retry/attempts",
+		"put traefik/http/services/foo/loadBalancer/servers/0/url",
+		"put traefik/http/routers/foo/middlewares/0",
+		"put traefik/http/routers/foo/rule",
+		"put traefik/http/routers/foo/service",
+		"delete traefik/http/services/bar/loadBalancer/servers/1/url",
+	}, kvStore.operations)
+}
@@ -0,0 +1,109 @@
+package kv
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/abronan/valkeyrie"
+	"github.com/abronan/valkeyrie/store"
+	"github.com/abronan/valkeyrie/store/consul"
+	etcdv3 "github.com/abronan/valkeyrie/store/etcd/v3"
+	"github.com/abronan/valkeyrie/store/redis"
+	"github.com/abronan/valkeyrie/store/zookeeper"
+)
+
+func init() {
+	consul.Register()
+	etcdv3.Register()
+	redis.Register()
+	zookeeper.Register()
+}
+
+// StoreConfig holds the configuration of a KV store client.
+type StoreConfig struct {
+	// Backend is the type of the store: consul, etcdv3, redis or zk.
+	Backend   string
+	Endpoints []string
+	Username  string
+	Password  string
+	// TLS enables TLS, verified with the CA bundle of CAFile or the system CAs.
+	// The client certificate of CertFile and KeyFile is presented if both are set.
+	TLS      bool
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+// NewStore creates a client of the configured KV store.
+func NewStore(cfg StoreConfig) (store.Store, error) {
+	switch store.Backend(cfg.Backend) {
+	case store.CONSUL, store.ETCDV3, store.REDIS, store.ZK:
+	default:
+		return nil, fmt.Errorf("unsupported KV store %q", cfg.Backend)
+	}
+
+	if len(cfg.Endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoint for the %s KV store", cfg.Backend)
+	}
+
+	storeConfig := &store.Config{
+		ConnectionTimeout: 3 * time.Second,
+		Username:          cfg.Username,
+		Password:          cfg.Password,
+	}
+
+	if cfg.TLS {
+		tlsConfig, err := buildTLSConfig(cfg.CAFile, cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		storeConfig.TLS = tlsConfig
+	} else if cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != "" {
+		return nil, errors.New("the KV store CA and client certificate require TLS")
+	}
+
+	kvStore, err := valkeyrie.NewStore(store.Backend(cfg.Backend), cfg.Endpoints, storeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s KV store client: %v", cfg.Backend, err)
+	}
+
+	return kvStore, nil
+}
+
+func buildTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the KV store CA: %v", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in the KV store CA %q", caFile)
+		}
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("the KV store client certificate requires both a certificate and a key")
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load the KV store client certificate: %v", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
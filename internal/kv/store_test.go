@@ -0,0 +1,89 @@
+package kv
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStoreInvalidConfig(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		config StoreConfig
+	}{
+		{
+			desc:   "unsupported store",
+			config: StoreConfig{Backend: "boltdb", Endpoints: []string{"localhost:1234"}},
+		},
+		{
+			desc:   "no endpoint",
+			config: StoreConfig{Backend: "consul"},
+		},
+		{
+			desc:   "CA without TLS",
+			config: StoreConfig{Backend: "consul", Endpoints: []string{"localhost:8500"}, CAFile: "ca.crt"},
+		},
+		{
+			desc:   "missing CA",
+			config: StoreConfig{Backend: "consul", Endpoints: []string{"localhost:8500"}, TLS: true, CAFile: "missing.crt"},
+		},
+		{
+			desc:   "client certificate without key",
+			config: StoreConfig{Backend: "consul", Endpoints: []string{"localhost:8500"}, TLS: true, CertFile: "tls.crt"},
+		},
+		{
+			desc:   "missing client certificate",
+			config: StoreConfig{Backend: "consul", Endpoints: []string{"localhost:8500"}, TLS: true, CertFile: "missing.crt", KeyFile: "missing.key"},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewStore(test.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "maesh")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(caFile, ca, 0600))
+
+	tlsConfig, err := buildTLSConfig(caFile, "", "")
+	require.NoError(t, err)
+
+	// The store is verified with the CA.
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+	res, err := client.Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, res.Body.Close())
+
+	// Without the CA, the system CAs do not verify the store.
+	tlsConfig, err = buildTLSConfig("", "", "")
+	require.NoError(t, err)
+
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+}
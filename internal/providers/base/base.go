@@ -1,18 +1,27 @@
 package base
 
 import (
+	"fmt"
+
 	"github.com/containous/maesh/internal/k8s"
 	"github.com/containous/traefik/v2/pkg/config/dynamic"
 	splitv1alpha2 "github.com/deislabs/smi-sdk-go/pkg/apis/split/v1alpha2"
 	corev1 "k8s.io/api/core/v1"
+	kubeerror "k8s.io/apimachinery/pkg/api/errors"
+	listers "k8s.io/client-go/listers/core/v1"
 )
 
+// minHTTPPort is the first mesh node port, on which the HTTP services are reachable.
+const minHTTPPort = 5000
+
 // Bool returns reference of the bool value.
 func Bool(v bool) *bool { return &v }
 
 // Provider is an interface for providers that allows the controller to interact with providers
 // without having to deal with specifics of said providers.
 type Provider interface {
+	Invalidator
+
 	Init()
 	BuildConfig() (*dynamic.Configuration, error)
 }
@@ -60,15 +69,18 @@ func GetTrafficSplitFromList(serviceName string, trafficSplits []*splitv1alpha2.
 	return nil
 }
 
-// GetEndpointsFromList returns an endpoint from a list.
-func GetEndpointsFromList(name, namespace string, endpointList []*corev1.Endpoints) *corev1.Endpoints {
-	for _, endpoints := range endpointList {
-		if endpoints.Name == name && endpoints.Namespace == namespace {
-			return endpoints
+// GetEndpoints returns the endpoints of a service, or nil if they don't exist.
+func GetEndpoints(endpointsLister listers.EndpointsLister, name, namespace string) (*corev1.Endpoints, error) {
+	endpoints, err := endpointsLister.Endpoints(namespace).Get(name)
+	if err != nil {
+		if kubeerror.IsNotFound(err) {
+			return nil, nil
 		}
+
+		return nil, err
 	}
 
-	return nil
+	return endpoints, nil
 }
 
 // AddBaseSMIMiddlewares adds base middleware to a dynamic config.
@@ -105,3 +117,19 @@ func GetServiceMode(annotations map[string]string, defaultMode string) string {
 
 	return mode
 }
+
+// GetHTTPMeshPort returns the mesh node port on which the HTTP service port with the given index is reachable.
+func GetHTTPMeshPort(portID int) int {
+	return minHTTPPort + portID
+}
+
+// BuildHostRule returns the rule matching the requests to a service, by its mesh host name and its cluster IP.
+// The cluster IP is left out when it is not assigned, like for the services of rendered manifests.
+func BuildHostRule(name, namespace, ip string) string {
+	rule := fmt.Sprintf("Host(`%s.%s.maesh`)", name, namespace)
+	if ip == "" {
+		return rule
+	}
+
+	return rule + fmt.Sprintf(" || Host(`%s`)", ip)
+}
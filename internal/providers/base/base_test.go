@@ -6,11 +6,14 @@ import (
 	"github.com/containous/maesh/internal/k8s"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
-func TestGetEndpointsFromList(t *testing.T) {
+func TestGetEndpoints(t *testing.T) {
 	testCases := []struct {
 		desc      string
 		provided  []*corev1.Endpoints
@@ -61,7 +64,7 @@ func TestGetEndpointsFromList(t *testing.T) {
 				},
 				{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      "fifi",
+						Name:      "foo",
 						Namespace: "fufu",
 					},
 				},
@@ -77,7 +80,13 @@ func TestGetEndpointsFromList(t *testing.T) {
 		t.Run(test.desc, func(t *testing.T) {
 			t.Parallel()
 
-			actual := GetEndpointsFromList(test.name, test.namespace, test.provided)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, endpoints := range test.provided {
+				require.NoError(t, indexer.Add(endpoints))
+			}
+
+			actual, err := GetEndpoints(listers.NewEndpointsLister(indexer), test.name, test.namespace)
+			require.NoError(t, err)
 			assert.Equal(t, test.expected, actual)
 		})
 	}
@@ -191,3 +200,8 @@ func TestGetServiceType(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildHostRule(t *testing.T) {
+	assert.Equal(t, "Host(`foo.bar.maesh`) || Host(`10.0.0.1`)", BuildHostRule("foo", "bar", "10.0.0.1"))
+	assert.Equal(t, "Host(`foo.bar.maesh`)", BuildHostRule("foo", "bar", ""))
+}
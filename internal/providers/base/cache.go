@@ -0,0 +1,206 @@
+package base
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Invalidator is implemented by providers caching the configuration built for each service.
+type Invalidator interface {
+	// InvalidateService invalidates the configuration of a service, after a change of the service or its endpoints.
+	InvalidateService(namespace, name string)
+	// InvalidateNamespace invalidates the configuration of all the services of a namespace.
+	InvalidateNamespace(namespace string)
+	// InvalidateAll invalidates the configuration of all the services.
+	InvalidateAll()
+}
+
+// FragmentBuilder builds the configuration of a service into the given fragment.
+// It returns the namespaces, other than the service one, whose changes also invalidate the fragment.
+type FragmentBuilder func(fragment *dynamic.Configuration, service *corev1.Service) ([]string, error)
+
+// fragment is the configuration built for a service.
+type fragment struct {
+	config       *dynamic.Configuration
+	dependencies []string
+}
+
+// ConfigCache caches the configuration fragment of each service, and tracks the services to rebuild.
+// Only the invalidated fragments are rebuilt, and the configuration is assembled again only if a fragment changed.
+type ConfigCache struct {
+	mu sync.Mutex
+
+	fragments map[string]*fragment
+	config    *dynamic.Configuration
+
+	all               bool
+	services          map[string]struct{}
+	namespaces        map[string]struct{}
+	changedNamespaces map[string]struct{}
+}
+
+// NewConfigCache creates an empty ConfigCache.
+func NewConfigCache() *ConfigCache {
+	c := &ConfigCache{
+		fragments: make(map[string]*fragment),
+	}
+
+	c.resetInvalidations()
+
+	return c
+}
+
+// InvalidateService invalidates the fragment of a service, and the fragments depending on its namespace.
+func (c *ConfigCache) InvalidateService(namespace, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.services[namespace+"/"+name] = struct{}{}
+	c.changedNamespaces[namespace] = struct{}{}
+}
+
+// InvalidateNamespace invalidates the fragments of all the services of a namespace, and the fragments depending on it.
+func (c *ConfigCache) InvalidateNamespace(namespace string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.namespaces[namespace] = struct{}{}
+	c.changedNamespaces[namespace] = struct{}{}
+}
+
+// InvalidateAll invalidates all the fragments.
+func (c *ConfigCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.all = true
+}
+
+// Build returns the configuration of the given services, rebuilding only the invalidated fragments.
+// The previous configuration is returned as is if no fragment changed.
+func (c *ConfigCache) Build(services []*corev1.Service, newBaseConfig func() *dynamic.Configuration, build FragmentBuilder) (*dynamic.Configuration, error) {
+	c.mu.Lock()
+	all, invalidServices, invalidNamespaces, changedNamespaces := c.all, c.services, c.namespaces, c.changedNamespaces
+	c.resetInvalidations()
+	c.mu.Unlock()
+
+	isInvalid := func(key, namespace string, f *fragment) bool {
+		if all {
+			return true
+		}
+
+		if _, ok := invalidServices[key]; ok {
+			return true
+		}
+
+		if _, ok := invalidNamespaces[namespace]; ok {
+			return true
+		}
+
+		for _, dependency := range f.dependencies {
+			if _, ok := changedNamespaces[dependency]; ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	changed := c.config == nil
+	seen := make(map[string]struct{}, len(services))
+
+	for _, service := range services {
+		key := service.Namespace + "/" + service.Name
+		seen[key] = struct{}{}
+
+		previous, exists := c.fragments[key]
+		if exists && !isInvalid(key, service.Namespace, previous) {
+			continue
+		}
+
+		f := &fragment{config: newFragmentConfig()}
+
+		dependencies, err := build(f.config, service)
+		if err != nil {
+			// Rebuild everything on the next call, as the fragments may be partially updated.
+			c.InvalidateAll()
+			return nil, err
+		}
+
+		f.dependencies = dependencies
+
+		if !exists || !reflect.DeepEqual(previous.config, f.config) {
+			changed = true
+		}
+
+		c.fragments[key] = f
+	}
+
+	for key := range c.fragments {
+		if _, ok := seen[key]; !ok {
+			delete(c.fragments, key)
+
+			changed = true
+		}
+	}
+
+	if !changed {
+		return c.config, nil
+	}
+
+	config := newBaseConfig()
+	for _, f := range c.fragments {
+		mergeConfig(config, f.config)
+	}
+
+	c.config = config
+
+	return config, nil
+}
+
+func (c *ConfigCache) resetInvalidations() {
+	c.all = false
+	c.services = make(map[string]struct{})
+	c.namespaces = make(map[string]struct{})
+	c.changedNamespaces = make(map[string]struct{})
+}
+
+func newFragmentConfig() *dynamic.Configuration {
+	return &dynamic.Configuration{
+		HTTP: &dynamic.HTTPConfiguration{
+			Routers:     map[string]*dynamic.Router{},
+			Services:    map[string]*dynamic.Service{},
+			Middlewares: map[string]*dynamic.Middleware{},
+		},
+		TCP: &dynamic.TCPConfiguration{
+			Routers:  map[string]*dynamic.TCPRouter{},
+			Services: map[string]*dynamic.TCPService{},
+		},
+	}
+}
+
+// mergeConfig adds the routers, services and middlewares of a fragment to the configuration.
+func mergeConfig(config, fragment *dynamic.Configuration) {
+	for key, router := range fragment.HTTP.Routers {
+		config.HTTP.Routers[key] = router
+	}
+
+	for key, service := range fragment.HTTP.Services {
+		config.HTTP.Services[key] = service
+	}
+
+	for key, middleware := range fragment.HTTP.Middlewares {
+		config.HTTP.Middlewares[key] = middleware
+	}
+
+	for key, router := range fragment.TCP.Routers {
+		config.TCP.Routers[key] = router
+	}
+
+	for key, service := range fragment.TCP.Services {
+		config.TCP.Services[key] = service
+	}
+}
@@ -0,0 +1,187 @@
+package base
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigCache(t *testing.T) {
+	services := []*corev1.Service{
+		buildService("foo", "ns1"),
+		buildService("bar", "ns1"),
+		buildService("baz", "ns2"),
+	}
+
+	// baz depends on ns1, as its traffic could come from ns1.
+	dependencies := map[string][]string{"ns2/baz": {"ns1"}}
+
+	testCases := []struct {
+		desc       string
+		invalidate func(c *ConfigCache)
+		services   []*corev1.Service
+		expected   []string
+		changed    bool
+	}{
+		{
+			desc:       "nothing invalidated",
+			invalidate: func(c *ConfigCache) {},
+			services:   services,
+			expected:   nil,
+		},
+		{
+			desc: "service invalidated",
+			invalidate: func(c *ConfigCache) {
+				c.InvalidateService("ns2", "baz")
+			},
+			services: services,
+			expected: []string{"ns2/baz"},
+			changed:  true,
+		},
+		{
+			desc: "service invalidated with dependent services",
+			invalidate: func(c *ConfigCache) {
+				c.InvalidateService("ns1", "foo")
+			},
+			services: services,
+			expected: []string{"ns1/foo", "ns2/baz"},
+			changed:  true,
+		},
+		{
+			desc: "namespace invalidated",
+			invalidate: func(c *ConfigCache) {
+				c.InvalidateNamespace("ns1")
+			},
+			services: services,
+			expected: []string{"ns1/foo", "ns1/bar", "ns2/baz"},
+			changed:  true,
+		},
+		{
+			desc: "all invalidated",
+			invalidate: func(c *ConfigCache) {
+				c.InvalidateAll()
+			},
+			services: services,
+			expected: []string{"ns1/foo", "ns1/bar", "ns2/baz"},
+			changed:  true,
+		},
+		{
+			desc:       "service deleted",
+			invalidate: func(c *ConfigCache) {},
+			services:   services[1:],
+			expected:   nil,
+			changed:    true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// The built fragments depend on the number of builds, so that rebuilt fragments are always changed.
+			var built []string
+
+			builds := 0
+			build := func(fragment *dynamic.Configuration, service *corev1.Service) ([]string, error) {
+				key := service.Namespace + "/" + service.Name
+				built = append(built, key)
+				builds++
+
+				fragment.HTTP.Routers[key] = &dynamic.Router{Service: key, Priority: builds}
+
+				return dependencies[key], nil
+			}
+
+			c := NewConfigCache()
+
+			first, err := c.Build(services, CreateBaseConfigWithReadiness, build)
+			require.NoError(t, err)
+			assert.Len(t, first.HTTP.Routers, 4)
+
+			built = nil
+
+			test.invalidate(c)
+
+			actual, err := c.Build(test.services, CreateBaseConfigWithReadiness, build)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, test.expected, built)
+			assert.Len(t, actual.HTTP.Routers, len(test.services)+1)
+
+			if test.changed {
+				assert.True(t, first != actual)
+				return
+			}
+
+			assert.True(t, first == actual)
+		})
+	}
+}
+
+func TestConfigCacheUnchangedFragment(t *testing.T) {
+	services := []*corev1.Service{buildService("foo", "ns1")}
+
+	build := func(fragment *dynamic.Configuration, service *corev1.Service) ([]string, error) {
+		fragment.HTTP.Routers[service.Name] = &dynamic.Router{Service: service.Name}
+		return nil, nil
+	}
+
+	c := NewConfigCache()
+
+	first, err := c.Build(services, CreateBaseConfigWithReadiness, build)
+	require.NoError(t, err)
+
+	c.InvalidateService("ns1", "foo")
+
+	actual, err := c.Build(services, CreateBaseConfigWithReadiness, build)
+	require.NoError(t, err)
+
+	// The fragment has been rebuilt to the same configuration, which is not assembled again.
+	assert.True(t, first == actual)
+}
+
+func TestConfigCacheBuildError(t *testing.T) {
+	services := []*corev1.Service{buildService("foo", "ns1"), buildService("bar", "ns1")}
+
+	var built []string
+
+	fail := true
+	build := func(fragment *dynamic.Configuration, service *corev1.Service) ([]string, error) {
+		built = append(built, service.Name)
+
+		if fail && service.Name == "bar" {
+			return nil, errors.New("boom")
+		}
+
+		return nil, nil
+	}
+
+	c := NewConfigCache()
+
+	_, err := c.Build(services, CreateBaseConfigWithReadiness, build)
+	require.Error(t, err)
+
+	fail = false
+	built = nil
+
+	_, err = c.Build(services, CreateBaseConfigWithReadiness, build)
+	require.NoError(t, err)
+
+	// Everything is rebuilt after an error.
+	assert.ElementsMatch(t, []string{"foo", "bar"}, built)
+}
+
+func buildService(name, namespace string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+}
@@ -19,6 +19,8 @@ import (
 
 // Provider holds a client to access the provider.
 type Provider struct {
+	*base.ConfigCache
+
 	defaultMode     string
 	tcpStateTable   *k8s.State
 	ignored         k8s.IgnoreWrapper
@@ -34,6 +36,7 @@ func (p *Provider) Init() {
 // New creates a new provider.
 func New(defaultMode string, tcpStateTable *k8s.State, ignored k8s.IgnoreWrapper, serviceLister listers.ServiceLister, endpointsLister listers.EndpointsLister) *Provider {
 	p := &Provider{
+		ConfigCache:     base.NewConfigCache(),
 		defaultMode:     defaultMode,
 		tcpStateTable:   tcpStateTable,
 		ignored:         ignored,
@@ -49,7 +52,7 @@ func New(defaultMode string, tcpStateTable *k8s.State, ignored k8s.IgnoreWrapper
 func (p *Provider) buildRouter(name, namespace, ip string, port int, serviceName string, addMiddlewares bool) *dynamic.Router {
 	if addMiddlewares {
 		return &dynamic.Router{
-			Rule:        fmt.Sprintf("Host(`%s.%s.maesh`) || Host(`%s`)", name, namespace, ip),
+			Rule:        base.BuildHostRule(name, namespace, ip),
 			EntryPoints: []string{fmt.Sprintf("http-%d", port)},
 			Middlewares: []string{serviceName},
 			Service:     serviceName,
@@ -57,7 +60,7 @@ func (p *Provider) buildRouter(name, namespace, ip string, port int, serviceName
 	}
 
 	return &dynamic.Router{
-		Rule:        fmt.Sprintf("Host(`%s.%s.maesh`) || Host(`%s`)", name, namespace, ip),
+		Rule:        base.BuildHostRule(name, namespace, ip),
 		EntryPoints: []string{fmt.Sprintf("http-%d", port)},
 		Service:     serviceName,
 	}
@@ -124,53 +127,55 @@ func (p *Provider) buildTCPService(endpoints *corev1.Endpoints) *dynamic.TCPServ
 
 // BuildConfig builds the configuration for routing
 // from a native kubernetes environment.
+// Only the configuration of the services invalidated since the last call is rebuilt.
 func (p *Provider) BuildConfig() (*dynamic.Configuration, error) {
 	services, err := p.serviceLister.Services(metav1.NamespaceAll).List(labels.Everything())
 	if err != nil {
 		return nil, fmt.Errorf("unable to get services: %v", err)
 	}
 
-	endpoints, err := p.endpointsLister.Endpoints(metav1.NamespaceAll).List(labels.Everything())
+	return p.Build(services, base.CreateBaseConfigWithReadiness, p.buildServiceConfig)
+}
+
+// buildServiceConfig builds the routers, services and middlewares of a service.
+func (p *Provider) buildServiceConfig(config *dynamic.Configuration, service *corev1.Service) ([]string, error) {
+	if p.ignored.IsIgnored(service.ObjectMeta) {
+		return nil, nil
+	}
+
+	endpoints, err := base.GetEndpoints(p.endpointsLister, service.Name, service.Namespace)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get endpoints: %v", err)
 	}
 
-	config := base.CreateBaseConfigWithReadiness()
-
-	for _, service := range services {
-		if p.ignored.IsIgnored(service.ObjectMeta) {
-			continue
-		}
+	serviceMode := base.GetServiceMode(service.Annotations, p.defaultMode)
+	scheme := base.GetScheme(service.Annotations)
 
-		serviceMode := base.GetServiceMode(service.Annotations, p.defaultMode)
-		scheme := base.GetScheme(service.Annotations)
+	for id, sp := range service.Spec.Ports {
+		key := buildKey(service.Name, service.Namespace, sp.Port)
 
-		for id, sp := range service.Spec.Ports {
-			key := buildKey(service.Name, service.Namespace, sp.Port)
+		if serviceMode == k8s.ServiceTypeHTTP {
+			config.HTTP.Services[key] = p.buildService(endpoints, scheme)
+			middlewares := p.buildHTTPMiddlewares(service.Annotations)
 
-			if serviceMode == k8s.ServiceTypeHTTP {
-				config.HTTP.Services[key] = p.buildService(base.GetEndpointsFromList(service.Name, service.Namespace, endpoints), scheme)
-				middlewares := p.buildHTTPMiddlewares(service.Annotations)
-
-				if middlewares != nil {
-					config.HTTP.Routers[key] = p.buildRouter(service.Name, service.Namespace, service.Spec.ClusterIP, 5000+id, key, true)
-					config.HTTP.Middlewares[key] = middlewares
-
-					continue
-				}
-
-				config.HTTP.Routers[key] = p.buildRouter(service.Name, service.Namespace, service.Spec.ClusterIP, 5000+id, key, false)
+			if middlewares != nil {
+				config.HTTP.Routers[key] = p.buildRouter(service.Name, service.Namespace, service.Spec.ClusterIP, base.GetHTTPMeshPort(id), key, true)
+				config.HTTP.Middlewares[key] = middlewares
 
 				continue
 			}
 
-			meshPort := p.getMeshPort(service.Name, service.Namespace, sp.Port)
-			config.TCP.Routers[key] = p.buildTCPRouter(meshPort, key)
-			config.TCP.Services[key] = p.buildTCPService(base.GetEndpointsFromList(service.Name, service.Namespace, endpoints))
+			config.HTTP.Routers[key] = p.buildRouter(service.Name, service.Namespace, service.Spec.ClusterIP, base.GetHTTPMeshPort(id), key, false)
+
+			continue
 		}
+
+		meshPort := p.getMeshPort(service.Name, service.Namespace, sp.Port)
+		config.TCP.Routers[key] = p.buildTCPRouter(meshPort, key)
+		config.TCP.Services[key] = p.buildTCPService(endpoints)
 	}
 
-	return config, nil
+	return nil, nil
 }
 
 func (p *Provider) buildHTTPMiddlewares(annotations map[string]string) *dynamic.Middleware {
@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/containous/maesh/internal/providers/base"
@@ -614,3 +615,105 @@ func TestBuildHTTPMiddlewares(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkBuildConfig(b *testing.B) {
+	const servicesCount = 10000
+
+	fakeClient := fake.NewSimpleClientset()
+	kubernetesFactory := informers.NewSharedInformerFactoryWithOptions(fakeClient, k8s.ResyncPeriod)
+	serviceInformer := kubernetesFactory.Core().V1().Services()
+	endpointsInformer := kubernetesFactory.Core().V1().Endpoints()
+
+	for i := 0; i < servicesCount; i++ {
+		meta := metav1.ObjectMeta{
+			Name:      fmt.Sprintf("service-%d", i),
+			Namespace: fmt.Sprintf("namespace-%d", i%100),
+		}
+
+		service := &corev1.Service{
+			ObjectMeta: meta,
+			Spec: corev1.ServiceSpec{
+				ClusterIP: "10.0.0.1",
+				Ports:     []corev1.ServicePort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}},
+			},
+		}
+
+		endpoints := &corev1.Endpoints{
+			ObjectMeta: meta,
+			Subsets: []corev1.EndpointSubset{
+				{
+					Addresses: []corev1.EndpointAddress{{IP: "10.1.0.1"}, {IP: "10.1.0.2"}},
+					Ports:     []corev1.EndpointPort{{Port: 8080}},
+				},
+			},
+		}
+
+		if err := serviceInformer.Informer().GetIndexer().Add(service); err != nil {
+			b.Fatal(err)
+		}
+
+		if err := endpointsInformer.Informer().GetIndexer().Add(endpoints); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	provider := New(k8s.ServiceTypeHTTP, nil, k8s.NewIgnored(), serviceInformer.Lister(), endpointsInformer.Lister())
+
+	if _, err := provider.BuildConfig(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			provider.InvalidateAll()
+
+			if _, err := provider.BuildConfig(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("one service invalidated", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			provider.InvalidateService(fmt.Sprintf("namespace-%d", i%100), fmt.Sprintf("service-%d", i%servicesCount))
+
+			if _, err := provider.BuildConfig(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("one service changed", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+
+			endpoints := &corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Name: "service-0", Namespace: "namespace-0"},
+				Subsets: []corev1.EndpointSubset{
+					{
+						Addresses: []corev1.EndpointAddress{{IP: fmt.Sprintf("10.2.%d.%d", i/256%256, i%256)}},
+						Ports:     []corev1.EndpointPort{{Port: 8080}},
+					},
+				},
+			}
+
+			if err := endpointsInformer.Informer().GetIndexer().Update(endpoints); err != nil {
+				b.Fatal(err)
+			}
+
+			b.StartTimer()
+
+			provider.InvalidateService(endpoints.Namespace, endpoints.Name)
+
+			if _, err := provider.BuildConfig(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -26,6 +26,8 @@ import (
 
 // Provider holds a client to access the provider.
 type Provider struct {
+	*base.ConfigCache
+
 	defaultMode          string
 	tcpStateTable        *k8s.State
 	ignored              k8s.IgnoreWrapper
@@ -58,6 +60,7 @@ func New(defaultMode string, tcpStateTable *k8s.State, ignored k8s.IgnoreWrapper
 	tcpRouteLister specsLister.TCPRouteLister,
 	trafficSplitLister splitLister.TrafficSplitLister) *Provider {
 	p := &Provider{
+		ConfigCache:          base.NewConfigCache(),
 		defaultMode:          defaultMode,
 		tcpStateTable:        tcpStateTable,
 		ignored:              ignored,
@@ -77,20 +80,13 @@ func New(defaultMode string, tcpStateTable *k8s.State, ignored k8s.IgnoreWrapper
 
 // BuildConfig builds the configuration for routing
 // from a native kubernetes environment.
+// Only the configuration of the services invalidated since the last call is rebuilt.
 func (p *Provider) BuildConfig() (*dynamic.Configuration, error) {
-	config := base.CreateBaseConfigWithReadiness()
-	base.AddBaseSMIMiddlewares(config)
-
 	services, err := p.serviceLister.Services(metav1.NamespaceAll).List(labels.Everything())
 	if err != nil {
 		return nil, fmt.Errorf("unable to get services: %v", err)
 	}
 
-	endpoints, err := p.endpointsLister.Endpoints(metav1.NamespaceAll).List(labels.Everything())
-	if err != nil {
-		return nil, fmt.Errorf("unable to get endpoints: %v", err)
-	}
-
 	trafficTargets, err := p.trafficTargetLister.TrafficTargets(metav1.NamespaceAll).List(labels.Everything())
 	if err != nil {
 		return nil, fmt.Errorf("unable to get traffictargets: %v", err)
@@ -101,65 +97,93 @@ func (p *Provider) BuildConfig() (*dynamic.Configuration, error) {
 		return nil, fmt.Errorf("unable to get trafficsplits: %v", err)
 	}
 
-	for _, service := range services {
-		if p.ignored.IsIgnored(service.ObjectMeta) {
-			continue
-		}
+	newBaseConfig := func() *dynamic.Configuration {
+		config := base.CreateBaseConfigWithReadiness()
+		base.AddBaseSMIMiddlewares(config)
 
-		serviceMode := p.getServiceMode(service.Annotations[k8s.AnnotationServiceType])
-		// Get all traffic targets in the service's namespace.
-		trafficTargetsInNamespace := p.getTrafficTargetsWithDestinationInNamespace(service.Namespace, trafficTargets)
-		log.Debugf("Found traffictargets for service %s/%s: %+v", service.Namespace, service.Name, trafficTargets)
-		// Find all traffic targets that are applicable to the service in question.
-		applicableTrafficTargets := p.getApplicableTrafficTargets(base.GetEndpointsFromList(service.Name, service.Namespace, endpoints), trafficTargetsInNamespace)
-		log.Debugf("Found applicable traffictargets for service %s/%s: %+v", service.Namespace, service.Name, applicableTrafficTargets)
-		// Group the traffic targets by destination, so that they can be built separately.
-		groupedByDestinationTrafficTargets := p.groupTrafficTargetsByDestination(applicableTrafficTargets)
-		log.Debugf("Found grouped traffictargets for service %s/%s: %+v", service.Namespace, service.Name, groupedByDestinationTrafficTargets)
-
-		// Get all traffic split in the service's namespace.
-		trafficSplitsInNamespace := p.getTrafficSplitsWithDestinationInNamespace(service.Namespace, trafficSplits)
-		log.Debugf("Found trafficsplits for service %s/%s: %+v", service.Namespace, service.Name, trafficSplitsInNamespace)
-
-		for _, groupedTrafficTargets := range groupedByDestinationTrafficTargets {
-			for _, groupedTrafficTarget := range groupedTrafficTargets {
-				for id, sp := range service.Spec.Ports {
-					key := buildKey(service.Name, service.Namespace, sp.Port, groupedTrafficTarget.Name, groupedTrafficTarget.Namespace)
-
-					//	For each source in the trafficTarget, get a list of IPs to whitelist.
-					sourceIPs := p.getSourceIPFromSourceSlice(groupedTrafficTarget.Sources)
-					whitelistKey := groupedTrafficTarget.Name + "-" + groupedTrafficTarget.Namespace + "-" + key + "-whitelist"
-					whitelistMiddleware := k8s.BlockAllMiddlewareKey
-
-					switch serviceMode {
-					case k8s.ServiceTypeHTTP:
-						if len(sourceIPs) > 0 {
-							config.HTTP.Middlewares[whitelistKey] = createWhitelistMiddleware(sourceIPs)
-							whitelistMiddleware = whitelistKey
-						}
-
-						scheme := base.GetScheme(service.Annotations)
-
-						trafficSplit := base.GetTrafficSplitFromList(service.Name, trafficSplitsInNamespace)
-						if trafficSplit == nil {
-							config.HTTP.Routers[key] = p.buildHTTPRouterFromTrafficTarget(service.Name, service.Namespace, service.Spec.ClusterIP, groupedTrafficTarget, 5000+id, key, whitelistMiddleware)
-							config.HTTP.Services[key] = p.buildHTTPServiceFromTrafficTarget(base.GetEndpointsFromList(service.Name, service.Namespace, endpoints), groupedTrafficTarget, scheme)
-
-							continue
-						}
-
-						p.buildTrafficSplit(config, trafficSplit, sp, id, groupedTrafficTarget, whitelistMiddleware, scheme)
-					case k8s.ServiceTypeTCP:
-						meshPort := p.getMeshPort(service.Name, service.Namespace, sp.Port)
-						config.TCP.Routers[key] = p.buildTCPRouterFromTrafficTarget(groupedTrafficTarget, meshPort, key)
-						config.TCP.Services[key] = p.buildTCPServiceFromTrafficTarget(base.GetEndpointsFromList(service.Name, service.Namespace, endpoints), groupedTrafficTarget)
+		return config
+	}
+
+	return p.Build(services, newBaseConfig, func(config *dynamic.Configuration, service *corev1.Service) ([]string, error) {
+		return p.buildServiceConfig(config, service, trafficTargets, trafficSplits)
+	})
+}
+
+// buildServiceConfig builds the routers, services and middlewares of a service.
+// It returns the namespaces of the sources of the service traffic targets, and the service namespace if its traffic is split,
+// as their changes also change the service configuration.
+func (p *Provider) buildServiceConfig(config *dynamic.Configuration, service *corev1.Service, trafficTargets []*access.TrafficTarget, trafficSplits []*split.TrafficSplit) ([]string, error) {
+	if p.ignored.IsIgnored(service.ObjectMeta) {
+		return nil, nil
+	}
+
+	endpoints, err := base.GetEndpoints(p.endpointsLister, service.Name, service.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get endpoints: %v", err)
+	}
+
+	var dependencies []string
+
+	serviceMode := p.getServiceMode(service.Annotations[k8s.AnnotationServiceType])
+	// Get all traffic targets in the service's namespace.
+	trafficTargetsInNamespace := p.getTrafficTargetsWithDestinationInNamespace(service.Namespace, trafficTargets)
+	log.Debugf("Found traffictargets for service %s/%s: %+v", service.Namespace, service.Name, trafficTargets)
+	// Find all traffic targets that are applicable to the service in question.
+	applicableTrafficTargets := p.getApplicableTrafficTargets(endpoints, trafficTargetsInNamespace)
+	log.Debugf("Found applicable traffictargets for service %s/%s: %+v", service.Namespace, service.Name, applicableTrafficTargets)
+	// Group the traffic targets by destination, so that they can be built separately.
+	groupedByDestinationTrafficTargets := p.groupTrafficTargetsByDestination(applicableTrafficTargets)
+	log.Debugf("Found grouped traffictargets for service %s/%s: %+v", service.Namespace, service.Name, groupedByDestinationTrafficTargets)
+
+	// Get all traffic split in the service's namespace.
+	trafficSplitsInNamespace := p.getTrafficSplitsWithDestinationInNamespace(service.Namespace, trafficSplits)
+	log.Debugf("Found trafficsplits for service %s/%s: %+v", service.Namespace, service.Name, trafficSplitsInNamespace)
+
+	for _, groupedTrafficTargets := range groupedByDestinationTrafficTargets {
+		for _, groupedTrafficTarget := range groupedTrafficTargets {
+			for id, sp := range service.Spec.Ports {
+				key := buildKey(service.Name, service.Namespace, sp.Port, groupedTrafficTarget.Name, groupedTrafficTarget.Namespace)
+
+				//	For each source in the trafficTarget, get a list of IPs to whitelist.
+				sourceIPs := p.getSourceIPFromSourceSlice(groupedTrafficTarget.Sources)
+				for _, source := range groupedTrafficTarget.Sources {
+					dependencies = append(dependencies, source.Namespace)
+				}
+
+				whitelistKey := BuildWhitelistKey(service.Name, service.Namespace, sp.Port, groupedTrafficTarget.Name, groupedTrafficTarget.Namespace)
+				whitelistMiddleware := k8s.BlockAllMiddlewareKey
+
+				switch serviceMode {
+				case k8s.ServiceTypeHTTP:
+					if len(sourceIPs) > 0 {
+						config.HTTP.Middlewares[whitelistKey] = createWhitelistMiddleware(sourceIPs)
+						whitelistMiddleware = whitelistKey
+					}
+
+					scheme := base.GetScheme(service.Annotations)
+
+					trafficSplit := base.GetTrafficSplitFromList(service.Name, trafficSplitsInNamespace)
+					if trafficSplit == nil {
+						config.HTTP.Routers[key] = p.buildHTTPRouterFromTrafficTarget(service.Name, service.Namespace, service.Spec.ClusterIP, groupedTrafficTarget, base.GetHTTPMeshPort(id), key, whitelistMiddleware)
+						config.HTTP.Services[key] = p.buildHTTPServiceFromTrafficTarget(endpoints, groupedTrafficTarget, scheme)
+
+						continue
 					}
+
+					// The endpoints of the backend services are part of the configuration.
+					dependencies = append(dependencies, service.Namespace)
+
+					p.buildTrafficSplit(config, trafficSplit, sp, id, groupedTrafficTarget, whitelistMiddleware, scheme)
+				case k8s.ServiceTypeTCP:
+					meshPort := p.getMeshPort(service.Name, service.Namespace, sp.Port)
+					config.TCP.Routers[key] = p.buildTCPRouterFromTrafficTarget(groupedTrafficTarget, meshPort, key)
+					config.TCP.Services[key] = p.buildTCPServiceFromTrafficTarget(endpoints, groupedTrafficTarget)
 				}
 			}
 		}
 	}
 
-	return config, nil
+	return dependencies, nil
 }
 
 func (p *Provider) getSourceIPFromSourceSlice(sources []access.IdentityBindingSubject) []string {
@@ -232,51 +256,64 @@ func (p *Provider) getApplicableTrafficTargets(endpoints *corev1.Endpoints, traf
 		log.Debugf("No applicable TrafficTargets for service %s/%s: No endpoint subsets", endpoints.Namespace, endpoints.Name)
 	}
 
-	for _, subset := range endpoints.Subsets {
-		for _, trafficTarget := range trafficTargets {
-			if endpoints.Namespace != trafficTarget.Destination.Namespace {
-				// Destination not in service namespace, skip.
-				log.Debugf("Destination namespace for TrafficTarget: %s not in service namespace: %s", trafficTarget.Destination.Name, endpoints.Namespace)
-				continue
-			}
+	for _, trafficTarget := range trafficTargets {
+		if IsApplicableTrafficTarget(p.podLister, endpoints, trafficTarget) {
+			result = append(result, trafficTarget)
+		}
+	}
 
-			var subsetMatch bool
+	return result
+}
 
-			for _, endpointPort := range subset.Ports {
-				if strconv.FormatInt(int64(endpointPort.Port), 10) == trafficTarget.Destination.Port || trafficTarget.Destination.Port == "" {
-					subsetMatch = true
-					break
-				}
-			}
+// IsApplicableTrafficTarget checks if the TrafficTarget applies to the service of the endpoints: its destination is in the
+// service namespace, and an endpoints subset matching its destination port has a pod running as its destination service account.
+func IsApplicableTrafficTarget(podLister listers.PodLister, endpoints *corev1.Endpoints, trafficTarget *access.TrafficTarget) bool {
+	if endpoints == nil {
+		return false
+	}
 
-			if !subsetMatch {
-				// No subset port match on destination port, so subset is not affected
-				log.Debugf("TrafficTarget: %s does not match destination ports for endpoints %s/%s", trafficTarget.Destination.Name, endpoints.Namespace, endpoints.Name)
-				continue
-			}
+	if endpoints.Namespace != trafficTarget.Destination.Namespace {
+		// Destination not in service namespace, skip.
+		log.Debugf("Destination namespace for TrafficTarget: %s not in service namespace: %s", trafficTarget.Destination.Name, endpoints.Namespace)
+		return false
+	}
 
-			if !p.validPodFound(subset.Addresses, trafficTarget.Destination.Name) {
-				// No valid pods with serviceAccount found on the subset, so it is not affected
-				log.Debugf("Endpoints %s/%s has no valid pods with destination service account: %s", endpoints.Namespace, endpoints.Name, trafficTarget.Destination.Name)
-				continue
+	for _, subset := range endpoints.Subsets {
+		var subsetMatch bool
+
+		for _, endpointPort := range subset.Ports {
+			if strconv.FormatInt(int64(endpointPort.Port), 10) == trafficTarget.Destination.Port || trafficTarget.Destination.Port == "" {
+				subsetMatch = true
+				break
 			}
+		}
+
+		if !subsetMatch {
+			// No subset port match on destination port, so subset is not affected
+			log.Debugf("TrafficTarget: %s does not match destination ports for endpoints %s/%s", trafficTarget.Destination.Name, endpoints.Namespace, endpoints.Name)
+			continue
+		}
 
+		if validPodFound(podLister, subset.Addresses, trafficTarget.Destination.Name) {
 			// We have a subset match, and valid referenced pods for the trafficTarget.
-			result = append(result, trafficTarget)
+			return true
 		}
+
+		// No valid pods with serviceAccount found on the subset, so it is not affected
+		log.Debugf("Endpoints %s/%s has no valid pods with destination service account: %s", endpoints.Namespace, endpoints.Name, trafficTarget.Destination.Name)
 	}
 
-	return result
+	return false
 }
 
-func (p *Provider) validPodFound(addresses []corev1.EndpointAddress, destinationName string) bool {
+func validPodFound(podLister listers.PodLister, addresses []corev1.EndpointAddress, destinationName string) bool {
 	for _, address := range addresses {
 		if address.TargetRef == nil {
 			log.Error("Address has no target reference")
 			continue
 		}
 
-		pod, err := p.podLister.Pods(address.TargetRef.Namespace).Get(address.TargetRef.Name)
+		pod, err := podLister.Pods(address.TargetRef.Namespace).Get(address.TargetRef.Name)
 		if err != nil {
 			log.Errorf("Could not get pod %s/%s: %v", address.TargetRef.Namespace, address.TargetRef.Name, err)
 			continue
@@ -385,7 +422,7 @@ func (p *Provider) buildRuleSnippetFromServiceAndMatch(name, namespace, ip strin
 		result = append(result, fmt.Sprintf("Method(`%s`)", methods))
 	}
 
-	result = append(result, fmt.Sprintf("(Host(`%s.%s.maesh`) || Host(`%s`))", name, namespace, ip))
+	result = append(result, "("+base.BuildHostRule(name, namespace, ip)+")")
 
 	return strings.Join(result, " && ")
 }
@@ -530,7 +567,7 @@ func (p *Provider) buildTrafficSplit(config *dynamic.Configuration, trafficSplit
 	}
 
 	weightedKey := buildKey(svc.Name, svc.Namespace, sp.Port, trafficTarget.Name, trafficTarget.Namespace)
-	config.HTTP.Routers[weightedKey] = p.buildHTTPRouterFromTrafficTarget(trafficSplit.Spec.Service, trafficSplit.Namespace, svc.Spec.ClusterIP, trafficTarget, 5000+id, weightedKey, whitelistMiddleware)
+	config.HTTP.Routers[weightedKey] = p.buildHTTPRouterFromTrafficTarget(trafficSplit.Spec.Service, trafficSplit.Namespace, svc.Spec.ClusterIP, trafficTarget, base.GetHTTPMeshPort(id), weightedKey, whitelistMiddleware)
 	config.HTTP.Services[weightedKey] = svcWeighted
 }
 
@@ -560,6 +597,11 @@ func buildKey(serviceName, namespace string, port int32, ttName, ttNamespace str
 	return fmt.Sprintf("%.10s-%.10s-%d-%.10s-%.10s-%.16s", serviceName, namespace, port, ttName, ttNamespace, fullHash)
 }
 
+// BuildWhitelistKey returns the name of the whitelist middleware of a TrafficTarget on a service port.
+func BuildWhitelistKey(serviceName, namespace string, port int32, ttName, ttNamespace string) string {
+	return ttName + "-" + ttNamespace + "-" + buildKey(serviceName, namespace, port, ttName, ttNamespace) + "-whitelist"
+}
+
 func createWhitelistMiddleware(sourceIPs []string) *dynamic.Middleware {
 	// Create middleware.
 	return &dynamic.Middleware{
@@ -2,6 +2,7 @@ package smi
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/containous/maesh/internal/providers/base"
@@ -10,10 +11,15 @@ import (
 	"github.com/containous/traefik/v2/pkg/config/dynamic"
 	accessv1alpha1 "github.com/deislabs/smi-sdk-go/pkg/apis/access/v1alpha1"
 	specsv1alpha1 "github.com/deislabs/smi-sdk-go/pkg/apis/specs/v1alpha1"
+	accessLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/access/listers/access/v1alpha1"
+	specsLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/specs/listers/specs/v1alpha1"
+	splitLister "github.com/deislabs/smi-sdk-go/pkg/gen/client/split/listers/split/v1alpha2"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
 func TestBuildRuleSnippetFromServiceAndMatch(t *testing.T) {
@@ -1362,3 +1368,156 @@ func TestBuildConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkBuildConfig(b *testing.B) {
+	const (
+		namespacesCount = 100
+		servicesCount   = 2000
+		sourcePodsCount = 10
+	)
+
+	newIndexer := func() cache.Indexer {
+		return cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	}
+
+	serviceIndexer := newIndexer()
+	endpointsIndexer := newIndexer()
+	podIndexer := newIndexer()
+	trafficTargetIndexer := newIndexer()
+	httpRouteGroupIndexer := newIndexer()
+
+	add := func(indexer cache.Indexer, obj interface{}) {
+		if err := indexer.Add(obj); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	// Each namespace has a TrafficTarget allowing the client pods of the namespace to reach its API services.
+	for i := 0; i < namespacesCount; i++ {
+		namespace := fmt.Sprintf("namespace-%d", i)
+
+		add(httpRouteGroupIndexer, &specsv1alpha1.HTTPRouteGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-routes", Namespace: namespace},
+			Matches:    []specsv1alpha1.HTTPMatch{{Name: "all", PathRegex: "/"}},
+		})
+
+		add(trafficTargetIndexer, &accessv1alpha1.TrafficTarget{
+			ObjectMeta:  metav1.ObjectMeta{Name: "api", Namespace: namespace},
+			Destination: accessv1alpha1.IdentityBindingSubject{Kind: "ServiceAccount", Name: "api", Namespace: namespace},
+			Sources:     []accessv1alpha1.IdentityBindingSubject{{Kind: "ServiceAccount", Name: "client", Namespace: namespace}},
+			Specs:       []accessv1alpha1.TrafficTargetSpec{{Kind: "HTTPRouteGroup", Name: "api-routes", Matches: []string{"all"}}},
+		})
+
+		for j := 0; j < sourcePodsCount; j++ {
+			add(podIndexer, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("client-%d", j), Namespace: namespace},
+				Spec:       corev1.PodSpec{ServiceAccountName: "client"},
+				Status:     corev1.PodStatus{PodIP: fmt.Sprintf("10.3.%d.%d", i, j)},
+			})
+		}
+	}
+
+	for i := 0; i < servicesCount; i++ {
+		meta := metav1.ObjectMeta{
+			Name:      fmt.Sprintf("service-%d", i),
+			Namespace: fmt.Sprintf("namespace-%d", i%namespacesCount),
+		}
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: meta.Name + "-pod", Namespace: meta.Namespace},
+			Spec:       corev1.PodSpec{ServiceAccountName: "api"},
+			Status:     corev1.PodStatus{PodIP: fmt.Sprintf("10.1.%d.%d", i/256%256, i%256)},
+		}
+
+		add(podIndexer, pod)
+
+		add(serviceIndexer, &corev1.Service{
+			ObjectMeta: meta,
+			Spec: corev1.ServiceSpec{
+				ClusterIP: "10.0.0.1",
+				Ports:     []corev1.ServicePort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}},
+			},
+		})
+
+		add(endpointsIndexer, &corev1.Endpoints{
+			ObjectMeta: meta,
+			Subsets: []corev1.EndpointSubset{
+				{
+					Addresses: []corev1.EndpointAddress{{
+						IP:        pod.Status.PodIP,
+						TargetRef: &corev1.ObjectReference{Kind: "Pod", Name: pod.Name, Namespace: pod.Namespace},
+					}},
+					Ports: []corev1.EndpointPort{{Port: 8080}},
+				},
+			},
+		})
+	}
+
+	provider := New(k8s.ServiceTypeHTTP, nil, k8s.NewIgnored(),
+		listers.NewServiceLister(serviceIndexer),
+		listers.NewEndpointsLister(endpointsIndexer),
+		listers.NewPodLister(podIndexer),
+		accessLister.NewTrafficTargetLister(trafficTargetIndexer),
+		specsLister.NewHTTPRouteGroupLister(httpRouteGroupIndexer),
+		specsLister.NewTCPRouteLister(newIndexer()),
+		splitLister.NewTrafficSplitLister(newIndexer()))
+
+	config, err := provider.BuildConfig()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if len(config.HTTP.Routers) != servicesCount+1 {
+		b.Fatalf("expected %d routers, got %d", servicesCount+1, len(config.HTTP.Routers))
+	}
+
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			provider.InvalidateAll()
+
+			if _, err := provider.BuildConfig(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("one service invalidated", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			provider.InvalidateService(fmt.Sprintf("namespace-%d", i%namespacesCount), fmt.Sprintf("service-%d", i%servicesCount))
+
+			if _, err := provider.BuildConfig(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("one source pod changed", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "client-0", Namespace: "namespace-0"},
+				Spec:       corev1.PodSpec{ServiceAccountName: "client"},
+				Status:     corev1.PodStatus{PodIP: fmt.Sprintf("10.4.%d.%d", i/256%256, i%256)},
+			}
+
+			if err := podIndexer.Update(pod); err != nil {
+				b.Fatal(err)
+			}
+
+			b.StartTimer()
+
+			provider.InvalidateNamespace(pod.Namespace)
+
+			if _, err := provider.BuildConfig(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
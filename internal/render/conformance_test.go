@@ -0,0 +1,71 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "Update the expected configurations of the conformance scenarios.")
+
+// TestConformance builds the configuration of each scenario of fixtures/conformance/<provider>/<scenario>,
+// a directory of YAML manifests, and compares it to the scenario expected.json file.
+// Run `go test ./internal/render/ -run TestConformance -update` to write the expected files of new or changed scenarios.
+func TestConformance(t *testing.T) {
+	providers := []struct {
+		name string
+		smi  bool
+	}{
+		{name: "kubernetes"},
+		{name: "smi", smi: true},
+	}
+
+	for _, provider := range providers {
+		provider := provider
+
+		scenarios, err := ioutil.ReadDir(filepath.Join("fixtures", "conformance", provider.name))
+		require.NoError(t, err)
+
+		for _, scenario := range scenarios {
+			if !scenario.IsDir() {
+				continue
+			}
+
+			dir := filepath.Join("fixtures", "conformance", provider.name, scenario.Name())
+
+			t.Run(provider.name+"/"+scenario.Name(), func(t *testing.T) {
+				t.Parallel()
+
+				objects, err := LoadManifests([]string{dir})
+				require.NoError(t, err)
+
+				conf, err := BuildConfiguration(objects, Config{SMI: provider.smi, DefaultMode: k8s.ServiceTypeHTTP})
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				require.NoError(t, Write(&buf, conf, FormatJSON))
+
+				expectedFile := filepath.Join(dir, "expected.json")
+
+				if *update {
+					require.NoError(t, ioutil.WriteFile(expectedFile, buf.Bytes(), 0644))
+				}
+
+				expected, err := ioutil.ReadFile(expectedFile)
+				if os.IsNotExist(err) {
+					t.Fatalf("missing %s, run the test with -update to create it", expectedFile)
+				}
+
+				require.NoError(t, err)
+				assert.Equal(t, string(expected), buf.String())
+			})
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/maesh/internal/providers/base"
+	"github.com/containous/maesh/internal/providers/kubernetes"
+	"github.com/containous/maesh/internal/providers/smi"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// Output formats.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// firstTCPMeshPort is the first mesh port allocated to the TCP services, as done by the controller.
+const firstTCPMeshPort = 10000
+
+// Config holds the options of a render.
+type Config struct {
+	SMI               bool
+	DefaultMode       string
+	IgnoreNamespaces  []string
+	NamespaceSelector labels.Selector
+	ServiceSelector   labels.Selector
+}
+
+// LoadManifests parses the objects of the given YAML manifests.
+// The directories are read for their .yaml and .yml files, non recursively.
+func LoadManifests(paths []string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+
+	keys := make(map[string]string)
+
+	for _, path := range paths {
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read manifest %q: %v", file, err)
+			}
+
+			fileObjects, err := k8s.ParseYaml(content)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse manifest %q: %v", file, err)
+			}
+
+			for _, object := range fileObjects {
+				key, err := objectKey(object)
+				if err != nil {
+					return nil, fmt.Errorf("invalid object in manifest %q: %v", file, err)
+				}
+
+				if previous, exists := keys[key]; exists {
+					return nil, fmt.Errorf("duplicate object %s in manifests %q and %q", key, previous, file)
+				}
+
+				keys[key] = file
+			}
+
+			objects = append(objects, fileObjects...)
+		}
+	}
+
+	return objects, nil
+}
+
+// objectKey returns the kind, namespace and name of an object, like "Service/default/whoami".
+func objectKey(object runtime.Object) (string, error) {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return "", err
+	}
+
+	return object.GetObjectKind().GroupVersionKind().Kind + "/" + accessor.GetNamespace() + "/" + accessor.GetName(), nil
+}
+
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read manifest %q: %v", path, err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(path, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("unable to list manifests in %q: %v", path, err)
+		}
+
+		files = append(files, matches...)
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// BuildConfiguration builds the configuration of the given objects with the provider selected by the config,
+// as the controller would in a cluster holding these objects.
+func BuildConfiguration(objects []runtime.Object, cfg Config) (*dynamic.Configuration, error) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	clients := k8s.NewClientMockFromObjects(stopCh, objects, cfg.SMI)
+
+	ignored := k8s.NewDefaultIgnored(cfg.IgnoreNamespaces)
+
+	if cfg.NamespaceSelector != nil {
+		ignored.NamespaceSelector = cfg.NamespaceSelector
+		ignored.NamespaceLister = clients.NamespaceLister
+	}
+
+	if cfg.ServiceSelector != nil {
+		ignored.ServiceSelector = cfg.ServiceSelector
+	}
+
+	tcpStateTable, err := buildTCPStateTable(clients, ignored, cfg.DefaultMode)
+	if err != nil {
+		return nil, err
+	}
+
+	var provider base.Provider
+	if cfg.SMI {
+		provider = smi.New(cfg.DefaultMode, tcpStateTable, ignored, clients.ServiceLister, clients.EndpointsLister, clients.PodLister,
+			clients.TrafficTargetLister, clients.HTTPRouteGroupLister, clients.TCPRouteLister, clients.TrafficSplitLister)
+	} else {
+		provider = kubernetes.New(cfg.DefaultMode, tcpStateTable, ignored, clients.ServiceLister, clients.EndpointsLister)
+	}
+
+	provider.Init()
+
+	return provider.BuildConfig()
+}
+
+// buildTCPStateTable allocates the mesh ports of the TCP services. Without the state of a cluster,
+// the ports are allocated in the order of the service namespaces, names and ports, to render stable configurations.
+func buildTCPStateTable(clients *k8s.ClientMock, ignored k8s.IgnoreWrapper, defaultMode string) (*k8s.State, error) {
+	services, err := clients.ServiceLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get services: %v", err)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Namespace != services[j].Namespace {
+			return services[i].Namespace < services[j].Namespace
+		}
+
+		return services[i].Name < services[j].Name
+	})
+
+	state := &k8s.State{Table: make(map[int]*k8s.ServiceWithPort)}
+	port := firstTCPMeshPort
+
+	for _, service := range services {
+		if ignored.IsIgnored(service.ObjectMeta) || base.GetServiceMode(service.Annotations, defaultMode) != k8s.ServiceTypeTCP {
+			continue
+		}
+
+		for _, sp := range service.Spec.Ports {
+			state.Table[port] = &k8s.ServiceWithPort{
+				Name:      service.Name,
+				Namespace: service.Namespace,
+				Port:      sp.Port,
+			}
+			port++
+		}
+	}
+
+	return state, nil
+}
+
+// Write writes the configuration in the given format.
+func Write(w io.Writer, conf *dynamic.Configuration, format string) error {
+	var (
+		content []byte
+		err     error
+	)
+
+	switch format {
+	case FormatJSON:
+		var buf bytes.Buffer
+
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(conf)
+		content = buf.Bytes()
+	case FormatYAML:
+		content, err = yaml.Marshal(conf)
+	case FormatTOML:
+		var buf bytes.Buffer
+
+		err = toml.NewEncoder(&buf).Encode(conf)
+		content = buf.Bytes()
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to encode configuration: %v", err)
+	}
+
+	_, err = w.Write(content)
+
+	return err
+}
@@ -0,0 +1,167 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/containous/maesh/internal/k8s"
+	"github.com/containous/traefik/v2/pkg/config/dynamic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadManifests(t *testing.T) {
+	testCases := []struct {
+		desc            string
+		paths           []string
+		expectedObjects int
+		expectedErr     bool
+	}{
+		{
+			desc:            "file",
+			paths:           []string{"fixtures/tcp_service.yaml"},
+			expectedObjects: 3,
+		},
+		{
+			desc:            "unsupported kinds",
+			paths:           []string{"fixtures/http_service.yaml"},
+			expectedObjects: 3,
+		},
+		{
+			desc:        "duplicate objects",
+			paths:       []string{"fixtures"},
+			expectedErr: true,
+		},
+		{
+			desc:        "missing file",
+			paths:       []string{"fixtures/missing.yaml"},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			objects, err := LoadManifests(test.paths)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, objects, test.expectedObjects)
+		})
+	}
+}
+
+func TestBuildConfiguration(t *testing.T) {
+	objects, err := LoadManifests([]string{"fixtures/tcp_service.yaml"})
+	require.NoError(t, err)
+
+	conf, err := BuildConfiguration(objects, Config{DefaultMode: k8s.ServiceTypeHTTP})
+	require.NoError(t, err)
+
+	expected := map[string]*dynamic.TCPRouter{
+		"test-foo-80-6653beb49ee354ea": {
+			EntryPoints: []string{"tcp-10000"},
+			Service:     "test-foo-80-6653beb49ee354ea",
+			Rule:        "HostSNI(`*`)",
+		},
+	}
+
+	require.NotNil(t, conf.TCP)
+	assert.Equal(t, expected, conf.TCP.Routers)
+}
+
+func TestBuildConfigurationWithoutClusterIP(t *testing.T) {
+	objects, err := LoadManifests([]string{"fixtures/http_service.yaml"})
+	require.NoError(t, err)
+
+	conf, err := BuildConfiguration(objects, Config{DefaultMode: k8s.ServiceTypeHTTP})
+	require.NoError(t, err)
+
+	require.NotNil(t, conf.HTTP)
+
+	router, ok := conf.HTTP.Routers["web-bar-80-3c207496721bd7e4"]
+	require.True(t, ok)
+	assert.Equal(t, "Host(`web.bar.maesh`)", router.Rule)
+}
+
+func TestWrite(t *testing.T) {
+	conf := &dynamic.Configuration{
+		TCP: &dynamic.TCPConfiguration{
+			Routers: map[string]*dynamic.TCPRouter{
+				"test": {EntryPoints: []string{"tcp-10000"}, Service: "test", Rule: "HostSNI(`*`)"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		format      string
+		expected    string
+		expectedErr bool
+	}{
+		{
+			format: FormatJSON,
+			expected: `{
+  "tcp": {
+    "routers": {
+      "test": {
+        "entryPoints": [
+          "tcp-10000"
+        ],
+        "service": "test",
+        "rule": "HostSNI(` + "`*`" + `)"
+      }
+    }
+  }
+}
+`,
+		},
+		{
+			format: FormatYAML,
+			expected: `tcp:
+  routers:
+    test:
+      entryPoints:
+      - tcp-10000
+      rule: HostSNI(` + "`*`" + `)
+      service: test
+`,
+		},
+		{
+			format: FormatTOML,
+			expected: `[tcp]
+  [tcp.routers]
+    [tcp.routers.test]
+      entryPoints = ["tcp-10000"]
+      service = "test"
+      rule = "HostSNI(` + "`*`" + `)"
+`,
+		},
+		{
+			format:      "xml",
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.format, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+
+			err := Write(&buf, conf, test.format)
+			if test.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, buf.String())
+		})
+	}
+}